@@ -15,23 +15,39 @@ import (
 
 // Telemetry defines the interface for telemetry operations
 type Telemetry interface {
-	// StartSpan starts a new span and returns the context and the span
+	// StartSpan starts a new internal span and returns the context and the span
 	StartSpan(ctx context.Context, name string) (context.Context, trace.Span)
 
+	// StartSpanWithKind starts a new span of the given kind, such as client or server,
+	// with the given attributes, and returns the context and the span
+	StartSpanWithKind(ctx context.Context, name string, kind trace.SpanKind, attributes ...attribute.KeyValue) (context.Context, trace.Span)
+
+	// StartSpanWithLinks starts a new span linked to the given span contexts, e.g. the
+	// traces of the messages of a batch, and returns the context and the span
+	StartSpanWithLinks(ctx context.Context, name string, links []trace.Link) (context.Context, trace.Span)
+
 	// EndSpan ends the given span
 	EndSpan(span trace.Span)
 
+	// WithSpan runs fn in a new span, recording the error fn returns, and ends the span
+	WithSpan(ctx context.Context, name string, fn func(ctx context.Context) error) error
+
 	// AddEvent adds an event to the given span
 	AddEvent(span trace.Span, name string, attributes ...attribute.KeyValue)
 
 	// RecordMetric records a metric with the given name and value
 	RecordMetric(ctx context.Context, name string, value float64, attributes ...attribute.KeyValue)
 
-	// PostEvent posts an event with the given name and properties
-	PostEvent(name string, properties map[string]string)
+	// RecordMetricE records a metric like RecordMetric, returning the error that
+	// prevented recording it
+	RecordMetricE(ctx context.Context, name string, value float64, attributes ...attribute.KeyValue) error
+
+	// PostEvent posts an event with the given name and properties on the span in ctx
+	PostEvent(ctx context.Context, name string, properties map[string]string)
 
-	// PostTrace posts a trace message with the given severity and properties
-	PostTrace(message string, severity string, properties map[string]string)
+	// PostTrace posts a trace message with the given severity and properties on the
+	// span in ctx
+	PostTrace(ctx context.Context, message string, severity string, properties map[string]string)
 
 	// RecordError records an error with the given attributes
 	RecordError(ctx context.Context, err error, attributes ...attribute.KeyValue)
@@ -66,6 +82,12 @@ type Telemetry interface {
 	// SetSession sets the session ID for the current context
 	SetSession(ctx context.Context, id string)
 
+	// TracingEnabled reports whether tracing is enabled
+	TracingEnabled() bool
+
+	// MetricsEnabled reports whether metrics are enabled
+	MetricsEnabled() bool
+
 	// Shutdown shuts down the telemetry provider
 	Shutdown(ctx context.Context) error
 }
@@ -78,23 +100,50 @@ const (
 	TelemetryTypeOpenTelemetry TelemetryType = "opentelemetry"
 	// TelemetryTypeAppInsights represents the Application Insights implementation
 	TelemetryTypeAppInsights TelemetryType = "appinsights"
+	// TelemetryTypeNone represents the no-op implementation
+	TelemetryTypeNone TelemetryType = "none"
 )
 
 // NewTelemetry creates and returns the appropriate telemetry implementation
 func NewTelemetry() (Telemetry, error) {
 	telemetryType := os.Getenv("TELEMETRY_TYPE")
+	// An empty service name lets OTEL_SERVICE_NAME/OTEL_RESOURCE_ATTRIBUTES apply,
+	// falling back to "unknown-service"
 	serviceName := os.Getenv("SERVICE_NAME")
-	if serviceName == "" {
-		serviceName = "unknown-service"
-	}
 
 	switch telemetryType {
 	case "opentelemetry", "otel", "":
 		traceEnabled := os.Getenv("OTEL_TRACE_ENABLED") == "true"
 		metricsEnabled := os.Getenv("OTEL_METRICS_ENABLED") == "true"
+		if !traceEnabled && !metricsEnabled {
+			return NewNoop(), nil
+		}
 		traceEndpoint := os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT")
 		metricEndpoint := os.Getenv("OTEL_EXPORTER_OTLP_METRICS_ENDPOINT")
-		return NewOpenTelemetry(serviceName, traceEndpoint, metricEndpoint, traceEnabled, metricsEnabled)
+		var opts []Option
+		if protocol := os.Getenv("OTEL_EXPORTER_OTLP_PROTOCOL"); protocol != "" {
+			opts = append(opts, WithExporterProtocol(protocol))
+		}
+		if samplerName := os.Getenv("OTEL_TRACES_SAMPLER"); samplerName != "" {
+			if sampler, ok := parseEnvSampler(samplerName, os.Getenv("OTEL_TRACES_SAMPLER_ARG")); ok {
+				opts = append(opts, WithSampler(sampler))
+			}
+		}
+		if os.Getenv("OTEL_EXPORTER_OTLP_INSECURE") == "true" {
+			opts = append(opts, WithInsecure())
+		}
+		if caCertFile := os.Getenv("OTEL_EXPORTER_OTLP_CERTIFICATE"); caCertFile != "" {
+			opts = append(opts, WithCACertFile(caCertFile))
+		}
+		if os.Getenv("TELEMETRY_METRICS_EXPORTER") == "prometheus" {
+			opts = append(opts, WithPrometheusExporter(nil))
+		}
+		if pushgatewayURL := os.Getenv("PUSHGATEWAY_URL"); pushgatewayURL != "" {
+			opts = append(opts, WithPushgateway(pushgatewayURL, os.Getenv("PUSHGATEWAY_JOB")))
+		}
+		return NewOpenTelemetry(serviceName, traceEndpoint, metricEndpoint, traceEnabled, metricsEnabled, opts...)
+	case "none":
+		return NewNoop(), nil
 	// Add cases for other telemetry types if needed
 	default:
 		return nil, fmt.Errorf("unknown telemetry type: %s", telemetryType)
@@ -114,6 +163,8 @@ func getTelemetryType() TelemetryType {
 		return TelemetryTypeOpenTelemetry
 	case "appinsights", "applicationinsights":
 		return TelemetryTypeAppInsights
+	case "none":
+		return TelemetryTypeNone
 	default:
 		// If an unknown type is specified, default to OpenTelemetry
 		return TelemetryTypeOpenTelemetry
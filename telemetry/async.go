@@ -0,0 +1,140 @@
+// async.go - Non-blocking metric recording from latency-sensitive goroutines
+
+package telemetry
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// asyncMeasurement is a metric value queued by an AsyncRecorder
+type asyncMeasurement struct {
+	name       string
+	value      float64
+	attributes []attribute.KeyValue
+}
+
+// AsyncRecorder queues metric values without blocking and records them through
+// RecordMetric from a background goroutine. Values are dropped when the queue is full.
+type AsyncRecorder struct {
+	o     *OpenTelemetry
+	queue chan asyncMeasurement
+	stop  chan struct{}
+	done  chan struct{}
+
+	// mu is held for reading while a value is queued and for writing while the
+	// recorder is stopped, so no value is queued after the final drain starts
+	mu     sync.RWMutex
+	closed bool
+
+	dropped atomic.Uint64
+}
+
+// NewAsyncRecorder returns an AsyncRecorder with a queue of buf entries. The
+// recorder is drained and stopped on Shutdown.
+func (o *OpenTelemetry) NewAsyncRecorder(buf int) *AsyncRecorder {
+	if buf < 1 {
+		buf = 1
+	}
+	r := &AsyncRecorder{
+		o:     o,
+		queue: make(chan asyncMeasurement, buf),
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	go r.run()
+
+	o.asyncMu.Lock()
+	o.asyncRecorders = append(o.asyncRecorders, r)
+	o.asyncMu.Unlock()
+	return r
+}
+
+// TryRecord queues a metric value without blocking. It returns false and counts
+// the value as dropped if the queue is full or the recorder is stopped. The
+// attributes are copied, so the caller may reuse the slice.
+func (r *AsyncRecorder) TryRecord(name string, value float64, attributes ...attribute.KeyValue) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.closed {
+		r.dropped.Add(1)
+		return false
+	}
+	m := asyncMeasurement{name: name, value: value}
+	if len(attributes) > 0 {
+		m.attributes = append([]attribute.KeyValue(nil), attributes...)
+	}
+	select {
+	case r.queue <- m:
+		return true
+	default:
+		r.dropped.Add(1)
+		return false
+	}
+}
+
+// Dropped returns the number of values dropped because the queue was full or
+// the recorder was stopped
+func (r *AsyncRecorder) Dropped() uint64 {
+	return r.dropped.Load()
+}
+
+// run records queued values until the recorder is stopped, then drains the queue
+func (r *AsyncRecorder) run() {
+	defer close(r.done)
+	for {
+		select {
+		case m := <-r.queue:
+			r.record(m)
+		case <-r.stop:
+			for {
+				select {
+				case m := <-r.queue:
+					r.record(m)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// record records a queued value through the regular metric path
+func (r *AsyncRecorder) record(m asyncMeasurement) {
+	r.o.RecordMetric(context.Background(), m.name, m.value, m.attributes...)
+}
+
+// close stops the recorder and waits until the queued values are recorded or ctx is done
+func (r *AsyncRecorder) close(ctx context.Context) error {
+	r.mu.Lock()
+	if !r.closed {
+		r.closed = true
+		close(r.stop)
+	}
+	r.mu.Unlock()
+	select {
+	case <-r.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// closeAsyncRecorders drains and stops all recorders created by NewAsyncRecorder
+func (o *OpenTelemetry) closeAsyncRecorders(ctx context.Context) error {
+	o.asyncMu.Lock()
+	recorders := o.asyncRecorders
+	o.asyncRecorders = nil
+	o.asyncMu.Unlock()
+
+	var err error
+	for _, r := range recorders {
+		if cErr := r.close(ctx); cErr != nil && err == nil {
+			err = cErr
+		}
+	}
+	return err
+}
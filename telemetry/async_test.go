@@ -0,0 +1,124 @@
+package telemetry
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+func TestAsyncRecorderCountsOverflow(t *testing.T) {
+	tt := newTestTelemetry(t)
+	// No background goroutine drains the queue, as if the SDK were stalled
+	r := &AsyncRecorder{
+		o:     tt.OpenTelemetry,
+		queue: make(chan asyncMeasurement, 2),
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+
+	for i, want := range []bool{true, true, false, false} {
+		if got := r.TryRecord("jobs", 1); got != want {
+			t.Errorf("TryRecord #%d = %v, want %v", i, got, want)
+		}
+	}
+	if got := r.Dropped(); got != 2 {
+		t.Errorf("Dropped() = %d, want 2", got)
+	}
+}
+
+func TestAsyncRecorderDrainsOnClose(t *testing.T) {
+	tt := newTestTelemetry(t)
+	r := tt.NewAsyncRecorder(16)
+
+	attrs := []attribute.KeyValue{attribute.String("queue", "a")}
+	for i := 0; i < 10; i++ {
+		if !r.TryRecord("jobs", 1, attrs...) {
+			t.Fatalf("TryRecord #%d dropped the value", i)
+		}
+	}
+	// The queued values keep their own copy of the attributes
+	attrs[0] = attribute.String("queue", "b")
+
+	if err := tt.closeAsyncRecorders(context.Background()); err != nil {
+		t.Fatalf("closeAsyncRecorders() error = %v", err)
+	}
+	if r.TryRecord("jobs", 1) {
+		t.Error("TryRecord after close queued the value")
+	}
+	if got := r.Dropped(); got != 1 {
+		t.Errorf("Dropped() = %d, want 1", got)
+	}
+
+	points := sumPoints(t, tt.metric(t, "jobs"))
+	if len(points) != 1 || points[0].Value != 10 {
+		t.Fatalf("jobs points = %+v, want 10 recorded", points)
+	}
+	if got := attrMap(points[0].Attributes.ToSlice())["queue"]; got != "a" {
+		t.Errorf("queue attribute = %q, want a", got)
+	}
+}
+
+func TestAsyncRecorderCloseRace(t *testing.T) {
+	tt := newTestTelemetry(t)
+	r := tt.NewAsyncRecorder(8)
+
+	const goroutines, values = 4, 500
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < values; i++ {
+				r.TryRecord("jobs", 1)
+			}
+		}()
+	}
+	if err := tt.closeAsyncRecorders(context.Background()); err != nil {
+		t.Fatalf("closeAsyncRecorders() error = %v", err)
+	}
+	wg.Wait()
+
+	var recorded float64
+	if m, ok := findMetric(tt.collect(t), "jobs"); ok {
+		for _, p := range sumPoints(t, m) {
+			recorded += p.Value
+		}
+	}
+	if total := recorded + float64(r.Dropped()); total != goroutines*values {
+		t.Errorf("recorded %v + dropped %d = %v, want every value accounted for (%d)",
+			recorded, r.Dropped(), total, goroutines*values)
+	}
+}
+
+func BenchmarkAsyncRecorderTryRecord(b *testing.B) {
+	o, err := NewOpenTelemetryWithOptions(
+		WithServiceName("telemetry-bench"),
+		WithTracingEnabled(false),
+		WithMetricsEnabled(true),
+		WithMetricEndpoint("127.0.0.1:1"),
+		WithInsecure(),
+		WithKubernetesDetector(false),
+	)
+	if err != nil {
+		b.Fatalf("NewOpenTelemetryWithOptions() error = %v", err)
+	}
+	defer func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_ = o.Shutdown(ctx)
+	}()
+	r := o.NewAsyncRecorder(1024)
+	attrs := []attribute.KeyValue{attribute.String("queue", "a")}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			r.TryRecord("jobs", 1, attrs...)
+		}
+	})
+	b.StopTimer()
+	b.ReportMetric(float64(r.Dropped())/float64(b.N), "dropped/op")
+}
@@ -0,0 +1,100 @@
+// attributes.go - Attribute normalization applied before data reaches the SDK
+
+package telemetry
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// defaultAttributeKeyAliases maps common spellings of HTTP and DB attribute
+// keys to their OpenTelemetry semantic convention names
+var defaultAttributeKeyAliases = map[string]string{
+	"status_code":      "http.status_code",
+	"statusCode":       "http.status_code",
+	"http_status_code": "http.status_code",
+	"method":           "http.method",
+	"http_method":      "http.method",
+	"route":            "http.route",
+	"http_route":       "http.route",
+	"db_system":        "db.system",
+	"dbSystem":         "db.system",
+	"db_operation":     "db.operation",
+	"dbOperation":      "db.operation",
+}
+
+// normalizeAttributes returns sanitized attributes with aliased keys replaced by
+// their canonical key. The input slice is not modified.
+func (o *OpenTelemetry) normalizeAttributes(attributes []attribute.KeyValue) []attribute.KeyValue {
+	attributes = sanitizeAttributes(attributes)
+	aliases := o.config.attributeKeyAliases
+	if len(aliases) == 0 || len(attributes) == 0 {
+		return attributes
+	}
+	normalized := make([]attribute.KeyValue, len(attributes))
+	for i, kv := range attributes {
+		if canonical, ok := aliases[string(kv.Key)]; ok {
+			kv.Key = attribute.Key(canonical)
+		}
+		normalized[i] = kv
+	}
+	return normalized
+}
+
+// sanitizeAttributes returns attributes with invalid UTF-8 in string values replaced
+// by the Unicode replacement character, since some exporters reject such values and
+// drop the whole span. The input slice is returned unchanged when all values are valid.
+func sanitizeAttributes(attributes []attribute.KeyValue) []attribute.KeyValue {
+	var sanitized []attribute.KeyValue
+	for i, kv := range attributes {
+		clean, ok := sanitizeAttribute(kv)
+		if ok && sanitized == nil {
+			continue
+		}
+		if sanitized == nil {
+			sanitized = make([]attribute.KeyValue, len(attributes))
+			copy(sanitized, attributes[:i])
+		}
+		sanitized[i] = clean
+	}
+	if sanitized == nil {
+		return attributes
+	}
+	return sanitized
+}
+
+// sanitizeString returns s with invalid UTF-8 replaced by the Unicode replacement character
+func sanitizeString(s string) string {
+	if utf8.ValidString(s) {
+		return s
+	}
+	return strings.ToValidUTF8(s, string(utf8.RuneError))
+}
+
+// sanitizeAttribute returns kv with invalid UTF-8 replaced and whether it was already valid
+func sanitizeAttribute(kv attribute.KeyValue) (attribute.KeyValue, bool) {
+	switch kv.Value.Type() {
+	case attribute.STRING:
+		v := kv.Value.AsString()
+		if utf8.ValidString(v) {
+			return kv, true
+		}
+		return kv.Key.String(sanitizeString(v)), false
+	case attribute.STRINGSLICE:
+		values := kv.Value.AsStringSlice()
+		valid := true
+		for i, v := range values {
+			if !utf8.ValidString(v) {
+				values[i] = sanitizeString(v)
+				valid = false
+			}
+		}
+		if valid {
+			return kv, true
+		}
+		return kv.Key.StringSlice(values), false
+	}
+	return kv, true
+}
@@ -0,0 +1,105 @@
+package telemetry
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+func TestAttributeKeyAliasesCollapseMetricKeys(t *testing.T) {
+	tt := newTestTelemetry(t)
+	ctx := context.Background()
+
+	tt.IncrementCounter(ctx, "requests", 1, attribute.Int("status_code", 200))
+	tt.IncrementCounter(ctx, "requests", 1, attribute.Int("statusCode", 200))
+	tt.IncrementCounter(ctx, "requests", 1, attribute.Int("http.status_code", 200))
+	tt.IncrementCounter(ctx, "requests", 1, attribute.String("dbSystem", "mysql"))
+
+	points := sumPoints(t, tt.metric(t, "requests"))
+	if len(points) != 2 {
+		t.Fatalf("got %d data points, want one per canonical attribute set: %+v", len(points), points)
+	}
+	for _, p := range points {
+		if v, ok := p.Attributes.Value("http.status_code"); ok {
+			if v.AsInt64() != 200 || p.Value != 3 {
+				t.Errorf("http.status_code point = %v with value %v, want 200 with value 3", v.AsInt64(), p.Value)
+			}
+			continue
+		}
+		if v, ok := p.Attributes.Value("db.system"); !ok || v.AsString() != "mysql" || p.Value != 1 {
+			t.Errorf("unexpected data point %v = %v", p.Attributes.ToSlice(), p.Value)
+		}
+	}
+}
+
+func TestAttributeKeyAliasesCustomAndDisabled(t *testing.T) {
+	tt := newTestTelemetry(t,
+		WithoutDefaultAttributeKeyAliases(),
+		WithAttributeKeyAliases(map[string]string{"tenant": "tenant.id"}))
+	ctx := context.Background()
+
+	tt.IncrementCounter(ctx, "jobs", 1, attribute.String("tenant", "acme"), attribute.Int("status_code", 500))
+
+	points := sumPoints(t, tt.metric(t, "jobs"))
+	if len(points) != 1 {
+		t.Fatalf("got %d data points, want 1", len(points))
+	}
+	attrs := attrMap(points[0].Attributes.ToSlice())
+	if attrs["tenant.id"] != "acme" {
+		t.Errorf("attributes = %v, want tenant aliased to tenant.id", attrs)
+	}
+	if attrs["status_code"] != "500" {
+		t.Errorf("attributes = %v, want status_code kept without the default aliases", attrs)
+	}
+}
+
+func TestInvalidUTF8AttributesAreSanitizedAndExported(t *testing.T) {
+	collector := newStubCollector(t)
+	tt := newTestTelemetry(t, WithTraceEndpoint(collector.addr), WithMetricEndpoint(collector.addr))
+	ctx := context.Background()
+	const invalid = "bad\xffvalue"
+
+	reqCtx, span := tt.StartSpan(ctx, "request")
+	tt.SetUser(reqCtx, invalid)
+	tt.EndSpan(span)
+	tt.TrackRequest(ctx, "GET", "/items/"+invalid, time.Millisecond, 200)
+	tt.TrackDBOperation(ctx, "postgresql", "SELECT", "SELECT * FROM "+invalid, 1, time.Millisecond, nil)
+	_, publish := tt.StartPublishSpan(ctx, "kafka", invalid)
+	tt.EndPublishSpan(publish, invalid, nil)
+	tt.IncrementCounter(ctx, "requests", 1, attribute.String("tenant", invalid))
+
+	for name, key := range map[string]string{
+		"request":            "user.id",
+		"HTTP Request":       "http.url",
+		"SELECT":             "db.statement",
+		invalid + " publish": "messaging.destination.name",
+	} {
+		spans := tt.endedSpans(sanitizeString(name))
+		if len(spans) != 1 {
+			t.Errorf("got %d spans named %q, want 1", len(spans), name)
+			continue
+		}
+		value := attrMap(spans[0].Attributes())[key]
+		if !utf8.ValidString(value) || !strings.Contains(value, "\uFFFD") {
+			t.Errorf("span %q attribute %s = %q, want valid UTF-8 with a replacement character", name, key, value)
+		}
+	}
+	points := sumPoints(t, tt.metric(t, "requests"))
+	if len(points) != 1 {
+		t.Fatalf("got %d data points, want 1", len(points))
+	}
+	if v, _ := points[0].Attributes.Value("tenant"); !utf8.ValidString(v.AsString()) {
+		t.Errorf("metric attribute tenant = %q, want valid UTF-8", v.AsString())
+	}
+
+	if err := tt.forceFlush(ctx); err != nil {
+		t.Fatalf("forceFlush() error = %v", err)
+	}
+	if got := collector.receivedSpans(); len(got) != 4 {
+		t.Errorf("collector received spans %q, want all 4", got)
+	}
+}
@@ -0,0 +1,106 @@
+// batch.go - Batch size, processed-item and I/O size tracking for batch-processing spans
+
+package telemetry
+
+import (
+	"context"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	batchSizeKey      = attribute.Key("batch.size")
+	batchProcessedKey = attribute.Key("batch.processed")
+
+	ioInputRecordsKey  = attribute.Key("io.input.records")
+	ioOutputRecordsKey = attribute.Key("io.output.records")
+	ioInputBytesKey    = attribute.Key("io.input.bytes")
+	ioOutputBytesKey   = attribute.Key("io.output.bytes")
+)
+
+// SetBatchSize sets the total number of items the span in ctx is expected to process
+func (o *OpenTelemetry) SetBatchSize(ctx context.Context, total int) {
+	if !o.traceEnabled {
+		return
+	}
+	span := trace.SpanFromContext(ctx)
+	if span.IsRecording() {
+		span.SetAttributes(batchSizeKey.Int(total))
+	}
+}
+
+// SetIOSizes sets the input and output record counts and byte sizes of the
+// operation of the span in ctx as the io.input.records, io.output.records,
+// io.input.bytes and io.output.bytes attributes. Nothing is done for spans that are
+// not recording, such as unsampled ones.
+func (o *OpenTelemetry) SetIOSizes(ctx context.Context, inRecords, outRecords int64, inBytes, outBytes int64) {
+	if !o.traceEnabled {
+		return
+	}
+	span := trace.SpanFromContext(ctx)
+	if span.IsRecording() {
+		span.SetAttributes(
+			ioInputRecordsKey.Int64(inRecords),
+			ioOutputRecordsKey.Int64(outRecords),
+			ioInputBytesKey.Int64(inBytes),
+			ioOutputBytesKey.Int64(outBytes),
+		)
+	}
+}
+
+// IncrementProcessed adds n to the number of items processed by the span in ctx.
+// When WithBatchThroughputMetrics is enabled, the batch.throughput histogram is
+// recorded in items per second when the span ends.
+func (o *OpenTelemetry) IncrementProcessed(ctx context.Context, n int) {
+	if !o.traceEnabled {
+		return
+	}
+	span := trace.SpanFromContext(ctx)
+	if !span.IsRecording() {
+		return
+	}
+	spanID := span.SpanContext().SpanID()
+	counter, _ := o.batchCounts.LoadOrStore(spanID, new(atomic.Int64))
+	span.SetAttributes(batchProcessedKey.Int64(counter.(*atomic.Int64).Add(int64(n))))
+}
+
+// batchSpanProcessor clears processed-item counters of ended spans and records
+// their throughput
+type batchSpanProcessor struct {
+	o *OpenTelemetry
+	spanNames
+}
+
+// OnStart does nothing, counters are created lazily by IncrementProcessed
+func (p *batchSpanProcessor) OnStart(context.Context, sdktrace.ReadWriteSpan) {}
+
+// OnEnd drops the span's counter and records the batch.throughput histogram with the
+// normalized span name, bounded like that of span.duration
+func (p *batchSpanProcessor) OnEnd(s sdktrace.ReadOnlySpan) {
+	if _, ok := p.o.batchCounts.LoadAndDelete(s.SpanContext().SpanID()); !ok {
+		return
+	}
+	if !p.o.config.batchThroughputMetrics {
+		return
+	}
+	seconds := s.EndTime().Sub(s.StartTime()).Seconds()
+	if seconds <= 0 {
+		return
+	}
+	for _, kv := range s.Attributes() {
+		if kv.Key == batchProcessedKey {
+			p.o.RecordHistogram(context.Background(), "batch.throughput", float64(kv.Value.AsInt64())/seconds,
+				attribute.String("span.name", p.spanName(s.Name())))
+			return
+		}
+	}
+}
+
+// Shutdown does nothing
+func (p *batchSpanProcessor) Shutdown(context.Context) error { return nil }
+
+// ForceFlush does nothing
+func (p *batchSpanProcessor) ForceFlush(context.Context) error { return nil }
@@ -0,0 +1,132 @@
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+func TestBatchProcessedAttributesAndThroughput(t *testing.T) {
+	tt := newTestTelemetry(t, WithBatchThroughputMetrics(true))
+
+	ctx, span := tt.StartSpan(context.Background(), "import")
+	tt.SetBatchSize(ctx, 10)
+	tt.IncrementProcessed(ctx, 4)
+	tt.IncrementProcessed(ctx, 6)
+	time.Sleep(time.Millisecond)
+	tt.EndSpan(span)
+
+	ended := tt.endedSpan(t, "import")
+	attrs := attrMap(ended.Attributes())
+	if attrs["batch.size"] != "10" || attrs["batch.processed"] != "10" {
+		t.Errorf("attributes = %v, want batch.size=10 and batch.processed=10", attrs)
+	}
+
+	points := histogramPoints(t, tt.metric(t, "batch.throughput"))
+	if len(points) != 1 || points[0].Count != 1 {
+		t.Fatalf("batch.throughput points = %+v, want a single recording", points)
+	}
+	want := 10 / ended.EndTime().Sub(ended.StartTime()).Seconds()
+	if math.Abs(points[0].Sum-want) > 1e-6*want {
+		t.Errorf("batch.throughput = %v, want %v items per second", points[0].Sum, want)
+	}
+	if name := attrMap(points[0].Attributes.ToSlice())["span.name"]; name != "import" {
+		t.Errorf("span.name = %q, want import", name)
+	}
+}
+
+func TestBatchThroughputNormalizesSpanNames(t *testing.T) {
+	tt := newTestTelemetry(t, WithBatchThroughputMetrics(true))
+	for _, name := range []string{"import /tenants/42", "import /tenants/43"} {
+		ctx, span := tt.StartSpan(context.Background(), name)
+		tt.IncrementProcessed(ctx, 5)
+		time.Sleep(time.Millisecond)
+		tt.EndSpan(span)
+	}
+
+	points := histogramPoints(t, tt.metric(t, "batch.throughput"))
+	if len(points) != 1 || points[0].Count != 2 {
+		t.Fatalf("batch.throughput points = %+v, want both spans under one name", points)
+	}
+	if name := attrMap(points[0].Attributes.ToSlice())["span.name"]; name != "import /tenants/{id}" {
+		t.Errorf("span.name = %q, want import /tenants/{id}", name)
+	}
+
+	p := &batchSpanProcessor{}
+	for i := 0; i < maxSpanNames; i++ {
+		p.spanName(fmt.Sprintf("job-%d", i))
+	}
+	if got := p.spanName("one-more"); got != otherSpanName {
+		t.Errorf("spanName() = %q beyond the limit, want %q", got, otherSpanName)
+	}
+}
+
+func TestBatchThroughputDisabledByDefault(t *testing.T) {
+	tt := newTestTelemetry(t)
+
+	ctx, span := tt.StartSpan(context.Background(), "import")
+	tt.IncrementProcessed(ctx, 3)
+	tt.EndSpan(span)
+
+	if got := attrMap(tt.endedSpan(t, "import").Attributes())["batch.processed"]; got != "3" {
+		t.Errorf("batch.processed = %q, want 3", got)
+	}
+	if _, ok := findMetric(tt.collect(t), "batch.throughput"); ok {
+		t.Error("batch.throughput recorded without WithBatchThroughputMetrics")
+	}
+}
+
+func TestSetIOSizesOnRecordingSpan(t *testing.T) {
+	tt := newTestTelemetry(t)
+
+	ctx, span := tt.StartSpan(context.Background(), "transform")
+	tt.SetIOSizes(ctx, 120, 118, 4096, 3900)
+	tt.EndSpan(span)
+
+	attrs := attrMap(tt.endedSpan(t, "transform").Attributes())
+	for key, want := range map[string]string{
+		"io.input.records":  "120",
+		"io.output.records": "118",
+		"io.input.bytes":    "4096",
+		"io.output.bytes":   "3900",
+	} {
+		if attrs[key] != want {
+			t.Errorf("%s = %q, want %q", key, attrs[key], want)
+		}
+	}
+}
+
+// attributeSpySpan is a non-recording span remembering the attributes set on it
+type attributeSpySpan struct {
+	noop.Span
+	attributes []attribute.KeyValue
+}
+
+func (s *attributeSpySpan) SetAttributes(kv ...attribute.KeyValue) {
+	s.attributes = append(s.attributes, kv...)
+}
+
+func TestSetIOSizesSkipsNonRecordingSpan(t *testing.T) {
+	tt := newTestTelemetry(t)
+	spy := &attributeSpySpan{}
+
+	tt.SetIOSizes(trace.ContextWithSpan(context.Background(), spy), 120, 118, 4096, 3900)
+	if len(spy.attributes) != 0 {
+		t.Errorf("attributes set on a non-recording span: %v", spy.attributes)
+	}
+
+	unsampled := newTestTelemetry(t, WithSampler(sdktrace.NeverSample()))
+	ctx, span := unsampled.StartSpan(context.Background(), "transform")
+	unsampled.SetIOSizes(ctx, 120, 118, 4096, 3900)
+	unsampled.EndSpan(span)
+	if spans := unsampled.endedSpans("transform"); len(spans) != 0 {
+		t.Errorf("unsampled span was recorded: %v", spans)
+	}
+}
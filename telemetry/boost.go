@@ -0,0 +1,82 @@
+// boost.go - Temporary sampling boosts for named operations
+
+package telemetry
+
+import (
+	"sync"
+	"time"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// samplingBoost is a sampler applied to an operation until a deadline
+type samplingBoost struct {
+	sampler sdktrace.Sampler
+	until   time.Time
+}
+
+// boostSampler samples root spans of boosted operations with the boost's sampler
+// until it expires, and defers to the wrapped sampler otherwise
+type boostSampler struct {
+	base sdktrace.Sampler
+	now  func() time.Time
+
+	mu     sync.RWMutex
+	boosts map[string]samplingBoost
+}
+
+// newBoostSampler wraps base so that sampling boosts take precedence over it
+func newBoostSampler(base sdktrace.Sampler) *boostSampler {
+	return &boostSampler{base: base, now: time.Now, boosts: make(map[string]samplingBoost)}
+}
+
+// boost applies ratio to root spans named operation for duration, replacing any
+// boost of the operation still active
+func (s *boostSampler) boost(operation string, ratio float64, duration time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.boosts[operation] = samplingBoost{
+		sampler: sdktrace.TraceIDRatioBased(ratio),
+		until:   s.now().Add(duration),
+	}
+}
+
+// ShouldSample applies an active boost of the span's name to root spans and drops
+// expired boosts
+func (s *boostSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	if trace.SpanContextFromContext(p.ParentContext).IsValid() {
+		return s.base.ShouldSample(p)
+	}
+	s.mu.RLock()
+	b, ok := s.boosts[p.Name]
+	s.mu.RUnlock()
+	if !ok {
+		return s.base.ShouldSample(p)
+	}
+	if s.now().Before(b.until) {
+		return b.sampler.ShouldSample(p)
+	}
+
+	s.mu.Lock()
+	if current, ok := s.boosts[p.Name]; ok && !s.now().Before(current.until) {
+		delete(s.boosts, p.Name)
+	}
+	s.mu.Unlock()
+	return s.base.ShouldSample(p)
+}
+
+// Description returns a description of the sampler
+func (s *boostSampler) Description() string {
+	return "BoostSampler{base:" + s.base.Description() + "}"
+}
+
+// BoostSampling samples new traces whose root span is named operation at ratio for
+// duration, e.g. to capture more checkout traces during an incident, and then reverts
+// to the configured sampler automatically. Sampling priorities still take precedence.
+func (o *OpenTelemetry) BoostSampling(operation string, ratio float64, duration time.Duration) {
+	if o.boosts == nil {
+		return
+	}
+	o.boosts.boost(operation, ratio, duration)
+}
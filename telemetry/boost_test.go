@@ -0,0 +1,54 @@
+package telemetry
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+func TestBoostSamplingExpiresAfterDuration(t *testing.T) {
+	tt := newTestTelemetry(t, WithSampler(sdktrace.ParentBased(sdktrace.NeverSample())))
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	tt.boosts.now = func() time.Time { return now }
+	sampled := func(ctx context.Context, name string) bool {
+		_, span := tt.StartSpan(ctx, name)
+		defer tt.EndSpan(span)
+		return span.SpanContext().IsSampled()
+	}
+
+	tt.BoostSampling("checkout", 1, time.Minute)
+	if !sampled(context.Background(), "checkout") {
+		t.Error("boosted operation not sampled")
+	}
+	if sampled(context.Background(), "search") {
+		t.Error("operation without a boost sampled, want the configured sampler")
+	}
+	if sampled(WithSamplingPriority(context.Background(), SamplingPriorityLow), "checkout") {
+		t.Error("low sampling priority overridden by the boost")
+	}
+
+	now = now.Add(59 * time.Second)
+	if !sampled(context.Background(), "checkout") {
+		t.Error("boost expired before its duration")
+	}
+	now = now.Add(time.Second)
+	if sampled(context.Background(), "checkout") {
+		t.Error("boosted operation still sampled after the boost expired")
+	}
+	if _, ok := tt.boosts.boosts["checkout"]; ok {
+		t.Error("expired boost kept")
+	}
+
+	tt.BoostSampling("checkout", 1, time.Minute)
+	tt.BoostSampling("checkout", 0, time.Minute)
+	if sampled(context.Background(), "checkout") {
+		t.Error("later boost of the operation did not replace the earlier one")
+	}
+}
+
+func TestBoostSamplingWithoutTracingIsNoop(t *testing.T) {
+	tt := newTestTelemetry(t, WithTracingEnabled(false))
+	tt.BoostSampling("checkout", 1, time.Minute)
+}
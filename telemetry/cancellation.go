@@ -0,0 +1,48 @@
+// cancellation.go - Recording context cancellation distinctly from errors
+
+package telemetry
+
+import (
+	"context"
+	"errors"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RecordCancellation records a cancelled event if ctx was cancelled, or a timeout
+// event with an error status if its deadline was exceeded, on the span in ctx.
+// It reports whether ctx was done.
+func (o *OpenTelemetry) RecordCancellation(ctx context.Context) bool {
+	return o.recordCancellation(ctx, ctx.Err())
+}
+
+// recordCancellation records err on the span in ctx if it is a context
+// cancellation or deadline error, and reports whether it was
+func (o *OpenTelemetry) recordCancellation(ctx context.Context, err error) bool {
+	var event, description string
+	switch {
+	case errors.Is(err, context.Canceled):
+		event = "cancelled"
+	case errors.Is(err, context.DeadlineExceeded):
+		event, description = "timeout", "deadline exceeded"
+	default:
+		return false
+	}
+	if !o.traceEnabled {
+		return true
+	}
+
+	span := trace.SpanFromContext(ctx)
+	if !span.IsRecording() {
+		return true
+	}
+	span.AddEvent(event, trace.WithAttributes(attribute.String("error.message", sanitizeString(err.Error()))))
+	if description != "" {
+		// A missed deadline is a failure of the operation, whereas a cancelled
+		// caller is not, so only timeouts mark the span as failed.
+		span.SetStatus(codes.Error, description)
+	}
+	return true
+}
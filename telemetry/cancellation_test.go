@@ -0,0 +1,76 @@
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/codes"
+)
+
+func TestRecordCancellation(t *testing.T) {
+	tt := newTestTelemetry(t)
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancelled, span := tt.StartSpan(cancelled, "cancelled")
+	cancel()
+	if !tt.RecordCancellation(cancelled) {
+		t.Error("RecordCancellation() = false for a cancelled context")
+	}
+	tt.EndSpan(span)
+
+	expired, cancelExpired := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancelExpired()
+	expired, span = tt.StartSpan(expired, "expired")
+	if !tt.RecordCancellation(expired) {
+		t.Error("RecordCancellation() = false for an expired context")
+	}
+	tt.EndSpan(span)
+
+	active, span := tt.StartSpan(context.Background(), "active")
+	if tt.RecordCancellation(active) {
+		t.Error("RecordCancellation() = true for an active context")
+	}
+	tt.EndSpan(span)
+
+	tests := []struct {
+		span   string
+		event  string
+		status codes.Code
+	}{
+		{"cancelled", "cancelled", codes.Unset},
+		{"expired", "timeout", codes.Error},
+	}
+	for _, tc := range tests {
+		s := tt.endedSpan(t, tc.span)
+		if events := s.Events(); len(events) != 1 || events[0].Name != tc.event {
+			t.Errorf("%s span events = %+v, want a single %s event", tc.span, events, tc.event)
+		}
+		if s.Status().Code != tc.status {
+			t.Errorf("%s span status = %v, want %v", tc.span, s.Status(), tc.status)
+		}
+	}
+	if s := tt.endedSpan(t, "active"); len(s.Events()) != 0 || s.Status().Code != codes.Unset {
+		t.Errorf("active span events = %+v, status = %v, want none", s.Events(), s.Status())
+	}
+}
+
+func TestWithSpanRecordsCancellationInsteadOfError(t *testing.T) {
+	tt := newTestTelemetry(t)
+
+	err := tt.WithSpan(context.Background(), "call", func(context.Context) error {
+		return fmt.Errorf("calling upstream: %w", context.Canceled)
+	})
+	if err == nil {
+		t.Fatal("WithSpan() error = nil, want the error returned by fn")
+	}
+
+	s := tt.endedSpan(t, "call")
+	if events := s.Events(); len(events) != 1 || events[0].Name != "cancelled" {
+		t.Errorf("events = %+v, want a single cancelled event and no exception", events)
+	}
+	if s.Status().Code == codes.Error {
+		t.Errorf("status = %v, want a cancelled call not marked as failed", s.Status())
+	}
+}
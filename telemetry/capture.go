@@ -0,0 +1,120 @@
+// capture.go - Capturing finished spans to an OTLP file and replaying them later
+
+package telemetry
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/encoding/protodelim"
+)
+
+// fileTraceClient is an OTLP trace client appending each uploaded batch to a file
+// as a length-delimited TracesData protobuf message
+type fileTraceClient struct {
+	path string
+
+	mu   sync.Mutex
+	file *os.File
+	w    *bufio.Writer
+}
+
+// newTraceCaptureExporter returns an OTLP exporter writing spans to the file at path
+func newTraceCaptureExporter(ctx context.Context, path string) (*otlptrace.Exporter, error) {
+	return otlptrace.New(ctx, &fileTraceClient{path: path})
+}
+
+// Start opens the capture file for appending
+func (c *fileTraceClient) Start(context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	file, err := os.OpenFile(c.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open trace capture file: %w", err)
+	}
+	c.file = file
+	c.w = bufio.NewWriter(file)
+	return nil
+}
+
+// Stop flushes and closes the capture file
+func (c *fileTraceClient) Stop(context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.file == nil {
+		return nil
+	}
+	err := c.w.Flush()
+	if cErr := c.file.Close(); err == nil {
+		err = cErr
+	}
+	c.file = nil
+	return err
+}
+
+// UploadTraces appends the spans to the capture file
+func (c *fileTraceClient) UploadTraces(_ context.Context, protoSpans []*tracepb.ResourceSpans) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.file == nil {
+		return errors.New("trace capture file is closed")
+	}
+	if _, err := protodelim.MarshalTo(c.w, &tracepb.TracesData{ResourceSpans: protoSpans}); err != nil {
+		return fmt.Errorf("failed to write captured spans: %w", err)
+	}
+	return c.w.Flush()
+}
+
+// ReplayTraceFile reads spans captured with WithTraceCapture from path and exports
+// them to the OTLP gRPC collector at endpoint. The connection uses the transport
+// security of WithTLSConfig, WithCACertFile and WithInsecure among opts, like the
+// exporters of NewOpenTelemetryWithOptions, and ctx bounds the whole replay.
+func ReplayTraceFile(ctx context.Context, path, endpoint string, opts ...Option) (err error) {
+	cfg := defaultOtelConfig()
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	tlsConfig, err := loadTLSConfig(cfg)
+	if err != nil {
+		return err
+	}
+	cfg.tlsConfig = tlsConfig
+
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open trace capture file: %w", err)
+	}
+	defer file.Close()
+
+	client := otlptracegrpc.NewClient(append(grpcTraceSecurityOptions(cfg), otlptracegrpc.WithEndpoint(endpoint))...)
+	if err := client.Start(ctx); err != nil {
+		return fmt.Errorf("failed to start trace client: %w", err)
+	}
+	defer func() {
+		if stopErr := client.Stop(ctx); stopErr != nil && err == nil {
+			err = fmt.Errorf("failed to stop trace client: %w", stopErr)
+		}
+	}()
+
+	r := bufio.NewReader(file)
+	for {
+		var data tracepb.TracesData
+		if err := protodelim.UnmarshalFrom(r, &data); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("failed to read captured spans: %w", err)
+		}
+		if err := client.UploadTraces(ctx, data.ResourceSpans); err != nil {
+			return fmt.Errorf("failed to replay captured spans: %w", err)
+		}
+	}
+}
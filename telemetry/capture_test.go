@@ -0,0 +1,99 @@
+package telemetry
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestTraceCaptureAndReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spans.otlp")
+	live := newStubCollector(t)
+	tt := newTestTelemetry(t,
+		WithTraceEndpoint(live.addr),
+		WithMetricEndpoint(live.addr),
+		WithTraceCapture(path))
+	ctx := context.Background()
+
+	parent, span := tt.StartSpan(ctx, "checkout")
+	_, child := tt.StartSpan(parent, "charge card")
+	tt.EndSpan(child)
+	tt.EndSpan(span)
+	if err := tt.traceProvider.ForceFlush(ctx); err != nil {
+		t.Fatalf("ForceFlush() error = %v", err)
+	}
+	_, span = tt.StartSpan(ctx, "refund")
+	tt.EndSpan(span)
+	if err := tt.traceProvider.ForceFlush(ctx); err != nil {
+		t.Fatalf("ForceFlush() error = %v", err)
+	}
+
+	if info, err := os.Stat(path); err != nil || info.Size() == 0 {
+		t.Fatalf("capture file not written: %v", err)
+	}
+
+	replayed := newStubCollector(t)
+	if err := ReplayTraceFile(ctx, path, replayed.addr, WithInsecure()); err != nil {
+		t.Fatalf("ReplayTraceFile() error = %v", err)
+	}
+	got := replayed.receivedSpans()
+	sort.Strings(got)
+	want := []string{"charge card", "checkout", "refund"}
+	if len(got) != len(want) {
+		t.Fatalf("replay collector received spans %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("replay collector received spans %q, want %q", got, want)
+		}
+	}
+}
+
+func TestReplayTraceFileErrors(t *testing.T) {
+	ctx := context.Background()
+	if err := ReplayTraceFile(ctx, filepath.Join(t.TempDir(), "missing.otlp"), "127.0.0.1:1", WithInsecure()); err == nil {
+		t.Error("ReplayTraceFile() error = nil for a missing file")
+	}
+
+	corrupt := filepath.Join(t.TempDir(), "corrupt.otlp")
+	if err := os.WriteFile(corrupt, []byte{0x05, 0xff, 0xff}, 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	collector := newStubCollector(t)
+	if err := ReplayTraceFile(ctx, corrupt, collector.addr, WithInsecure()); err == nil {
+		t.Error("ReplayTraceFile() error = nil for a corrupt file")
+	}
+	if err := ReplayTraceFile(ctx, corrupt, collector.addr, WithCACertFile(filepath.Join(t.TempDir(), "missing.pem"))); err == nil {
+		t.Error("ReplayTraceFile() error = nil for a missing CA certificate file")
+	}
+}
+
+func TestReplayTraceFileHonoursContextAndTLS(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spans.otlp")
+	live := newStubCollector(t)
+	tt := newTestTelemetry(t,
+		WithTraceEndpoint(live.addr),
+		WithMetricEndpoint(live.addr),
+		WithTraceCapture(path))
+	ctx := context.Background()
+	_, span := tt.StartSpan(ctx, "checkout")
+	tt.EndSpan(span)
+	if err := tt.traceProvider.ForceFlush(ctx); err != nil {
+		t.Fatalf("ForceFlush() error = %v", err)
+	}
+
+	// Without WithInsecure the replay dials with TLS, which the plaintext collector
+	// never completes, so the deadline ends the replay
+	collector := newStubCollector(t)
+	ctx, cancel := context.WithTimeout(ctx, 500*time.Millisecond)
+	defer cancel()
+	if err := ReplayTraceFile(ctx, path, collector.addr); err == nil {
+		t.Error("ReplayTraceFile() error = nil for a TLS replay to a plaintext collector")
+	}
+	if got := collector.receivedSpans(); len(got) != 0 {
+		t.Errorf("plaintext collector received spans %q over TLS", got)
+	}
+}
@@ -0,0 +1,50 @@
+// circuit.go - Circuit breaker state instrumentation
+
+package telemetry
+
+import (
+	"context"
+	"strings"
+
+	"github.com/sadco-io/sad-go-logger/logger"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// circuitStateValues encodes circuit breaker states as circuit_breaker.state gauge values
+var circuitStateValues = map[string]float64{
+	"closed":    0,
+	"half":      1,
+	"half-open": 1,
+	"half_open": 1,
+	"open":      2,
+}
+
+// RecordCircuitState records a state change of the named circuit breaker: the
+// circuit_breaker.state gauge, encoded as closed=0, half-open=1 and open=2, a
+// circuit_breaker.transitions counter increment, and a state change event on the
+// span in ctx. Unknown states are logged and only recorded as an event.
+func (o *OpenTelemetry) RecordCircuitState(ctx context.Context, name, state string) {
+	attrs := []attribute.KeyValue{
+		attribute.String("circuit_breaker.name", name),
+		attribute.String("circuit_breaker.state", state),
+	}
+
+	if value, ok := circuitStateValues[strings.ToLower(state)]; ok {
+		o.RecordGauge(ctx, "circuit_breaker.state", value, attrs[0])
+		o.IncrementCounter(ctx, "circuit_breaker.transitions", 1, attrs...)
+	} else {
+		logger.Log.Warn("Unknown circuit breaker state",
+			zap.String("name", name),
+			zap.String("state", state))
+	}
+
+	if !o.traceEnabled {
+		return
+	}
+	span := trace.SpanFromContext(ctx)
+	if span.IsRecording() {
+		span.AddEvent("circuit_breaker.state_change", trace.WithAttributes(sanitizeAttributes(attrs)...))
+	}
+}
@@ -0,0 +1,47 @@
+package telemetry
+
+import (
+	"context"
+	"testing"
+)
+
+func TestRecordCircuitStateRecordsGaugeCounterAndEvent(t *testing.T) {
+	logs := observeLogs(t)
+	tt := newTestTelemetry(t)
+	ctx, span := tt.StartSpan(context.Background(), "call payments")
+
+	tt.RecordCircuitState(ctx, "payments", "open")
+	tt.RecordCircuitState(ctx, "payments", "Half-Open")
+	tt.RecordCircuitState(ctx, "inventory", "open")
+	tt.RecordCircuitState(ctx, "inventory", "tripped")
+	tt.EndSpan(span)
+
+	states := make(map[string]float64)
+	for _, p := range gaugePoints(t, tt.metric(t, "circuit_breaker.state")) {
+		name, _ := p.Attributes.Value("circuit_breaker.name")
+		states[name.AsString()] = p.Value
+	}
+	if len(states) != 2 || states["payments"] != 1 || states["inventory"] != 2 {
+		t.Errorf("circuit_breaker.state = %v, want payments half-open (1) and inventory open (2)", states)
+	}
+
+	var transitions float64
+	for _, p := range sumPoints(t, tt.metric(t, "circuit_breaker.transitions")) {
+		transitions += p.Value
+	}
+	if transitions != 3 {
+		t.Errorf("circuit_breaker.transitions = %v, want 3 known transitions", transitions)
+	}
+	if n := len(logs.FilterMessage("Unknown circuit breaker state").All()); n != 1 {
+		t.Errorf("logged %d unknown states, want 1", n)
+	}
+
+	events := tt.endedSpan(t, "call payments").Events()
+	if len(events) != 4 {
+		t.Fatalf("got %d events, want one per state change", len(events))
+	}
+	last := attrMap(events[3].Attributes)
+	if events[3].Name != "circuit_breaker.state_change" || last["circuit_breaker.name"] != "inventory" || last["circuit_breaker.state"] != "tripped" {
+		t.Errorf("last event = %s %v, want the unknown state change", events[3].Name, last)
+	}
+}
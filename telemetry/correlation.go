@@ -0,0 +1,48 @@
+// correlation.go - Trace and span IDs for correlating logs with traces
+
+package telemetry
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// sampledSpanContext returns the span context of ctx if it belongs to a sampled,
+// and thus exported, trace
+func sampledSpanContext(ctx context.Context) (trace.SpanContext, bool) {
+	sc := trace.SpanContextFromContext(ctx)
+	return sc, sc.IsValid() && sc.IsSampled()
+}
+
+// TraceIDFromContext returns the hex trace ID of the span in ctx, or "" if there is
+// no span or its trace is not sampled
+func TraceIDFromContext(ctx context.Context) string {
+	if sc, ok := sampledSpanContext(ctx); ok {
+		return sc.TraceID().String()
+	}
+	return ""
+}
+
+// SpanIDFromContext returns the hex span ID of the span in ctx, or "" if there is
+// no span or its trace is not sampled
+func SpanIDFromContext(ctx context.Context) string {
+	if sc, ok := sampledSpanContext(ctx); ok {
+		return sc.SpanID().String()
+	}
+	return ""
+}
+
+// LogFields returns trace_id and span_id logger fields for the span in ctx, to
+// stitch log lines to the trace, or no fields if its trace is not sampled
+func LogFields(ctx context.Context) []zap.Field {
+	sc, ok := sampledSpanContext(ctx)
+	if !ok {
+		return nil
+	}
+	return []zap.Field{
+		zap.String("trace_id", sc.TraceID().String()),
+		zap.String("span_id", sc.SpanID().String()),
+	}
+}
@@ -0,0 +1,52 @@
+package telemetry
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestCorrelationIDsFromRecordingSpan(t *testing.T) {
+	tt := newTestTelemetry(t)
+	ctx, span := tt.StartSpan(context.Background(), "handle")
+	defer tt.EndSpan(span)
+	sc := span.SpanContext()
+
+	if got := TraceIDFromContext(ctx); got != sc.TraceID().String() || len(got) != 32 {
+		t.Errorf("TraceIDFromContext() = %q, want %s", got, sc.TraceID())
+	}
+	if got := SpanIDFromContext(ctx); got != sc.SpanID().String() || len(got) != 16 {
+		t.Errorf("SpanIDFromContext() = %q, want %s", got, sc.SpanID())
+	}
+
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range LogFields(ctx) {
+		f.AddTo(enc)
+	}
+	if enc.Fields["trace_id"] != sc.TraceID().String() || enc.Fields["span_id"] != sc.SpanID().String() || len(enc.Fields) != 2 {
+		t.Errorf("LogFields() = %v, want the trace_id and span_id of the span", enc.Fields)
+	}
+}
+
+func TestCorrelationIDsWithoutSampledSpan(t *testing.T) {
+	unsampled := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: trace.TraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36},
+		SpanID:  trace.SpanID{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7},
+	}))
+	for name, ctx := range map[string]context.Context{
+		"no span":   context.Background(),
+		"unsampled": unsampled,
+	} {
+		if got := TraceIDFromContext(ctx); got != "" {
+			t.Errorf("%s: TraceIDFromContext() = %q, want empty", name, got)
+		}
+		if got := SpanIDFromContext(ctx); got != "" {
+			t.Errorf("%s: SpanIDFromContext() = %q, want empty", name, got)
+		}
+		if fields := LogFields(ctx); len(fields) != 0 {
+			t.Errorf("%s: LogFields() = %v, want none", name, fields)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+// database.go - Database operation instrumentation helpers and connection pool metrics
+
+package telemetry
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	// sqlStringLiteral matches single- and double-quoted literals, with quotes
+	// escaped either by doubling or, as in MySQL, by a backslash
+	sqlStringLiteral = regexp.MustCompile(`'(?:[^'\\]|\\.|'')*'|"(?:[^"\\]|\\.|"")*"`)
+	// sqlNumericToken matches words containing a digit, so that digits inside
+	// identifiers and positional placeholders such as $1 are seen as a whole
+	sqlNumericToken   = regexp.MustCompile(`[\w$]*\d[\w$.]*`)
+	sqlNumericLiteral = regexp.MustCompile(`^\d+(?:\.\d+)?$`)
+)
+
+// TrackDBOperation records a database operation as a client span with the rows
+// affected, duration and a redacted statement, and records err if non-nil
+func (o *OpenTelemetry) TrackDBOperation(ctx context.Context, system, operation, statement string, rowsAffected int64, duration time.Duration, err error) {
+	if !o.traceEnabled {
+		return
+	}
+	name := operation
+	if name == "" {
+		name = "DB Operation"
+	}
+	ctx, span := o.tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient))
+	defer o.EndSpan(span)
+
+	span.SetAttributes(sanitizeAttributes([]attribute.KeyValue{
+		semconv.DBSystemKey.String(system),
+		semconv.DBOperationKey.String(operation),
+		semconv.DBStatementKey.String(redactStatement(statement)),
+		attribute.Int64("db.rows_affected", rowsAffected),
+		attribute.Int64("db.duration_ms", duration.Milliseconds()),
+	})...)
+	o.RecordError(ctx, err)
+}
+
+// redactStatement replaces string and numeric literals in a SQL statement with
+// placeholders so that values never leave the process. Double-quoted strings are
+// redacted too, since MySQL treats them as literals. Identifiers and positional
+// placeholders containing digits are kept.
+func redactStatement(statement string) string {
+	statement = sqlStringLiteral.ReplaceAllString(statement, "?")
+	return sqlNumericToken.ReplaceAllStringFunc(statement, func(token string) string {
+		if sqlNumericLiteral.MatchString(token) {
+			return "?"
+		}
+		return token
+	})
+}
+
+// MonitorDBPool registers gauges reporting the connection pool statistics of db on
+// each metric collection: db.sql.connections.open, db.sql.connections.in_use,
+// db.sql.connections.idle, db.sql.connections.wait_count and
+// db.sql.connections.wait_duration in milliseconds. db.Stats() is read at most once
+// per interval, however often metrics are collected. The gauges are unregistered
+// on Shutdown.
+func (o *OpenTelemetry) MonitorDBPool(db *sql.DB, interval time.Duration) error {
+	if !o.metricsEnabled {
+		return nil
+	}
+	open, err := o.meter.Int64ObservableGauge("db.sql.connections.open")
+	if err != nil {
+		return fmt.Errorf("failed to create db pool gauge: %w", err)
+	}
+	inUse, err := o.meter.Int64ObservableGauge("db.sql.connections.in_use")
+	if err != nil {
+		return fmt.Errorf("failed to create db pool gauge: %w", err)
+	}
+	idle, err := o.meter.Int64ObservableGauge("db.sql.connections.idle")
+	if err != nil {
+		return fmt.Errorf("failed to create db pool gauge: %w", err)
+	}
+	waitCount, err := o.meter.Int64ObservableGauge("db.sql.connections.wait_count")
+	if err != nil {
+		return fmt.Errorf("failed to create db pool gauge: %w", err)
+	}
+	waitDuration, err := o.meter.Float64ObservableGauge("db.sql.connections.wait_duration", metric.WithUnit("ms"))
+	if err != nil {
+		return fmt.Errorf("failed to create db pool gauge: %w", err)
+	}
+
+	var mu sync.Mutex
+	var stats sql.DBStats
+	var readAt time.Time
+	registration, err := o.meter.RegisterCallback(func(_ context.Context, observer metric.Observer) error {
+		mu.Lock()
+		if readAt.IsZero() || time.Since(readAt) >= interval {
+			stats, readAt = db.Stats(), time.Now()
+		}
+		s := stats
+		mu.Unlock()
+
+		observer.ObserveInt64(open, int64(s.OpenConnections))
+		observer.ObserveInt64(inUse, int64(s.InUse))
+		observer.ObserveInt64(idle, int64(s.Idle))
+		observer.ObserveInt64(waitCount, s.WaitCount)
+		observer.ObserveFloat64(waitDuration, float64(s.WaitDuration.Milliseconds()))
+		return nil
+	}, open, inUse, idle, waitCount, waitDuration)
+	if err != nil {
+		return fmt.Errorf("failed to register db pool callback: %w", err)
+	}
+
+	o.dbPoolsMu.Lock()
+	o.dbPools = append(o.dbPools, registration)
+	o.dbPoolsMu.Unlock()
+	return nil
+}
+
+// unregisterDBPools stops reporting the pools registered with MonitorDBPool
+func (o *OpenTelemetry) unregisterDBPools() error {
+	o.dbPoolsMu.Lock()
+	registrations := o.dbPools
+	o.dbPools = nil
+	o.dbPoolsMu.Unlock()
+
+	var err error
+	for _, registration := range registrations {
+		if uErr := registration.Unregister(); uErr != nil {
+			err = uErr
+		}
+	}
+	return err
+}
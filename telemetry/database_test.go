@@ -0,0 +1,203 @@
+package telemetry
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestRedactStatement(t *testing.T) {
+	tests := []struct {
+		statement string
+		want      string
+	}{
+		{"SELECT * FROM users WHERE name = 'O''Brien' AND age > 42", "SELECT * FROM users WHERE name = ? AND age > ?"},
+		{"UPDATE accounts SET balance = 10.50 WHERE id = 7", "UPDATE accounts SET balance = ? WHERE id = ?"},
+		{"SELECT * FROM orders WHERE id = $1 AND status = $2", "SELECT * FROM orders WHERE id = $1 AND status = $2"},
+		{"SELECT t1.col2 FROM table2 t1 LIMIT 5", "SELECT t1.col2 FROM table2 t1 LIMIT ?"},
+		{"INSERT INTO events_2024 (v) VALUES (3)", "INSERT INTO events_2024 (v) VALUES (?)"},
+		{`SELECT * FROM users WHERE name = "Jane" AND city = "São ""Paulo"""`, "SELECT * FROM users WHERE name = ? AND city = ?"},
+		{`SELECT * FROM notes WHERE body = 'it\'s' AND tag = "say \"hi\"" AND id = 9`, "SELECT * FROM notes WHERE body = ? AND tag = ? AND id = ?"},
+		{`INSERT INTO paths (p) VALUES ('C:\\temp\\'), ('x')`, "INSERT INTO paths (p) VALUES (?), (?)"},
+	}
+	for _, tc := range tests {
+		if got := redactStatement(tc.statement); got != tc.want {
+			t.Errorf("redactStatement(%q) = %q, want %q", tc.statement, got, tc.want)
+		}
+	}
+}
+
+func TestTrackDBOperation(t *testing.T) {
+	tt := newTestTelemetry(t)
+	ctx := context.Background()
+
+	tt.TrackDBOperation(ctx, "postgresql", "UPDATE", "UPDATE users SET plan = 'pro' WHERE id = $1 AND age > 30",
+		3, 12*time.Millisecond, nil)
+	tt.TrackDBOperation(ctx, "postgresql", "DELETE", "DELETE FROM users WHERE id = 9",
+		0, time.Millisecond, errors.New("deadlock detected"))
+
+	update := tt.endedSpan(t, "UPDATE")
+	if update.SpanKind() != trace.SpanKindClient {
+		t.Errorf("span kind = %v, want client", update.SpanKind())
+	}
+	attrs := attrMap(update.Attributes())
+	for key, want := range map[string]string{
+		"db.system":        "postgresql",
+		"db.operation":     "UPDATE",
+		"db.statement":     "UPDATE users SET plan = ? WHERE id = $1 AND age > ?",
+		"db.rows_affected": "3",
+		"db.duration_ms":   "12",
+	} {
+		if attrs[key] != want {
+			t.Errorf("attribute %s = %q, want %q", key, attrs[key], want)
+		}
+	}
+	if update.Status().Code == codes.Error {
+		t.Errorf("successful operation has error status %q", update.Status().Description)
+	}
+
+	failed := tt.endedSpan(t, "DELETE")
+	if got := failed.Status(); got.Code != codes.Error || got.Description != "deadlock detected" {
+		t.Errorf("failed operation status = %+v, want error with the message", got)
+	}
+	if len(failed.Events()) != 1 || failed.Events()[0].Name != "exception" {
+		t.Errorf("failed operation events = %+v, want the recorded error", failed.Events())
+	}
+}
+
+// fakeDriver is a database/sql driver whose connections support nothing but being
+// opened, pooled and closed, enough to drive the pool statistics
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) { return fakeConn{}, nil }
+
+type fakeConn struct{}
+
+func (fakeConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (fakeConn) Close() error                        { return nil }
+func (fakeConn) Begin() (driver.Tx, error)           { return nil, errors.New("not supported") }
+
+var registerFakeDriver sync.Once
+
+// openFakeDB opens a pool of fake connections, closed when the test ends
+func openFakeDB(t *testing.T) *sql.DB {
+	t.Helper()
+	registerFakeDriver.Do(func() { sql.Register("telemetry-fake", fakeDriver{}) })
+	db, err := sql.Open("telemetry-fake", "")
+	if err != nil {
+		t.Fatalf("sql.Open() error = %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	return db
+}
+
+// dbPoolStats returns the value of each db.sql.connections gauge
+func dbPoolStats(t *testing.T, tt *testTelemetry) map[string]float64 {
+	t.Helper()
+	stats := make(map[string]float64)
+	for _, name := range []string{"open", "in_use", "idle", "wait_count", "wait_duration"} {
+		m, ok := findMetric(tt.collect(t), "db.sql.connections."+name)
+		if !ok {
+			continue
+		}
+		switch data := m.Data.(type) {
+		case metricdata.Gauge[int64]:
+			stats[name] = float64(data.DataPoints[0].Value)
+		case metricdata.Gauge[float64]:
+			stats[name] = data.DataPoints[0].Value
+		}
+	}
+	return stats
+}
+
+func TestMonitorDBPoolReportsStats(t *testing.T) {
+	tt := newTestTelemetry(t)
+	db := openFakeDB(t)
+	db.SetMaxOpenConns(2)
+	ctx := context.Background()
+
+	if err := tt.MonitorDBPool(db, 0); err != nil {
+		t.Fatalf("MonitorDBPool() error = %v", err)
+	}
+
+	first, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatalf("db.Conn() error = %v", err)
+	}
+	second, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatalf("db.Conn() error = %v", err)
+	}
+	// The pool is exhausted, so a third request waits until it times out
+	waitCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if _, err := db.Conn(waitCtx); err == nil {
+		t.Fatal("db.Conn() succeeded on an exhausted pool")
+	}
+	_ = second.Close()
+
+	got := dbPoolStats(t, tt)
+	want := db.Stats()
+	for name, value := range map[string]float64{
+		"open":       2,
+		"in_use":     1,
+		"idle":       1,
+		"wait_count": 1,
+	} {
+		if got[name] != value {
+			t.Errorf("db.sql.connections.%s = %v, want %v", name, got[name], value)
+		}
+	}
+	if got["wait_duration"] != float64(want.WaitDuration.Milliseconds()) || got["wait_duration"] < 15 {
+		t.Errorf("db.sql.connections.wait_duration = %v, want %v ms", got["wait_duration"], want.WaitDuration.Milliseconds())
+	}
+	_ = first.Close()
+}
+
+func TestMonitorDBPoolReadsStatsOncePerInterval(t *testing.T) {
+	tt := newTestTelemetry(t)
+	db := openFakeDB(t)
+	ctx := context.Background()
+
+	if err := tt.MonitorDBPool(db, time.Hour); err != nil {
+		t.Fatalf("MonitorDBPool() error = %v", err)
+	}
+	if got := dbPoolStats(t, tt)["open"]; got != 0 {
+		t.Fatalf("db.sql.connections.open = %v, want 0 before any connection", got)
+	}
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatalf("db.Conn() error = %v", err)
+	}
+	defer conn.Close()
+	if got := dbPoolStats(t, tt)["open"]; got != 0 {
+		t.Errorf("db.sql.connections.open = %v within the interval, want the cached 0", got)
+	}
+}
+
+func TestMonitorDBPoolGaugesStopWhenUnregistered(t *testing.T) {
+	tt := newTestTelemetry(t)
+	db := openFakeDB(t)
+
+	if err := tt.MonitorDBPool(db, 0); err != nil {
+		t.Fatalf("MonitorDBPool() error = %v", err)
+	}
+	if len(dbPoolStats(t, tt)) != 5 {
+		t.Fatal("db pool gauges were not reported")
+	}
+	if err := tt.unregisterDBPools(); err != nil {
+		t.Fatalf("unregisterDBPools() error = %v", err)
+	}
+	if stats := dbPoolStats(t, tt); len(stats) != 0 {
+		t.Errorf("db pool gauges reported after unregistering: %v", stats)
+	}
+}
@@ -0,0 +1,31 @@
+// drop_rules.go - Dropping metric data points carrying specific attribute values
+
+package telemetry
+
+import (
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// metricDropRule drops data points of an instrument whose attribute key has a given value
+type metricDropRule struct {
+	key   attribute.Key
+	value string
+}
+
+// metricAttributes returns the normalized attributes of a data point of the named
+// instrument, and false if a drop rule matches them and the point must not be recorded
+func (o *OpenTelemetry) metricAttributes(name string, attributes []attribute.KeyValue) ([]attribute.KeyValue, bool) {
+	normalized := o.normalizeAttributes(attributes)
+	rules := o.config.metricDropRules[name]
+	if len(rules) == 0 {
+		return normalized, true
+	}
+	for _, kv := range normalized {
+		for _, rule := range rules {
+			if kv.Key == rule.key && kv.Value.Emit() == rule.value {
+				return nil, false
+			}
+		}
+	}
+	return normalized, true
+}
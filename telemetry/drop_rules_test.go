@@ -0,0 +1,28 @@
+package telemetry
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+func TestMetricDropRuleDropsMatchingPoints(t *testing.T) {
+	tt := newTestTelemetry(t, WithMetricDropRule("http.requests", "user_agent", "kube-probe/1.29"))
+	ctx := context.Background()
+
+	tt.IncrementCounter(ctx, "http.requests", 1, attribute.String("user_agent", "kube-probe/1.29"))
+	tt.IncrementCounter(ctx, "http.requests", 1, attribute.String("user_agent", "Mozilla/5.0"))
+	tt.IncrementCounter(ctx, "http.errors", 1, attribute.String("user_agent", "kube-probe/1.29"))
+
+	points := sumPoints(t, tt.metric(t, "http.requests"))
+	if len(points) != 1 {
+		t.Fatalf("got %d http.requests points, want only the non-matching one: %+v", len(points), points)
+	}
+	if got := attrMap(points[0].Attributes.ToSlice())["user_agent"]; got != "Mozilla/5.0" || points[0].Value != 1 {
+		t.Errorf("http.requests point = %s with value %v, want Mozilla/5.0 with value 1", got, points[0].Value)
+	}
+	if points := sumPoints(t, tt.metric(t, "http.errors")); len(points) != 1 {
+		t.Errorf("got %d http.errors points, want the rule limited to http.requests", len(points))
+	}
+}
@@ -0,0 +1,31 @@
+// error_handler.go - Handling of OpenTelemetry SDK errors, such as failed exports
+
+package telemetry
+
+import (
+	"context"
+
+	"github.com/sadco-io/sad-go-logger/logger"
+	"go.uber.org/zap"
+)
+
+// exportErrorsMetric counts the errors reported by the OpenTelemetry SDK
+const exportErrorsMetric = "telemetry.export.errors"
+
+// handleOTelError is installed as the global OpenTelemetry error handler. It logs
+// err and counts it in the telemetry.export.errors counter. Recording the counter
+// may itself report an error and re-enter the handler; such errors are only added
+// to the pending count, which is recorded by the next handler call, so the handler
+// never recurses or loops.
+func (o *OpenTelemetry) handleOTelError(err error) {
+	logger.Log.Error("OpenTelemetry error", zap.Error(err))
+
+	o.exportErrorsPending.Add(1)
+	if !o.exportErrorsHandling.CompareAndSwap(false, true) {
+		return
+	}
+	defer o.exportErrorsHandling.Store(false)
+	if n := o.exportErrorsPending.Swap(0); n > 0 {
+		o.IncrementCounter(context.Background(), exportErrorsMetric, float64(n))
+	}
+}
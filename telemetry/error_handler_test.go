@@ -0,0 +1,48 @@
+package telemetry
+
+import (
+	"errors"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+)
+
+func exportErrors(t *testing.T, tt *testTelemetry) float64 {
+	t.Helper()
+	var total float64
+	for _, p := range sumPoints(t, tt.metric(t, exportErrorsMetric)) {
+		total += p.Value
+	}
+	return total
+}
+
+func TestSDKErrorsAreLoggedAndCountedOnce(t *testing.T) {
+	logs := observeLogs(t)
+	tt := newTestTelemetry(t)
+
+	otel.Handle(errors.New("traces export: connection refused"))
+	otel.Handle(errors.New("metrics export: connection refused"))
+	if got := exportErrors(t, tt); got != 2 {
+		t.Errorf("%s = %v, want each error counted once", exportErrorsMetric, got)
+	}
+	if n := len(logs.FilterMessage("OpenTelemetry error").All()); n != 2 {
+		t.Errorf("logged %d SDK errors, want 2", n)
+	}
+}
+
+func TestSDKErrorDuringHandlingIsCountedByNextCall(t *testing.T) {
+	tt := newTestTelemetry(t)
+
+	// An error reported while the handler records the counter must not recurse
+	tt.exportErrorsHandling.Store(true)
+	tt.handleOTelError(errors.New("re-entered"))
+	tt.exportErrorsHandling.Store(false)
+	if _, ok := findMetric(tt.collect(t), exportErrorsMetric); ok {
+		t.Error("re-entrant error recorded immediately, want it left pending")
+	}
+
+	tt.handleOTelError(errors.New("export failed"))
+	if got := exportErrors(t, tt); got != 2 {
+		t.Errorf("%s = %v, want the pending and the new error counted once each", exportErrorsMetric, got)
+	}
+}
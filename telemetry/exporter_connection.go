@@ -0,0 +1,95 @@
+// exporter_connection.go - Connectivity of the gRPC trace exporters, exported as a gauge
+
+package telemetry
+
+import (
+	"context"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+)
+
+const (
+	// exporterConnectedMetric is 1 while an exporter connection is ready and 0 otherwise
+	exporterConnectedMetric = "telemetry.exporter.connected"
+
+	// defaultGRPCEndpoint is the endpoint of the OTLP/gRPC exporters when none is set
+	defaultGRPCEndpoint = "localhost:4317"
+)
+
+// exporterConnection is a gRPC connection of a trace exporter whose state is watched
+type exporterConnection struct {
+	endpoint  string
+	conn      *grpc.ClientConn
+	connected atomic.Bool
+}
+
+// newExporterConnection creates the gRPC connection of the trace exporter for
+// endpoint, with the configured transport security
+func newExporterConnection(endpoint string, cfg otelConfig) (*exporterConnection, error) {
+	if endpoint == "" {
+		endpoint = defaultGRPCEndpoint
+	}
+	conn, err := grpc.NewClient(endpoint, grpc.WithTransportCredentials(grpcTransportCredentials(cfg)))
+	if err != nil {
+		return nil, err
+	}
+	return &exporterConnection{endpoint: endpoint, conn: conn}, nil
+}
+
+// watch tracks the state of the connection until ctx is done. Idle connections are
+// reconnected, so the state reflects the reachability of the collector between
+// exports.
+func (c *exporterConnection) watch(ctx context.Context) {
+	for {
+		state := c.conn.GetState()
+		c.connected.Store(state == connectivity.Ready)
+		if state == connectivity.Idle {
+			c.conn.Connect()
+		}
+		if !c.conn.WaitForStateChange(ctx, state) {
+			return
+		}
+	}
+}
+
+// watchExporterConnections starts watching the exporter connections and registers
+// the telemetry.exporter.connected gauge, with an endpoint attribute per connection
+func (o *OpenTelemetry) watchExporterConnections(ctx context.Context) error {
+	if len(o.exporterConns) == 0 {
+		return nil
+	}
+	ctx, o.stopExporterWatch = context.WithCancel(context.WithoutCancel(ctx))
+	for _, c := range o.exporterConns {
+		go c.watch(ctx)
+	}
+	if !o.metricsEnabled {
+		return nil
+	}
+	_, err := o.meter.Float64ObservableGauge(exporterConnectedMetric, metric.WithFloat64Callback(
+		func(_ context.Context, observer metric.Float64Observer) error {
+			for _, c := range o.exporterConns {
+				value := 0.0
+				if c.connected.Load() {
+					value = 1
+				}
+				observer.Observe(value, metric.WithAttributes(attribute.String("endpoint", c.endpoint)))
+			}
+			return nil
+		}))
+	return err
+}
+
+// closeExporterConnections stops watching and closes the exporter connections,
+// which the exporters do not close themselves
+func (o *OpenTelemetry) closeExporterConnections() {
+	if o.stopExporterWatch != nil {
+		o.stopExporterWatch()
+	}
+	for _, c := range o.exporterConns {
+		_ = c.conn.Close()
+	}
+}
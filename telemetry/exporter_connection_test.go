@@ -0,0 +1,62 @@
+package telemetry
+
+import (
+	"testing"
+	"time"
+)
+
+// exporterConnected returns the telemetry.exporter.connected value for endpoint,
+// or -1 if it was not observed
+func (tt *testTelemetry) exporterConnected(t *testing.T, endpoint string) float64 {
+	t.Helper()
+	m, ok := findMetric(tt.collect(t), exporterConnectedMetric)
+	if !ok {
+		return -1
+	}
+	for _, p := range gaugePoints(t, m) {
+		if v, ok := p.Attributes.Value("endpoint"); ok && v.AsString() == endpoint {
+			return p.Value
+		}
+	}
+	return -1
+}
+
+// waitExporterConnected waits until the telemetry.exporter.connected value for
+// endpoint is want
+func (tt *testTelemetry) waitExporterConnected(t *testing.T, endpoint string, want float64) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		got := tt.exporterConnected(t, endpoint)
+		if got == want {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%s{endpoint=%q} = %v, want %v", exporterConnectedMetric, endpoint, got, want)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestExporterConnectedGaugeDropsWhenCollectorGoesDown(t *testing.T) {
+	traces := newStubCollector(t)
+	metrics := newStubCollector(t)
+	tt := newTestTelemetry(t,
+		WithTraceEndpoint(traces.addr),
+		WithMetricEndpoint(metrics.addr),
+		WithExporterConnectionMetrics(true))
+
+	tt.waitExporterConnected(t, traces.addr, 1)
+
+	traces.server.Stop()
+	tt.waitExporterConnected(t, traces.addr, 0)
+}
+
+func TestExporterConnectedGaugeDisabledByDefault(t *testing.T) {
+	traces := newStubCollector(t)
+	tt := newTestTelemetry(t, WithTraceEndpoint(traces.addr))
+
+	if _, ok := findMetric(tt.collect(t), exporterConnectedMetric); ok {
+		t.Errorf("%s recorded without WithExporterConnectionMetrics", exporterConnectedMetric)
+	}
+}
@@ -0,0 +1,155 @@
+// exporters.go - Construction of the OTLP exporters and span exporter wrappers
+
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"google.golang.org/grpc"
+)
+
+const (
+	// ProtocolGRPC exports over OTLP/gRPC, usually on port 4317
+	ProtocolGRPC = "grpc"
+	// ProtocolHTTPProtobuf exports over OTLP/HTTP with protobuf payloads, usually on port 4318
+	ProtocolHTTPProtobuf = "http/protobuf"
+)
+
+// validateExporterProtocol returns an error for protocols without an exporter
+func validateExporterProtocol(protocol string) error {
+	switch protocol {
+	case ProtocolGRPC, ProtocolHTTPProtobuf:
+		return nil
+	}
+	return fmt.Errorf("unsupported OTLP exporter protocol: %q", protocol)
+}
+
+// hasURLScheme reports whether an endpoint is a full URL rather than host:port
+func hasURLScheme(endpoint string) bool {
+	return strings.HasPrefix(endpoint, "http://") || strings.HasPrefix(endpoint, "https://")
+}
+
+// splitEndpoints splits a comma-separated list of endpoints. An empty list yields
+// a single empty endpoint so the exporter falls back to its default.
+func splitEndpoints(endpoints string) []string {
+	var result []string
+	for _, endpoint := range strings.Split(endpoints, ",") {
+		if endpoint = strings.TrimSpace(endpoint); endpoint != "" {
+			result = append(result, endpoint)
+		}
+	}
+	if len(result) == 0 {
+		return []string{""}
+	}
+	return result
+}
+
+// newSpanExporter creates the span exporter for a single trace endpoint, wrapped
+// according to the configuration. A gRPC exporter uses conn if it is not nil. All
+// exporters share the export slots of the configuration, so the concurrency limit
+// applies to the exports of all endpoints together.
+func newSpanExporter(ctx context.Context, endpoint string, conn *grpc.ClientConn, cfg otelConfig) (sdktrace.SpanExporter, error) {
+	var spanExporter sdktrace.SpanExporter
+	var err error
+	switch {
+	case cfg.exporterProtocol == ProtocolHTTPProtobuf:
+		spanExporter, err = otlptracehttp.New(ctx, httpTraceEndpointOptions(endpoint, cfg)...)
+	case conn != nil:
+		spanExporter, err = otlptracegrpc.New(ctx, otlptracegrpc.WithGRPCConn(conn))
+	default:
+		opts := append([]otlptracegrpc.Option{otlptracegrpc.WithEndpoint(endpoint)}, grpcTraceSecurityOptions(cfg)...)
+		spanExporter, err = otlptracegrpc.New(ctx, opts...)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.exportSlots != nil {
+		spanExporter = newConcurrencyLimitedExporter(spanExporter, cfg.exportSlots)
+	}
+	return spanExporter, nil
+}
+
+// httpTraceEndpointOptions returns the OTLP/HTTP trace exporter options for an
+// endpoint. A full URL sets the scheme and the path, whose default is /v1/traces,
+// while host:port uses the configured transport security.
+func httpTraceEndpointOptions(endpoint string, cfg otelConfig) []otlptracehttp.Option {
+	var opts []otlptracehttp.Option
+	switch {
+	case hasURLScheme(endpoint):
+		opts = append(opts, otlptracehttp.WithEndpointURL(endpoint))
+	case endpoint != "":
+		opts = append(opts, otlptracehttp.WithEndpoint(endpoint))
+	}
+	// The scheme of a full URL decides whether TLS is used, so only an explicit
+	// TLS configuration is added to it
+	if cfg.tlsConfig != nil || !hasURLScheme(endpoint) {
+		opts = append(opts, httpTraceSecurityOptions(cfg)...)
+	}
+	return opts
+}
+
+// newMetricExporter creates the metric exporter for the metric endpoint using the
+// configured protocol
+func newMetricExporter(ctx context.Context, endpoint string, cfg otelConfig) (sdkmetric.Exporter, error) {
+	if cfg.exporterProtocol == ProtocolHTTPProtobuf {
+		return otlpmetrichttp.New(ctx, httpMetricEndpointOptions(endpoint, cfg)...)
+	}
+	opts := append([]otlpmetricgrpc.Option{otlpmetricgrpc.WithEndpoint(endpoint)}, grpcMetricSecurityOptions(cfg)...)
+	return otlpmetricgrpc.New(ctx, opts...)
+}
+
+// httpMetricEndpointOptions returns the OTLP/HTTP metric exporter options for an
+// endpoint, following the same conventions as httpTraceEndpointOptions with a
+// default path of /v1/metrics
+func httpMetricEndpointOptions(endpoint string, cfg otelConfig) []otlpmetrichttp.Option {
+	var opts []otlpmetrichttp.Option
+	switch {
+	case hasURLScheme(endpoint):
+		opts = append(opts, otlpmetrichttp.WithEndpointURL(endpoint))
+	case endpoint != "":
+		opts = append(opts, otlpmetrichttp.WithEndpoint(endpoint))
+	}
+	if cfg.tlsConfig != nil || !hasURLScheme(endpoint) {
+		opts = append(opts, httpMetricSecurityOptions(cfg)...)
+	}
+	return opts
+}
+
+// concurrencyLimitedExporter bounds the number of concurrent ExportSpans calls of
+// the exporters sharing its slots, queuing the rest until a slot is free
+type concurrencyLimitedExporter struct {
+	sdktrace.SpanExporter
+	slots chan struct{}
+}
+
+// newConcurrencyLimitedExporter wraps exporter so that at most cap(slots) exports
+// of all exporters sharing slots run at once
+func newConcurrencyLimitedExporter(exporter sdktrace.SpanExporter, slots chan struct{}) *concurrencyLimitedExporter {
+	return &concurrencyLimitedExporter{SpanExporter: exporter, slots: slots}
+}
+
+// ExportSpans waits for a free slot, or until ctx is done, and exports the spans
+func (e *concurrencyLimitedExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	select {
+	case e.slots <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	defer func() { <-e.slots }()
+	return e.SpanExporter.ExportSpans(ctx, spans)
+}
+
+// MaxConcurrentExports returns the configured limit on concurrent span exports,
+// or 0 if exports are not limited
+func (o *OpenTelemetry) MaxConcurrentExports() int {
+	return o.config.maxConcurrentExports
+}
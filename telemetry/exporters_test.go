@@ -0,0 +1,211 @@
+package telemetry
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// slowExporter is a span exporter taking delay per export, tracking the highest
+// number of exports in flight across all slowExporters sharing inFlight
+type slowExporter struct {
+	delay       time.Duration
+	inFlight    *atomic.Int64
+	maxInFlight *atomic.Int64
+}
+
+func (e slowExporter) ExportSpans(context.Context, []sdktrace.ReadOnlySpan) error {
+	n := e.inFlight.Add(1)
+	defer e.inFlight.Add(-1)
+	for {
+		max := e.maxInFlight.Load()
+		if n <= max || e.maxInFlight.CompareAndSwap(max, n) {
+			break
+		}
+	}
+	time.Sleep(e.delay)
+	return nil
+}
+
+func (e slowExporter) Shutdown(context.Context) error { return nil }
+
+func TestConcurrencyLimitIsSharedAcrossExporters(t *testing.T) {
+	const limit = 2
+	slots := make(chan struct{}, limit)
+	var inFlight, maxInFlight atomic.Int64
+	exporters := make([]sdktrace.SpanExporter, 3)
+	for i := range exporters {
+		exporters[i] = newConcurrencyLimitedExporter(slowExporter{
+			delay:       5 * time.Millisecond,
+			inFlight:    &inFlight,
+			maxInFlight: &maxInFlight,
+		}, slots)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 12; i++ {
+		wg.Add(1)
+		go func(exporter sdktrace.SpanExporter) {
+			defer wg.Done()
+			if err := exporter.ExportSpans(context.Background(), nil); err != nil {
+				t.Errorf("ExportSpans() error = %v", err)
+			}
+		}(exporters[i%len(exporters)])
+	}
+	wg.Wait()
+
+	if got := maxInFlight.Load(); got > limit {
+		t.Errorf("%d exports ran at once across the exporters, want at most %d", got, limit)
+	}
+}
+
+func TestConcurrencyLimitedExporterHonorsContext(t *testing.T) {
+	slots := make(chan struct{}, 1)
+	slots <- struct{}{}
+	var inFlight, maxInFlight atomic.Int64
+	exporter := newConcurrencyLimitedExporter(slowExporter{inFlight: &inFlight, maxInFlight: &maxInFlight}, slots)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := exporter.ExportSpans(ctx, nil); err != context.DeadlineExceeded {
+		t.Errorf("ExportSpans() error = %v, want the context error while all slots are taken", err)
+	}
+	if maxInFlight.Load() != 0 {
+		t.Error("ExportSpans exported without a free slot")
+	}
+}
+
+func TestSpanExportersShareExportSlots(t *testing.T) {
+	tt := newTestTelemetry(t, WithMaxConcurrentExports(3))
+	cfg := tt.config
+	if cfg.exportSlots == nil || cap(cfg.exportSlots) != 3 {
+		t.Fatalf("export slots capacity = %d, want 3", cap(cfg.exportSlots))
+	}
+
+	var slots []chan struct{}
+	for _, endpoint := range splitEndpoints("127.0.0.1:1,127.0.0.1:2") {
+		exporter, err := newSpanExporter(context.Background(), endpoint, nil, cfg)
+		if err != nil {
+			t.Fatalf("newSpanExporter(%q) error = %v", endpoint, err)
+		}
+		defer exporter.Shutdown(context.Background())
+		limited, ok := exporter.(*concurrencyLimitedExporter)
+		if !ok {
+			t.Fatalf("newSpanExporter(%q) = %T, want a concurrency limited exporter", endpoint, exporter)
+		}
+		slots = append(slots, limited.slots)
+	}
+	if slots[0] != cfg.exportSlots || slots[1] != cfg.exportSlots {
+		t.Error("endpoint exporters do not share the configured export slots")
+	}
+}
+
+func TestSpansAreExportedToEveryTraceEndpoint(t *testing.T) {
+	first, second := newStubCollector(t), newStubCollector(t)
+	tt := newTestTelemetry(t,
+		WithTraceEndpoint(first.addr+", "+second.addr),
+		WithMetricEndpoint(first.addr))
+	ctx := context.Background()
+
+	_, span := tt.StartSpan(ctx, "replicated")
+	tt.EndSpan(span)
+	if err := tt.forceFlush(ctx); err != nil {
+		t.Fatalf("forceFlush() error = %v", err)
+	}
+
+	for i, collector := range []*stubCollector{first, second} {
+		if spans := collector.receivedSpans(); len(spans) != 1 || spans[0] != "replicated" {
+			t.Errorf("collector %d received spans %v, want the replicated span", i, spans)
+		}
+	}
+}
+
+func TestSplitEndpoints(t *testing.T) {
+	tests := []struct {
+		endpoints string
+		want      []string
+	}{
+		{"", []string{""}},
+		{" , ", []string{""}},
+		{"collector:4317", []string{"collector:4317"}},
+		{"a:4317, b:4317,,", []string{"a:4317", "b:4317"}},
+	}
+	for _, tc := range tests {
+		got := splitEndpoints(tc.endpoints)
+		if len(got) != len(tc.want) {
+			t.Errorf("splitEndpoints(%q) = %q, want %q", tc.endpoints, got, tc.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tc.want[i] {
+				t.Errorf("splitEndpoints(%q) = %q, want %q", tc.endpoints, got, tc.want)
+				break
+			}
+		}
+	}
+}
+
+func TestMetricExporterTypePerProtocol(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		protocol string
+		endpoint string
+		check    func(any) bool
+	}{
+		{ProtocolGRPC, "127.0.0.1:4317", func(e any) bool { _, ok := e.(*otlpmetricgrpc.Exporter); return ok }},
+		{ProtocolHTTPProtobuf, "127.0.0.1:4318", func(e any) bool { _, ok := e.(*otlpmetrichttp.Exporter); return ok }},
+		{ProtocolHTTPProtobuf, "https://collector.example.com/otlp/v1/metrics", func(e any) bool { _, ok := e.(*otlpmetrichttp.Exporter); return ok }},
+	}
+	for _, tc := range tests {
+		exporter, err := newMetricExporter(ctx, tc.endpoint, otelConfig{exporterProtocol: tc.protocol, insecure: true})
+		if err != nil {
+			t.Fatalf("newMetricExporter(%s, %s) error = %v", tc.protocol, tc.endpoint, err)
+		}
+		if !tc.check(exporter) {
+			t.Errorf("newMetricExporter(%s, %s) = %T, want the %s exporter", tc.protocol, tc.endpoint, exporter, tc.protocol)
+		}
+		_ = exporter.Shutdown(ctx)
+	}
+}
+
+func TestHTTPProtobufProtocolExportsOverHTTP(t *testing.T) {
+	var mu sync.Mutex
+	requests := make(map[string]string)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests[r.Method+" "+r.URL.Path] = r.Header.Get("Content-Type")
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/x-protobuf")
+	}))
+	defer server.Close()
+
+	tt := newTestTelemetry(t,
+		WithExporterProtocol(ProtocolHTTPProtobuf),
+		WithTraceEndpoint(server.URL+"/v1/traces"),
+		WithMetricEndpoint(strings.TrimPrefix(server.URL, "http://")))
+	ctx := context.Background()
+
+	_, span := tt.StartSpan(ctx, "operation")
+	tt.EndSpan(span)
+	tt.IncrementCounter(ctx, "operations", 1)
+	if err := tt.forceFlush(ctx); err != nil {
+		t.Fatalf("forceFlush() error = %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	for _, path := range []string{"POST /v1/traces", "POST /v1/metrics"} {
+		if contentType, ok := requests[path]; !ok || contentType != "application/x-protobuf" {
+			t.Errorf("requests = %v, want %s with a protobuf payload", requests, path)
+		}
+	}
+}
@@ -0,0 +1,261 @@
+// grpc.go - gRPC instrumentation helpers and interceptors
+
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// netPeerIPKey is the peer IP address attribute used on gRPC server spans
+const netPeerIPKey = attribute.Key("net.peer.ip")
+
+// SetGRPCPeerAttributes sets rpc.system=grpc and, when the context carries gRPC
+// peer info, the net.peer.ip and net.peer.port attributes on the span in ctx
+func (o *OpenTelemetry) SetGRPCPeerAttributes(ctx context.Context) {
+	if !o.traceEnabled {
+		return
+	}
+	span := trace.SpanFromContext(ctx)
+	if span.IsRecording() {
+		span.SetAttributes(grpcPeerAttributes(ctx)...)
+	}
+}
+
+// grpcPeerAttributes returns the rpc.system and peer address attributes for ctx
+func grpcPeerAttributes(ctx context.Context) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{semconv.RPCSystemGRPC}
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return attrs
+	}
+	switch addr := p.Addr.(type) {
+	case *net.TCPAddr:
+		return append(attrs, netPeerIPKey.String(addr.IP.String()), semconv.NetPeerPortKey.Int(addr.Port))
+	case *net.UDPAddr:
+		return append(attrs, netPeerIPKey.String(addr.IP.String()), semconv.NetPeerPortKey.Int(addr.Port))
+	}
+	return append(attrs, netPeerIPKey.String(p.Addr.String()))
+}
+
+// UnaryServerInterceptor returns a gRPC server interceptor running each unary RPC in
+// a server span that continues the trace context of the incoming metadata
+func (o *OpenTelemetry) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if !o.traceEnabled {
+			return handler(ctx, req)
+		}
+		ctx, span := o.startServerRPCSpan(ctx, info.FullMethod)
+		defer o.EndSpan(span)
+
+		resp, err := handler(ctx, req)
+		o.endRPC(ctx, span, err)
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor returns a gRPC server interceptor running each streaming
+// RPC in a server span that continues the trace context of the incoming metadata
+func (o *OpenTelemetry) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if !o.traceEnabled {
+			return handler(srv, ss)
+		}
+		ctx, span := o.startServerRPCSpan(ss.Context(), info.FullMethod)
+		defer o.EndSpan(span)
+
+		err := handler(srv, &tracedServerStream{ServerStream: ss, ctx: ctx})
+		o.endRPC(ctx, span, err)
+		return err
+	}
+}
+
+// UnaryClientInterceptor returns a gRPC client interceptor running each unary RPC in
+// a client span whose trace context is sent in the outgoing metadata
+func (o *OpenTelemetry) UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if !o.traceEnabled {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+		ctx, span := o.startClientRPCSpan(ctx, method)
+		defer o.EndSpan(span)
+
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		o.endRPC(ctx, span, err)
+		return err
+	}
+}
+
+// StreamClientInterceptor returns a gRPC client interceptor running each streaming
+// RPC in a client span whose trace context is sent in the outgoing metadata. The
+// span ends when the stream fails, the server closes it, the single reply of a
+// client-streaming RPC is received or the context of the stream is done.
+func (o *OpenTelemetry) StreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		if !o.traceEnabled {
+			return streamer(ctx, desc, cc, method, opts...)
+		}
+		ctx, span := o.startClientRPCSpan(ctx, method)
+
+		cs, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			o.endRPC(ctx, span, err)
+			o.EndSpan(span)
+			return nil, err
+		}
+		stream := &tracedClientStream{
+			ClientStream:  cs,
+			serverStreams: desc.ServerStreams,
+			finished:      make(chan struct{}),
+			finish: func(err error) {
+				o.endRPC(ctx, span, err)
+				o.EndSpan(span)
+			},
+		}
+		go stream.watch(ctx)
+		return stream, nil
+	}
+}
+
+// startServerRPCSpan starts the server span of an RPC from the trace context of the
+// incoming metadata in ctx
+func (o *OpenTelemetry) startServerRPCSpan(ctx context.Context, fullMethod string) (context.Context, trace.Span) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	ctx = textMapPropagator.Extract(ctx, metadataCarrier(md))
+
+	attrs := append(grpcPeerAttributes(ctx), rpcMethodAttributes(fullMethod)...)
+	return o.tracer.Start(ctx, strings.TrimPrefix(fullMethod, "/"),
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(attrs...))
+}
+
+// startClientRPCSpan starts the client span of an RPC and adds its trace context to
+// the outgoing metadata of the returned context
+func (o *OpenTelemetry) startClientRPCSpan(ctx context.Context, fullMethod string) (context.Context, trace.Span) {
+	attrs := append([]attribute.KeyValue{semconv.RPCSystemGRPC}, rpcMethodAttributes(fullMethod)...)
+	ctx, span := o.tracer.Start(ctx, strings.TrimPrefix(fullMethod, "/"),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...))
+
+	md, ok := metadata.FromOutgoingContext(ctx)
+	if ok {
+		md = md.Copy()
+	} else {
+		md = metadata.MD{}
+	}
+	textMapPropagator.Inject(ctx, metadataCarrier(md))
+	return metadata.NewOutgoingContext(ctx, md), span
+}
+
+// endRPC sets the gRPC status code of an RPC on span and records err, if any
+func (o *OpenTelemetry) endRPC(ctx context.Context, span trace.Span, err error) {
+	span.SetAttributes(semconv.RPCGRPCStatusCodeKey.Int(int(status.Code(err))))
+	o.RecordError(ctx, err)
+}
+
+// rpcMethodAttributes returns the rpc.service and rpc.method attributes of a full
+// method name of the form /package.Service/Method
+func rpcMethodAttributes(fullMethod string) []attribute.KeyValue {
+	service, method, ok := strings.Cut(strings.TrimPrefix(fullMethod, "/"), "/")
+	if !ok {
+		return nil
+	}
+	return []attribute.KeyValue{semconv.RPCServiceKey.String(service), semconv.RPCMethodKey.String(method)}
+}
+
+// metadataCarrier adapts gRPC metadata to a propagation.TextMapCarrier
+type metadataCarrier metadata.MD
+
+// Get returns the first value of key
+func (c metadataCarrier) Get(key string) string {
+	if values := metadata.MD(c).Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// Set replaces the values of key with value
+func (c metadataCarrier) Set(key, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+// Keys returns the metadata keys
+func (c metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// tracedServerStream is a ServerStream whose context carries the RPC span
+type tracedServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context returns the context carrying the RPC span
+func (s *tracedServerStream) Context() context.Context {
+	return s.ctx
+}
+
+// tracedClientStream is a ClientStream calling finish once the stream is done
+type tracedClientStream struct {
+	grpc.ClientStream
+	serverStreams bool
+	finish        func(err error)
+	finished      chan struct{}
+	once          sync.Once
+}
+
+// RecvMsg receives a message, finishing the stream on io.EOF or an error, or after
+// the reply of an RPC whose server does not stream
+func (s *tracedClientStream) RecvMsg(m any) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err != nil || !s.serverStreams {
+		s.done(err)
+	}
+	return err
+}
+
+// watch finishes the stream with the context error when ctx is done first, as the
+// caller may stop receiving without seeing the error
+func (s *tracedClientStream) watch(ctx context.Context) {
+	select {
+	case <-ctx.Done():
+		s.done(status.FromContextError(ctx.Err()).Err())
+	case <-s.finished:
+	}
+}
+
+// Header returns the header metadata, finishing the stream on an error
+func (s *tracedClientStream) Header() (metadata.MD, error) {
+	md, err := s.ClientStream.Header()
+	if err != nil {
+		s.done(err)
+	}
+	return md, err
+}
+
+// done calls finish once, with a nil error for a stream closed by the server
+func (s *tracedClientStream) done(err error) {
+	if errors.Is(err, io.EOF) {
+		err = nil
+	}
+	s.once.Do(func() {
+		s.finish(err)
+		close(s.finished)
+	})
+}
@@ -0,0 +1,281 @@
+package telemetry
+
+import (
+	"context"
+	"io"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	grpccodes "google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func TestUnaryServerInterceptorSetsPeerAttributes(t *testing.T) {
+	tests := []struct {
+		name string
+		addr net.Addr
+		want map[string]string
+	}{
+		{"tcp peer", &net.TCPAddr{IP: net.ParseIP("10.1.2.3"), Port: 52044},
+			map[string]string{"rpc.system": "grpc", "net.peer.ip": "10.1.2.3", "net.peer.port": "52044"}},
+		{"unix peer", &net.UnixAddr{Name: "/run/app.sock", Net: "unix"},
+			map[string]string{"rpc.system": "grpc", "net.peer.ip": "/run/app.sock"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tt := newTestTelemetry(t)
+			interceptor := tt.UnaryServerInterceptor()
+			ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: tc.addr})
+			info := &grpc.UnaryServerInfo{FullMethod: "/orders.v1.Orders/Get"}
+
+			_, err := interceptor(ctx, nil, info, func(context.Context, any) (any, error) { return nil, nil })
+			if err != nil {
+				t.Fatalf("interceptor error = %v", err)
+			}
+
+			attrs := attrMap(tt.endedSpan(t, "orders.v1.Orders/Get").Attributes())
+			for key, want := range tc.want {
+				if attrs[key] != want {
+					t.Errorf("%s = %q, want %q", key, attrs[key], want)
+				}
+			}
+			if _, ok := attrs["net.peer.port"]; ok != (tc.want["net.peer.port"] != "") {
+				t.Errorf("attributes = %v, want net.peer.port only for IP peers", attrs)
+			}
+		})
+	}
+}
+
+func TestSetGRPCPeerAttributesWithoutPeer(t *testing.T) {
+	tt := newTestTelemetry(t)
+
+	ctx, span := tt.StartSpan(context.Background(), "handler")
+	tt.SetGRPCPeerAttributes(ctx)
+	tt.EndSpan(span)
+
+	attrs := attrMap(tt.endedSpan(t, "handler").Attributes())
+	if attrs["rpc.system"] != "grpc" {
+		t.Errorf("rpc.system = %q, want grpc", attrs["rpc.system"])
+	}
+	if _, ok := attrs["net.peer.ip"]; ok {
+		t.Errorf("attributes = %v, want no peer address without peer info", attrs)
+	}
+}
+
+// newBufconnConn serves the services registered by register with the server
+// interceptors of tt over an in-memory connection and returns a client connection
+// using the client interceptors
+func newBufconnConn(t *testing.T, tt *testTelemetry, register func(*grpc.Server)) *grpc.ClientConn {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	server := grpc.NewServer(
+		grpc.UnaryInterceptor(tt.UnaryServerInterceptor()),
+		grpc.StreamInterceptor(tt.StreamServerInterceptor()))
+	register(server)
+	go func() { _ = server.Serve(lis) }()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithUnaryInterceptor(tt.UnaryClientInterceptor()),
+		grpc.WithStreamInterceptor(tt.StreamClientInterceptor()))
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+// newBufconnHealthClient serves the gRPC health service over newBufconnConn and
+// returns a client for it
+func newBufconnHealthClient(t *testing.T, tt *testTelemetry) healthpb.HealthClient {
+	t.Helper()
+	conn := newBufconnConn(t, tt, func(server *grpc.Server) {
+		healthServer := health.NewServer()
+		healthServer.SetServingStatus("orders", healthpb.HealthCheckResponse_SERVING)
+		healthpb.RegisterHealthServer(server, healthServer)
+	})
+	return healthpb.NewHealthClient(conn)
+}
+
+// rpcSpans returns the client and server spans of the RPC named name
+func rpcSpans(t *testing.T, tt *testTelemetry, name string) (client, server sdktrace.ReadOnlySpan) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		for _, s := range tt.endedSpans(name) {
+			switch s.SpanKind() {
+			case trace.SpanKindClient:
+				client = s
+			case trace.SpanKindServer:
+				server = s
+			}
+		}
+		if client != nil && server != nil {
+			return client, server
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%s spans: client %v, server %v, want both ended", name, client != nil, server != nil)
+		}
+		time.Sleep(time.Millisecond)
+		client, server = nil, nil
+	}
+}
+
+func TestGRPCInterceptorsPropagateAcrossConnection(t *testing.T) {
+	tests := []struct {
+		service    string
+		wantStatus string
+		wantError  bool
+	}{
+		{"orders", "0", false},
+		{"payments", "5", true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.service, func(t *testing.T) {
+			tt := newTestTelemetry(t)
+			client := newBufconnHealthClient(t, tt)
+			ctx, root := tt.StartSpan(context.Background(), "caller")
+			_, err := client.Check(ctx, &healthpb.HealthCheckRequest{Service: tc.service})
+			tt.EndSpan(root)
+			if (err != nil) != tc.wantError {
+				t.Fatalf("Check() error = %v, want error %v", err, tc.wantError)
+			}
+
+			clientSpan, serverSpan := rpcSpans(t, tt, "grpc.health.v1.Health/Check")
+			if clientSpan.Parent().SpanID() != root.SpanContext().SpanID() {
+				t.Error("client span is not a child of the caller's span")
+			}
+			if serverSpan.Parent().SpanID() != clientSpan.SpanContext().SpanID() || !serverSpan.Parent().IsRemote() {
+				t.Error("server span does not continue the client span from the metadata")
+			}
+			for _, s := range []sdktrace.ReadOnlySpan{clientSpan, serverSpan} {
+				attrs := attrMap(s.Attributes())
+				if attrs["rpc.service"] != "grpc.health.v1.Health" || attrs["rpc.method"] != "Check" || attrs["rpc.grpc.status_code"] != tc.wantStatus {
+					t.Errorf("%v span attributes = %v, want the method and status code %s", s.SpanKind(), attrs, tc.wantStatus)
+				}
+				if failed := s.Status().Code == codes.Error; failed != tc.wantError {
+					t.Errorf("%v span status = %v, want error %v", s.SpanKind(), s.Status(), tc.wantError)
+				}
+			}
+		})
+	}
+}
+
+func TestGRPCStreamInterceptorsEndSpansWithStream(t *testing.T) {
+	tt := newTestTelemetry(t)
+	client := newBufconnHealthClient(t, tt)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	stream, err := client.Watch(ctx, &healthpb.HealthCheckRequest{Service: "orders"})
+	if err != nil {
+		t.Fatalf("Watch() error = %v", err)
+	}
+	if resp, err := stream.Recv(); err != nil || resp.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+		t.Fatalf("Recv() = %v, %v, want the serving status", resp, err)
+	}
+	cancel()
+	if _, err := stream.Recv(); status.Code(err) != grpccodes.Canceled {
+		t.Fatalf("Recv() after cancel error = %v, want canceled", err)
+	}
+
+	clientSpan, serverSpan := rpcSpans(t, tt, "grpc.health.v1.Health/Watch")
+	if serverSpan.Parent().SpanID() != clientSpan.SpanContext().SpanID() {
+		t.Error("server stream span does not continue the client stream span")
+	}
+	if got := attrMap(clientSpan.Attributes())["rpc.grpc.status_code"]; got != "1" {
+		t.Errorf("client stream status code = %s, want canceled (1)", got)
+	}
+}
+
+func TestGRPCStreamClientSpanEndsWhenContextIsCancelled(t *testing.T) {
+	tt := newTestTelemetry(t)
+	client := newBufconnHealthClient(t, tt)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	stream, err := client.Watch(ctx, &healthpb.HealthCheckRequest{Service: "orders"})
+	if err != nil {
+		t.Fatalf("Watch() error = %v", err)
+	}
+	if _, err := stream.Recv(); err != nil {
+		t.Fatalf("Recv() error = %v", err)
+	}
+	// The caller stops receiving, so only the cancellation can end the span
+	cancel()
+
+	clientSpan, _ := rpcSpans(t, tt, "grpc.health.v1.Health/Watch")
+	if got := attrMap(clientSpan.Attributes())["rpc.grpc.status_code"]; got != "1" {
+		t.Errorf("client stream status code = %s, want canceled (1)", got)
+	}
+}
+
+// uploadStreamDesc describes a client-streaming RPC receiving health check
+// requests and replying once with the number of requests
+var uploadStreamDesc = grpc.StreamDesc{
+	StreamName:    "Upload",
+	ClientStreams: true,
+	Handler: func(_ any, stream grpc.ServerStream) error {
+		var n int
+		for {
+			var req healthpb.HealthCheckRequest
+			if err := stream.RecvMsg(&req); err == io.EOF {
+				break
+			} else if err != nil {
+				return err
+			}
+			n++
+		}
+		return stream.SendMsg(&healthpb.HealthCheckRequest{Service: strconv.Itoa(n)})
+	},
+}
+
+func TestGRPCClientStreamingSpanEndsAfterReply(t *testing.T) {
+	tt := newTestTelemetry(t)
+	conn := newBufconnConn(t, tt, func(server *grpc.Server) {
+		server.RegisterService(&grpc.ServiceDesc{
+			ServiceName: "test.Uploader",
+			HandlerType: (*any)(nil),
+			Streams:     []grpc.StreamDesc{uploadStreamDesc},
+		}, struct{}{})
+	})
+
+	stream, err := conn.NewStream(context.Background(), &uploadStreamDesc, "/test.Uploader/Upload")
+	if err != nil {
+		t.Fatalf("NewStream() error = %v", err)
+	}
+	for _, service := range []string{"orders", "payments", "billing"} {
+		if err := stream.SendMsg(&healthpb.HealthCheckRequest{Service: service}); err != nil {
+			t.Fatalf("SendMsg() error = %v", err)
+		}
+	}
+	if err := stream.CloseSend(); err != nil {
+		t.Fatalf("CloseSend() error = %v", err)
+	}
+	var reply healthpb.HealthCheckRequest
+	if err := stream.RecvMsg(&reply); err != nil || reply.GetService() != "3" {
+		t.Fatalf("RecvMsg() = %v, %v, want the count of 3 requests", &reply, err)
+	}
+
+	clientSpan, serverSpan := rpcSpans(t, tt, "test.Uploader/Upload")
+	if serverSpan.Parent().SpanID() != clientSpan.SpanContext().SpanID() {
+		t.Error("server span does not continue the client-streaming span")
+	}
+	if got := attrMap(clientSpan.Attributes())["rpc.grpc.status_code"]; got != "0" {
+		t.Errorf("client stream status code = %s, want OK (0)", got)
+	}
+	if clientSpan.Status().Code == codes.Error {
+		t.Errorf("client stream status = %v, want no error", clientSpan.Status())
+	}
+}
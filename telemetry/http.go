@@ -0,0 +1,77 @@
+// http.go - HTTP instrumentation helpers
+
+package telemetry
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// redactedHeaderValue replaces the values of sensitive headers
+const redactedHeaderValue = "[REDACTED]"
+
+// sensitiveHeaders lists the lowercase names of headers whose values are never captured
+var sensitiveHeaders = map[string]bool{
+	"authorization":       true,
+	"proxy-authorization": true,
+	"cookie":              true,
+	"set-cookie":          true,
+	"x-api-key":           true,
+}
+
+// CaptureHeaders sets allowlisted headers as <prefix>.<name> attributes on the span
+// in ctx, e.g. http.request.header.user-agent. Header names are lowercased and the
+// values of sensitive headers such as Authorization are redacted.
+func (o *OpenTelemetry) CaptureHeaders(ctx context.Context, headers http.Header, prefix string, allowlist []string) {
+	if !o.traceEnabled || len(allowlist) == 0 {
+		return
+	}
+	span := trace.SpanFromContext(ctx)
+	if !span.IsRecording() {
+		return
+	}
+
+	prefix = strings.TrimSuffix(prefix, ".")
+	attrs := make([]attribute.KeyValue, 0, len(allowlist))
+	for _, name := range allowlist {
+		values := headers.Values(name)
+		if len(values) == 0 {
+			continue
+		}
+		name = strings.ToLower(name)
+		if sensitiveHeaders[name] {
+			values = []string{redactedHeaderValue}
+		}
+		attrs = append(attrs, attribute.StringSlice(prefix+"."+name, values))
+	}
+	span.SetAttributes(sanitizeAttributes(attrs)...)
+}
+
+// TrackHTTPDependency records an HTTP dependency call like TrackDependency, deriving
+// success from the status code: codes below the failure threshold (default: 400),
+// or listed with WithHTTPDependencySuccessStatuses, are successes
+func (o *OpenTelemetry) TrackHTTPDependency(ctx context.Context, target string, statusCode int, duration time.Duration, attributes ...attribute.KeyValue) {
+	spanAttributes := make([]attribute.KeyValue, 0, len(attributes)+1)
+	spanAttributes = append(spanAttributes, semconv.HTTPStatusCodeKey.Int(statusCode))
+	spanAttributes = append(spanAttributes, attributes...)
+	o.trackDependency(ctx, "http", target, duration, o.httpDependencySucceeded(statusCode), spanAttributes...)
+}
+
+// httpDependencySucceeded reports whether an HTTP dependency status code counts as success
+func (o *OpenTelemetry) httpDependencySucceeded(statusCode int) bool {
+	if statusCode < o.config.httpDependencyFailureStatus {
+		return true
+	}
+	for _, code := range o.config.httpDependencySuccessStatuses {
+		if code == statusCode {
+			return true
+		}
+	}
+	return false
+}
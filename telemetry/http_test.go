@@ -0,0 +1,105 @@
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// stringSliceAttrs returns the string slice attributes of attrs by key
+func stringSliceAttrs(attrs []attribute.KeyValue) map[string][]string {
+	m := make(map[string][]string, len(attrs))
+	for _, kv := range attrs {
+		if kv.Value.Type() == attribute.STRINGSLICE {
+			m[string(kv.Key)] = kv.Value.AsStringSlice()
+		}
+	}
+	return m
+}
+
+func TestCaptureHeadersAllowlistsAndRedacts(t *testing.T) {
+	tt := newTestTelemetry(t)
+	headers := http.Header{}
+	headers.Set("User-Agent", "curl/8.0")
+	headers.Add("Accept", "text/html")
+	headers.Add("Accept", "application/json")
+	headers.Set("Authorization", "Bearer secret")
+	headers.Set("Cookie", "session=secret")
+	headers.Set("X-Internal", "not allowlisted")
+
+	ctx, span := tt.StartSpan(context.Background(), "request")
+	tt.CaptureHeaders(ctx, headers, "http.request.header.",
+		[]string{"User-Agent", "accept", "Authorization", "Cookie", "Content-Type"})
+	tt.EndSpan(span)
+
+	got := stringSliceAttrs(tt.endedSpan(t, "request").Attributes())
+	want := map[string][]string{
+		"http.request.header.user-agent":    {"curl/8.0"},
+		"http.request.header.accept":        {"text/html", "application/json"},
+		"http.request.header.authorization": {redactedHeaderValue},
+		"http.request.header.cookie":        {redactedHeaderValue},
+	}
+	if len(got) != len(want) {
+		t.Errorf("captured headers %v, want %v", got, want)
+	}
+	for key, values := range want {
+		if len(got[key]) != len(values) {
+			t.Errorf("%s = %q, want %q", key, got[key], values)
+			continue
+		}
+		for i := range values {
+			if got[key][i] != values[i] {
+				t.Errorf("%s = %q, want %q", key, got[key], values)
+				break
+			}
+		}
+	}
+}
+
+func TestTrackHTTPDependencyDerivesSuccessFromStatus(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    []Option
+		status  int
+		success bool
+	}{
+		{"2xx", nil, http.StatusOK, true},
+		{"404 by default", nil, http.StatusNotFound, false},
+		{"404 configured as success", []Option{WithHTTPDependencySuccessStatuses(http.StatusNotFound)}, http.StatusNotFound, true},
+		{"4xx below a raised threshold", []Option{WithHTTPDependencyFailureStatus(500)}, http.StatusConflict, true},
+		{"5xx", []Option{WithHTTPDependencySuccessStatuses(http.StatusNotFound)}, http.StatusBadGateway, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tt := newTestTelemetry(t, tc.opts...)
+
+			tt.TrackHTTPDependency(context.Background(), "billing", tc.status, 20*time.Millisecond,
+				attribute.String("peer.service", "billing"))
+
+			span := tt.endedSpan(t, "Dependency Call")
+			attrs := attrMap(span.Attributes())
+			if attrs["http.status_code"] != fmt.Sprint(tc.status) || attrs["peer.service"] != "billing" {
+				t.Errorf("span attributes = %v, want the status code and extra attributes", attrs)
+			}
+			if attrs["dependency.type"] != "http" || attrs["dependency.success"] != fmt.Sprint(tc.success) {
+				t.Errorf("span attributes = %v, want an http dependency with success=%v", attrs, tc.success)
+			}
+			if failed := span.Status().Code == codes.Error; failed == tc.success {
+				t.Errorf("span status = %v, want success=%v", span.Status(), tc.success)
+			}
+
+			points := sumPoints(t, tt.metric(t, "dependency.calls"))
+			if len(points) != 1 {
+				t.Fatalf("got %d dependency.calls points, want 1", len(points))
+			}
+			if got := attrMap(points[0].Attributes.ToSlice())["dependency.success"]; got != fmt.Sprint(tc.success) {
+				t.Errorf("dependency.calls success = %s, want %v", got, tc.success)
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+// instruments.go - Tracking of the intended instrument kind of each metric name and
+// caching of the instruments created for them
+
+package telemetry
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+
+	"github.com/sadco-io/sad-go-logger/logger"
+	"go.uber.org/zap"
+)
+
+// packagePrefix is the function name prefix of this package, skipped when looking
+// up the call site that registered an instrument
+const packagePrefix = "github.com/sadco-io/sad-go-telemetry/telemetry."
+
+// InstrumentKind is the kind of instrument a metric name is meant to be recorded with
+type InstrumentKind string
+
+const (
+	// InstrumentKindCounter is a monotonic counter, recorded with RecordMetric or IncrementCounter
+	InstrumentKindCounter InstrumentKind = "counter"
+	// InstrumentKindGauge is a gauge, recorded with RecordGauge
+	InstrumentKindGauge InstrumentKind = "gauge"
+	// InstrumentKindHistogram is a histogram, recorded with RecordHistogram
+	InstrumentKindHistogram InstrumentKind = "histogram"
+)
+
+// checkInstrumentKind reports whether a value of the given kind may be recorded for
+// name. A name's kind and first call site come from WithInstrumentKinds or, failing
+// that, its first use. A mismatched value is dropped rather than registered as a
+// second, conflicting SDK instrument; the mismatch is logged once per name and kind,
+// naming both call sites, and with WithStrictInstruments also returned as an error.
+func (o *OpenTelemetry) checkInstrumentKind(name string, kind InstrumentKind) (bool, error) {
+	o.instrumentsMu.RLock()
+	expected, ok := o.instrumentKinds[name]
+	o.instrumentsMu.RUnlock()
+	if !ok {
+		o.instrumentsMu.Lock()
+		if expected, ok = o.instrumentKinds[name]; !ok {
+			o.instrumentKinds[name] = kind
+			o.instrumentKindCallers[name] = externalCaller()
+			expected = kind
+		}
+		o.instrumentsMu.Unlock()
+	}
+	if expected == kind {
+		return true, nil
+	}
+
+	if _, warned := o.instrumentKindWarned.LoadOrStore(name+"\x00"+string(kind), struct{}{}); !warned {
+		o.instrumentsMu.RLock()
+		caller := o.instrumentKindCallers[name]
+		o.instrumentsMu.RUnlock()
+		logger.Log.Warn("Metric recorded with a mismatched instrument kind",
+			zap.String("name", name),
+			zap.String("expected", string(expected)),
+			zap.String("actual", string(kind)),
+			zap.String("caller", caller),
+			zap.String("conflictingCaller", externalCaller()))
+	}
+	if o.config.strictInstruments {
+		return false, fmt.Errorf("metric %q is a %s but was recorded as a %s", name, expected, kind)
+	}
+	return false, nil
+}
+
+// instrumentEntry is a cached instrument with the signature and call site of its
+// first registration
+type instrumentEntry struct {
+	kind       InstrumentKind
+	unit       string
+	caller     string
+	instrument any
+}
+
+// cachedInstrument returns the instrument registered for name, creating it once on
+// first use, so recording on the hot path is a read-locked map lookup without
+// allocations. Instruments are keyed by name alone, as the SDK treats any other
+// signature for the same name as a conflicting instrument: a later registration
+// with a different unit reuses the first one, logging the conflict once with both
+// call sites, and one with a different kind is refused.
+func cachedInstrument[T any](o *OpenTelemetry, kind InstrumentKind, name, unit string, create func() (T, error)) (T, error) {
+	o.instrumentsMu.RLock()
+	entry, ok := o.instruments[name]
+	o.instrumentsMu.RUnlock()
+	if !ok {
+		o.instrumentsMu.Lock()
+		if entry, ok = o.instruments[name]; !ok {
+			instrument, err := create()
+			if err != nil {
+				o.instrumentsMu.Unlock()
+				return instrument, err
+			}
+			entry = &instrumentEntry{kind: kind, unit: unit, caller: externalCaller(), instrument: instrument}
+			o.instruments[name] = entry
+		}
+		o.instrumentsMu.Unlock()
+	}
+
+	if entry.kind != kind {
+		var zero T
+		return zero, fmt.Errorf("metric %q is registered as a %s at %s", name, entry.kind, entry.caller)
+	}
+	if entry.unit != unit {
+		o.warnInstrumentConflict(name, entry, unit)
+	}
+	return entry.instrument.(T), nil
+}
+
+// warnInstrumentConflict logs, once per instrument and unit, that the instrument was
+// registered again with a different unit than its first registration
+func (o *OpenTelemetry) warnInstrumentConflict(name string, first *instrumentEntry, unit string) {
+	if _, warned := o.instrumentConflictWarned.LoadOrStore(name+"\x00"+unit, struct{}{}); warned {
+		return
+	}
+	logger.Log.Warn("Metric instrument registered with conflicting units, reusing the first registration",
+		zap.String("name", name),
+		zap.String("kind", string(first.kind)),
+		zap.String("unit", first.unit),
+		zap.String("caller", first.caller),
+		zap.String("conflictingUnit", unit),
+		zap.String("conflictingCaller", externalCaller()))
+}
+
+// externalCaller returns the file and line of the innermost caller outside this package
+func externalCaller() string {
+	pcs := make([]uintptr, 16)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(2, pcs)])
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, packagePrefix) {
+			return fmt.Sprintf("%s:%d", frame.File, frame.Line)
+		}
+		if !more {
+			return "unknown"
+		}
+	}
+}
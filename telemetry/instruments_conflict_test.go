@@ -0,0 +1,129 @@
+package telemetry_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/sadco-io/sad-go-logger/logger"
+	"github.com/sadco-io/sad-go-telemetry/telemetry"
+	"github.com/sadco-io/sad-go-telemetry/telemetry/telemetrytest"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// This test lives outside the package so that the call sites named in the warning
+// are the test's own lines rather than the testing package's
+
+// newConflictTelemetry returns a metrics-only instance collected by the returned
+// reader, with the package logger observed
+func newConflictTelemetry(t *testing.T) (*telemetry.OpenTelemetry, *telemetrytest.MetricReader, *observer.ObservedLogs) {
+	t.Helper()
+	core, logs := observer.New(zap.DebugLevel)
+	previous := logger.Log
+	logger.Log = zap.New(core)
+	t.Cleanup(func() { logger.Log = previous })
+
+	reader := telemetrytest.NewMetricReader()
+	o, err := telemetry.NewOpenTelemetryWithOptions(
+		telemetry.WithServiceName("telemetry-test"),
+		telemetry.WithTracingEnabled(false),
+		telemetry.WithMetricsEnabled(true),
+		telemetry.WithMetricEndpoint("127.0.0.1:1"),
+		telemetry.WithInsecure(),
+		telemetry.WithKubernetesDetector(false),
+		reader.Option(),
+	)
+	if err != nil {
+		t.Fatalf("NewOpenTelemetryWithOptions() error = %v", err)
+	}
+	t.Cleanup(func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_ = o.Shutdown(ctx)
+	})
+	return o, reader, logs
+}
+
+// assertDistinctTestCallers checks that the caller and conflictingCaller fields of a
+// warning name two different lines of this file
+func assertDistinctTestCallers(t *testing.T, fields map[string]any) {
+	t.Helper()
+	caller, _ := fields["caller"].(string)
+	conflicting, _ := fields["conflictingCaller"].(string)
+	if !strings.Contains(caller, "instruments_conflict_test.go") || !strings.Contains(conflicting, "instruments_conflict_test.go") || caller == conflicting {
+		t.Errorf("warning call sites = %q and %q, want the two distinct lines of this test", caller, conflicting)
+	}
+}
+
+func TestConflictingInstrumentUnitsWarnOnceAndReuseFirst(t *testing.T) {
+	o, reader, logs := newConflictTelemetry(t)
+	ctx := context.Background()
+
+	o.RecordGauge(ctx, "cache.hit_rate", 0.5)      // registers the gauge without a unit
+	o.RecordPercentage(ctx, "cache.hit_rate", 0.7) // registers it again with unit 1
+	o.RecordPercentage(ctx, "cache.hit_rate", 0.9)
+
+	entries := logs.FilterMessage("Metric instrument registered with conflicting units, reusing the first registration").All()
+	if len(entries) != 1 {
+		t.Fatalf("logged %d conflict warnings, want 1", len(entries))
+	}
+	fields := entries[0].ContextMap()
+	assertDistinctTestCallers(t, fields)
+	if fields["unit"] != "" || fields["conflictingUnit"] != "1" {
+		t.Errorf("warning units = %q and %q, want the first and conflicting units", fields["unit"], fields["conflictingUnit"])
+	}
+
+	if n := len(logs.FilterMessage("Failed to create gauge instrument").All()); n != 0 {
+		t.Errorf("logged %d instrument creation failures, want the first registration reused", n)
+	}
+	m, ok := reader.Metric(t, "cache.hit_rate")
+	if !ok {
+		t.Fatal("cache.hit_rate not collected")
+	}
+	gauge, ok := m.Data.(metricdata.Gauge[float64])
+	if !ok || len(gauge.DataPoints) != 1 || gauge.DataPoints[0].Value != 0.9 {
+		t.Errorf("cache.hit_rate = %+v, want the latest value on the single instrument", m.Data)
+	}
+	if m.Unit != "" {
+		t.Errorf("cache.hit_rate unit = %q, want the first registration's", m.Unit)
+	}
+}
+
+func TestConflictingInstrumentKindsWarnOnceAndKeepFirst(t *testing.T) {
+	o, reader, logs := newConflictTelemetry(t)
+	ctx := context.Background()
+
+	o.IncrementCounter(ctx, "jobs.active", 1) // registers a counter
+	o.RecordGauge(ctx, "jobs.active", 5)      // conflicts with the counter
+	o.RecordGauge(ctx, "jobs.active", 6)
+	o.IncrementCounter(ctx, "jobs.active", 1)
+
+	entries := logs.FilterMessage("Metric recorded with a mismatched instrument kind").All()
+	if len(entries) != 1 {
+		t.Fatalf("logged %d mismatch warnings, want 1", len(entries))
+	}
+	fields := entries[0].ContextMap()
+	assertDistinctTestCallers(t, fields)
+	if fields["expected"] != "counter" || fields["actual"] != "gauge" {
+		t.Errorf("warning kinds = %q and %q, want counter and gauge", fields["expected"], fields["actual"])
+	}
+
+	var count int
+	for _, scope := range reader.Collect(t).ScopeMetrics {
+		for _, m := range scope.Metrics {
+			if m.Name == "jobs.active" {
+				count++
+			}
+		}
+	}
+	if count != 1 {
+		t.Fatalf("collected %d jobs.active instruments, want only the counter", count)
+	}
+	m, _ := reader.Metric(t, "jobs.active")
+	sum, ok := m.Data.(metricdata.Sum[float64])
+	if !ok || len(sum.DataPoints) != 1 || sum.DataPoints[0].Value != 2 {
+		t.Errorf("jobs.active = %+v, want the counter total 2", m.Data)
+	}
+}
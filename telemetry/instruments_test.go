@@ -0,0 +1,129 @@
+package telemetry
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel/metric"
+)
+
+const instrumentKindWarning = "Metric recorded with a mismatched instrument kind"
+
+func TestMismatchedInstrumentKindWarnsOnce(t *testing.T) {
+	logs := observeLogs(t)
+	tt := newTestTelemetry(t)
+	ctx := context.Background()
+
+	tt.IncrementCounter(ctx, "queue.depth", 1)
+	tt.RecordGauge(ctx, "queue.depth", 5)
+	tt.RecordGauge(ctx, "queue.depth", 6)
+	if err := tt.RecordMetricE(ctx, "queue.depth", 1); err != nil {
+		t.Errorf("RecordMetricE() error = %v, want nil for the registered kind", err)
+	}
+
+	entries := logs.FilterMessage(instrumentKindWarning).All()
+	if len(entries) != 1 {
+		t.Fatalf("logged %d mismatch warnings, want 1", len(entries))
+	}
+	fields := entries[0].ContextMap()
+	if fields["name"] != "queue.depth" || fields["expected"] != "counter" || fields["actual"] != "gauge" {
+		t.Errorf("warning fields = %v, want queue.depth expected counter, got gauge", fields)
+	}
+	if points := sumPoints(t, tt.metric(t, "queue.depth")); len(points) != 1 || points[0].Value != 2 {
+		t.Errorf("queue.depth counter = %+v, want 2", points)
+	}
+}
+
+func TestStrictInstrumentsRejectMismatchedKind(t *testing.T) {
+	logs := observeLogs(t)
+	tt := newTestTelemetry(t,
+		WithStrictInstruments(true),
+		WithInstrumentKinds(map[string]InstrumentKind{"requests.total": InstrumentKindCounter}))
+	ctx := context.Background()
+
+	// Declared as a counter, so even the first use as a gauge is rejected
+	tt.RecordGauge(ctx, "requests.total", 3)
+	if _, ok := findMetric(tt.collect(t), "requests.total"); ok {
+		t.Error("requests.total recorded as a gauge despite strict instruments")
+	}
+	if n := logs.FilterMessage(instrumentKindWarning).Len(); n != 1 {
+		t.Errorf("logged %d mismatch warnings, want 1", n)
+	}
+
+	tt.RecordGauge(ctx, "queue.depth", 4)
+	if err := tt.RecordMetricE(ctx, "queue.depth", 1); err == nil {
+		t.Error("RecordMetricE() error = nil for a gauge recorded as a counter")
+	}
+	if points := gaugePoints(t, tt.metric(t, "queue.depth")); len(points) != 1 || points[0].Value != 4 {
+		t.Errorf("queue.depth = %+v, want only the gauge value", points)
+	}
+	if err := tt.RecordMetricE(ctx, "requests.total", 1); err != nil {
+		t.Errorf("RecordMetricE() error = %v for the declared kind", err)
+	}
+}
+
+func newInstrumentBenchmarkTelemetry(b *testing.B) *OpenTelemetry {
+	o, err := NewOpenTelemetryWithOptions(
+		WithServiceName("telemetry-bench"),
+		WithTracingEnabled(false),
+		WithMetricsEnabled(true),
+		WithMetricEndpoint("127.0.0.1:1"),
+		WithInsecure(),
+		WithKubernetesDetector(false),
+	)
+	if err != nil {
+		b.Fatalf("NewOpenTelemetryWithOptions() error = %v", err)
+	}
+	b.Cleanup(func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_ = o.Shutdown(ctx)
+	})
+	return o
+}
+
+// BenchmarkCachedInstrument measures the lookup of an already registered instrument,
+// which must not allocate
+func BenchmarkCachedInstrument(b *testing.B) {
+	o := newInstrumentBenchmarkTelemetry(b)
+	name := "requests.handled"
+	create := func() (metric.Float64Counter, error) { return o.meter.Float64Counter(name) }
+	if _, err := cachedInstrument(o, InstrumentKindCounter, name, "", create); err != nil {
+		b.Fatalf("cachedInstrument() error = %v", err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := cachedInstrument(o, InstrumentKindCounter, name, "", create); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkUncachedInstrument measures asking the meter for the instrument on every
+// recording, as was done before instruments were cached
+func BenchmarkUncachedInstrument(b *testing.B) {
+	o := newInstrumentBenchmarkTelemetry(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := o.meter.Float64Counter("requests.handled"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestCachedInstrumentDoesNotAllocate(t *testing.T) {
+	tt := newTestTelemetry(t)
+	name := "requests.handled"
+	create := func() (metric.Float64Counter, error) { return tt.meter.Float64Counter(name) }
+	if _, err := cachedInstrument(tt.OpenTelemetry, InstrumentKindCounter, name, "", create); err != nil {
+		t.Fatalf("cachedInstrument() error = %v", err)
+	}
+	allocs := testing.AllocsPerRun(100, func() {
+		_, _ = cachedInstrument(tt.OpenTelemetry, InstrumentKindCounter, name, "", create)
+	})
+	if allocs != 0 {
+		t.Errorf("cached instrument lookup allocated %v times, want 0", allocs)
+	}
+}
@@ -0,0 +1,104 @@
+// kpi.go - Daily business KPIs, accumulated per day and reset at the day boundary
+
+package telemetry
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sadco-io/sad-go-logger/logger"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.uber.org/zap"
+)
+
+// dailyKPI accumulates the values of a KPI per attribute set for the current day
+type dailyKPI struct {
+	loc *time.Location
+	now func() time.Time
+
+	mu     sync.Mutex
+	day    string
+	values map[attribute.Distinct]*accumulatedValue
+}
+
+// newDailyKPI returns a KPI whose days start at midnight in loc, as told by now
+func newDailyKPI(loc *time.Location, now func() time.Time) *dailyKPI {
+	return &dailyKPI{loc: loc, now: now, values: make(map[attribute.Distinct]*accumulatedValue)}
+}
+
+// roll resets the values to zero when the day has changed since the last call. It
+// must be called with mu held.
+func (k *dailyKPI) roll() {
+	day := k.now().In(k.loc).Format(time.DateOnly)
+	if day == k.day {
+		return
+	}
+	k.day = day
+	for _, v := range k.values {
+		v.value = 0
+	}
+}
+
+// add adds value to the total of attrs for the current day
+func (k *dailyKPI) add(attrs attribute.Set, value float64) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.roll()
+	v, ok := k.values[attrs.Equivalent()]
+	if !ok {
+		v = &accumulatedValue{attrs: attrs}
+		k.values[attrs.Equivalent()] = v
+	}
+	v.value += value
+}
+
+// observe reports the totals of the current day
+func (k *dailyKPI) observe(_ context.Context, observer metric.Float64Observer) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.roll()
+	for _, v := range k.values {
+		observer.Observe(v.value, metric.WithAttributeSet(v.attrs))
+	}
+	return nil
+}
+
+// RecordDailyKPI adds value to a gauge reporting the total added since the start of
+// the day, such as signups or orders today. Days start at midnight in the location
+// set with WithKPILocation (default: UTC); the totals reset to zero once per day,
+// however often they are recorded or collected.
+func (o *OpenTelemetry) RecordDailyKPI(ctx context.Context, name string, value float64, attributes ...attribute.KeyValue) {
+	if !o.metricsEnabled || metricsSuppressed(ctx) {
+		return
+	}
+	if keep, err := o.checkInstrumentKind(name, InstrumentKindGauge); !keep {
+		if err != nil {
+			logger.Log.Error("Dropping metric value", zap.Error(err))
+		}
+		return
+	}
+	attrs, keep := o.metricAttributes(name, attributes)
+	if !keep {
+		return
+	}
+
+	o.dailyKPIsMu.Lock()
+	kpi, ok := o.dailyKPIs[name]
+	if !ok {
+		kpi = newDailyKPI(o.config.kpiLocation, time.Now)
+		if _, err := o.meter.Float64ObservableGauge(name, metric.WithFloat64Callback(kpi.observe)); err != nil {
+			o.dailyKPIsMu.Unlock()
+			logger.Log.Error("Failed to create daily KPI instrument", zap.Error(err))
+			return
+		}
+		if o.dailyKPIs == nil {
+			o.dailyKPIs = make(map[string]*dailyKPI)
+		}
+		o.dailyKPIs[name] = kpi
+	}
+	o.dailyKPIsMu.Unlock()
+
+	kpi.add(attribute.NewSet(attrs...), value)
+}
@@ -0,0 +1,73 @@
+package telemetry
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// kpiValues returns the values of a daily KPI gauge by its plan attribute
+func kpiValues(t *testing.T, tt *testTelemetry, name string) map[string]float64 {
+	t.Helper()
+	values := make(map[string]float64)
+	m, ok := findMetric(tt.collect(t), name)
+	if !ok {
+		return values
+	}
+	for _, p := range gaugePoints(t, m) {
+		values[attrMap(p.Attributes.ToSlice())["plan"]] = p.Value
+	}
+	return values
+}
+
+func TestDailyKPIResetsAtLocalMidnight(t *testing.T) {
+	tt := newTestTelemetry(t)
+	loc := time.FixedZone("UTC-5", -5*60*60)
+	now := time.Date(2026, 3, 1, 18, 0, 0, 0, loc)
+	kpi := newDailyKPI(loc, func() time.Time { return now })
+	if _, err := tt.meter.Float64ObservableGauge("signups.today", metric.WithFloat64Callback(kpi.observe)); err != nil {
+		t.Fatalf("Float64ObservableGauge() error = %v", err)
+	}
+	free, pro := attribute.NewSet(attribute.String("plan", "free")), attribute.NewSet(attribute.String("plan", "pro"))
+
+	kpi.add(free, 3)
+	kpi.add(pro, 1)
+	kpi.add(free, 2)
+	if got := kpiValues(t, tt, "signups.today"); got["free"] != 5 || got["pro"] != 1 {
+		t.Fatalf("signups.today = %v, want free=5 and pro=1", got)
+	}
+
+	// 20:00 local is already the next day in UTC, but not in the KPI's location
+	now = time.Date(2026, 3, 1, 20, 0, 0, 0, loc)
+	kpi.add(pro, 1)
+	if got := kpiValues(t, tt, "signups.today"); got["free"] != 5 || got["pro"] != 2 {
+		t.Errorf("signups.today before local midnight = %v, want free=5 and pro=2", got)
+	}
+
+	now = time.Date(2026, 3, 2, 0, 5, 0, 0, loc)
+	if got := kpiValues(t, tt, "signups.today"); got["free"] != 0 || got["pro"] != 0 {
+		t.Errorf("signups.today after local midnight = %v, want both reset to 0", got)
+	}
+	kpi.add(free, 1)
+	if got := kpiValues(t, tt, "signups.today"); got["free"] != 1 || got["pro"] != 0 {
+		t.Errorf("signups.today on the new day = %v, want free=1 and pro=0", got)
+	}
+}
+
+func TestRecordDailyKPIAccumulatesInConfiguredLocation(t *testing.T) {
+	loc := time.FixedZone("UTC+9", 9*60*60)
+	tt := newTestTelemetry(t, WithKPILocation(loc))
+	ctx := context.Background()
+
+	tt.RecordDailyKPI(ctx, "orders.today", 2, attribute.String("plan", "pro"))
+	tt.RecordDailyKPI(ctx, "orders.today", 3, attribute.String("plan", "pro"))
+	if got := kpiValues(t, tt, "orders.today"); got["pro"] != 5 {
+		t.Errorf("orders.today = %v, want pro=5", got)
+	}
+	if kpi := tt.dailyKPIs["orders.today"]; kpi == nil || kpi.loc != loc {
+		t.Errorf("daily KPI location = %v, want the WithKPILocation location", kpi)
+	}
+}
@@ -0,0 +1,92 @@
+// links.go - Spans linking to other traces, for fan-in, batch processing and background jobs
+
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"net/url"
+
+	"github.com/sadco-io/sad-go-logger/logger"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+	"go.uber.org/zap"
+)
+
+// errNoTriggerSpan is returned when storing a trigger context without a valid span
+var errNoTriggerSpan = errors.New("context has no valid span context to store")
+
+// JoinContexts starts a span named name as a child of the span in primary, linked
+// to the span in secondary. This is used at fan-in points of scatter-gather flows.
+func (o *OpenTelemetry) JoinContexts(primary, secondary context.Context, name string) (context.Context, trace.Span) {
+	if !o.traceEnabled {
+		return primary, noop.Span{}
+	}
+	var opts []trace.SpanStartOption
+	if sc := trace.SpanContextFromContext(secondary); sc.IsValid() {
+		opts = append(opts, trace.WithLinks(trace.Link{SpanContext: sc}))
+	}
+	return o.tracer.Start(primary, name, opts...)
+}
+
+// StartSpanWithLinks starts a span named name as a child of the span in ctx, linked
+// to links, e.g. the producer spans of the messages of a batch processed at once.
+// Links without a valid span context are dropped by the SDK.
+func (o *OpenTelemetry) StartSpanWithLinks(ctx context.Context, name string, links []trace.Link) (context.Context, trace.Span) {
+	if !o.traceEnabled {
+		return ctx, noop.Span{}
+	}
+	return o.tracer.Start(ctx, name, trace.WithLinks(links...))
+}
+
+// StoreTriggerContext serializes the span context of ctx to a portable string, the
+// W3C traceparent and tracestate as URL query parameters, so a background job stored
+// in a database or queue can later link back to its trigger with StartJobSpan
+func (o *OpenTelemetry) StoreTriggerContext(ctx context.Context) (string, error) {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return "", errNoTriggerSpan
+	}
+	carrier := propagation.MapCarrier{}
+	propagation.TraceContext{}.Inject(ctx, carrier)
+	values := url.Values{}
+	for _, key := range carrier.Keys() {
+		values.Set(key, carrier.Get(key))
+	}
+	return values.Encode(), nil
+}
+
+// StartJobSpan starts a root span named name for a background job, linked to the
+// trigger span serialized by StoreTriggerContext. An invalid serialized context is
+// logged and the span is started without a link.
+func (o *OpenTelemetry) StartJobSpan(ctx context.Context, serialized, name string) (context.Context, trace.Span) {
+	if !o.traceEnabled {
+		return ctx, noop.Span{}
+	}
+	opts := []trace.SpanStartOption{trace.WithNewRoot()}
+	if sc, err := parseTriggerContext(serialized); err != nil {
+		logger.Log.Warn("Failed to parse trigger context, starting job span without link",
+			zap.Error(err),
+			zap.String("span", name))
+	} else {
+		opts = append(opts, trace.WithLinks(trace.Link{SpanContext: sc}))
+	}
+	return o.tracer.Start(ctx, name, opts...)
+}
+
+// parseTriggerContext parses a span context serialized by StoreTriggerContext
+func parseTriggerContext(serialized string) (trace.SpanContext, error) {
+	values, err := url.ParseQuery(serialized)
+	if err != nil {
+		return trace.SpanContext{}, err
+	}
+	carrier := propagation.MapCarrier{}
+	for key := range values {
+		carrier.Set(key, values.Get(key))
+	}
+	sc := trace.SpanContextFromContext(propagation.TraceContext{}.Extract(context.Background(), carrier))
+	if !sc.IsValid() {
+		return trace.SpanContext{}, errors.New("serialized trigger context has no valid traceparent")
+	}
+	return sc, nil
+}
@@ -0,0 +1,176 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestJoinContextsLinksSecondaryTrace(t *testing.T) {
+	tt := newTestTelemetry(t)
+
+	primary, primarySpan := tt.StartSpan(context.Background(), "scatter-a")
+	secondary, secondarySpan := tt.StartSpan(context.Background(), "scatter-b")
+	if primarySpan.SpanContext().TraceID() == secondarySpan.SpanContext().TraceID() {
+		t.Fatal("scatter spans share a trace ID, want two distinct traces")
+	}
+
+	_, joined := tt.JoinContexts(primary, secondary, "gather")
+	tt.EndSpan(joined)
+	tt.EndSpan(secondarySpan)
+	tt.EndSpan(primarySpan)
+
+	span := tt.endedSpan(t, "gather")
+	if span.Parent().SpanID() != primarySpan.SpanContext().SpanID() ||
+		span.SpanContext().TraceID() != primarySpan.SpanContext().TraceID() {
+		t.Errorf("joined span parent = %v, want a child of the primary span", span.Parent())
+	}
+	links := span.Links()
+	if len(links) != 1 || !links[0].SpanContext.Equal(secondarySpan.SpanContext()) {
+		t.Errorf("joined span links = %+v, want a link to the secondary span", links)
+	}
+}
+
+func TestJoinContextsWithoutSecondarySpan(t *testing.T) {
+	tt := newTestTelemetry(t)
+
+	primary, primarySpan := tt.StartSpan(context.Background(), "scatter")
+	_, joined := tt.JoinContexts(primary, context.Background(), "gather")
+	tt.EndSpan(joined)
+	tt.EndSpan(primarySpan)
+
+	if links := tt.endedSpan(t, "gather").Links(); len(links) != 0 {
+		t.Errorf("joined span links = %+v, want none without a secondary span", links)
+	}
+}
+
+func TestStoreTriggerContextAndStartJobSpan(t *testing.T) {
+	tt := newTestTelemetry(t)
+
+	ctx, trigger := tt.StartSpan(context.Background(), "enqueue report")
+	serialized, err := tt.StoreTriggerContext(ctx)
+	if err != nil {
+		t.Fatalf("StoreTriggerContext() error = %v", err)
+	}
+	tt.EndSpan(trigger)
+
+	// The job runs later, without the trigger's context
+	_, job := tt.StartJobSpan(context.Background(), serialized, "generate report")
+	tt.EndSpan(job)
+
+	span := tt.endedSpan(t, "generate report")
+	if span.Parent().IsValid() {
+		t.Errorf("job span parent = %v, want a root span", span.Parent())
+	}
+	links := span.Links()
+	if len(links) != 1 || !links[0].SpanContext.Equal(trigger.SpanContext().WithRemote(true)) {
+		t.Errorf("job span links = %+v, want a link to the trigger span %v", links, trigger.SpanContext())
+	}
+}
+
+func TestStartJobSpanWithInvalidTriggerContext(t *testing.T) {
+	logs := observeLogs(t)
+	tt := newTestTelemetry(t)
+
+	if _, err := tt.StoreTriggerContext(context.Background()); err == nil {
+		t.Error("StoreTriggerContext() error = nil without a span")
+	}
+
+	_, job := tt.StartJobSpan(context.Background(), "traceparent=garbage", "generate report")
+	tt.EndSpan(job)
+
+	if links := tt.endedSpan(t, "generate report").Links(); len(links) != 0 {
+		t.Errorf("job span links = %+v, want none for an invalid trigger context", links)
+	}
+	if n := logs.FilterMessage("Failed to parse trigger context, starting job span without link").Len(); n != 1 {
+		t.Errorf("logged %d parse failures, want 1", n)
+	}
+}
+
+func TestSpanStartersReturnNoopSpanWithoutTracing(t *testing.T) {
+	type callerKey struct{}
+	tt := newTestTelemetry(t, WithTracingEnabled(false))
+	ctx := context.WithValue(context.Background(), callerKey{}, "caller")
+
+	starters := map[string]func() (context.Context, trace.Span){
+		"StartSpan": func() (context.Context, trace.Span) {
+			return tt.StartSpan(ctx, "operation")
+		},
+		"StartSpanWithKind": func() (context.Context, trace.Span) {
+			return tt.StartSpanWithKind(ctx, "operation", trace.SpanKindClient)
+		},
+		"StartSpanWithLinks": func() (context.Context, trace.Span) {
+			return tt.StartSpanWithLinks(ctx, "batch", nil)
+		},
+		"JoinContexts": func() (context.Context, trace.Span) {
+			return tt.JoinContexts(ctx, context.Background(), "join")
+		},
+		"StartJobSpan": func() (context.Context, trace.Span) {
+			return tt.StartJobSpan(ctx, "", "job")
+		},
+		"StartPublishSpan": func() (context.Context, trace.Span) {
+			return tt.StartPublishSpan(ctx, "kafka", "orders")
+		},
+		"StartConsumeSpan": func() (context.Context, trace.Span) {
+			return tt.StartConsumeSpan(ctx, "kafka", "orders")
+		},
+	}
+	for name, start := range starters {
+		gotCtx, span := start()
+		if span == nil {
+			t.Errorf("%s() span = nil, want a no-op span", name)
+			continue
+		}
+		if span.IsRecording() || span.SpanContext().IsValid() {
+			t.Errorf("%s() span is recording or valid with tracing disabled", name)
+		}
+		if gotCtx != ctx {
+			t.Errorf("%s() returned a different context, want the caller's", name)
+		}
+		span.SetAttributes(attribute.String("key", "value"))
+		span.End()
+	}
+
+	_, span := tt.StartPublishSpan(ctx, "kafka", "orders")
+	tt.EndPublishSpan(span, "m-1", errors.New("broker unavailable"))
+	if n := len(tt.spans.Ended()); n != 0 {
+		t.Errorf("recorded %d spans with tracing disabled, want 0", n)
+	}
+}
+
+func TestStartSpanWithLinksAttachesEveryLink(t *testing.T) {
+	tt := newTestTelemetry(t)
+	var tel Telemetry = tt.OpenTelemetry
+
+	_, first := tel.StartSpan(context.Background(), "publish-1")
+	_, second := tel.StartSpan(context.Background(), "publish-2")
+	tel.EndSpan(first)
+	tel.EndSpan(second)
+
+	_, batch := tel.StartSpanWithLinks(context.Background(), "process batch", []trace.Link{
+		{SpanContext: first.SpanContext(), Attributes: []attribute.KeyValue{attribute.Int("messaging.batch.index", 0)}},
+		{SpanContext: second.SpanContext(), Attributes: []attribute.KeyValue{attribute.Int("messaging.batch.index", 1)}},
+	})
+	tel.EndSpan(batch)
+
+	span := tt.endedSpan(t, "process batch")
+	if span.Parent().IsValid() {
+		t.Errorf("batch span parent = %v, want a new root linked to the producers", span.Parent())
+	}
+	links := span.Links()
+	if len(links) != 2 {
+		t.Fatalf("batch span has %d links, want 2", len(links))
+	}
+	for i, want := range []trace.Span{first, second} {
+		if !links[i].SpanContext.Equal(want.SpanContext()) {
+			t.Errorf("link %d = %v, want the span context of publish-%d", i, links[i].SpanContext, i+1)
+		}
+		if got := attrMap(links[i].Attributes)["messaging.batch.index"]; got != strconv.Itoa(i) {
+			t.Errorf("link %d messaging.batch.index = %q, want %d", i, got, i)
+		}
+	}
+}
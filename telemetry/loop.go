@@ -0,0 +1,48 @@
+// loop.go - Instrumentation of periodic, ticker-driven worker loops
+
+package telemetry
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const (
+	loopNameKey      = attribute.Key("loop.name")
+	loopIterationKey = attribute.Key("loop.iteration")
+)
+
+// InstrumentLoop runs fn every interval until ctx is done, each run in a span named
+// name with a loop.iteration attribute counting from 1. Errors returned by fn are
+// recorded on the span and the loop continues. When an iteration starts behind
+// schedule, e.g. because the previous one outlasted interval, the delay since its
+// scheduled tick is recorded in milliseconds in the loop.lag_ms gauge with a
+// loop.name attribute.
+func InstrumentLoop(ctx context.Context, t Telemetry, name string, interval time.Duration, fn func(ctx context.Context) error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	runLoop(ctx, t, name, ticker.C, time.Now, fn)
+}
+
+// runLoop runs the iterations of InstrumentLoop on the ticks of ticks, measuring
+// the lag with now
+func runLoop(ctx context.Context, t Telemetry, name string, ticks <-chan time.Time, now func() time.Time, fn func(ctx context.Context) error) {
+	for iteration := int64(1); ; iteration++ {
+		var tick time.Time
+		select {
+		case <-ctx.Done():
+			return
+		case tick = <-ticks:
+		}
+		if lag := now().Sub(tick); lag >= time.Millisecond {
+			t.RecordGauge(ctx, "loop.lag_ms", float64(lag.Milliseconds()), loopNameKey.String(name))
+		}
+
+		iterCtx, span := t.StartSpan(ctx, name)
+		span.SetAttributes(loopIterationKey.Int64(iteration))
+		t.RecordError(iterCtx, fn(iterCtx))
+		t.EndSpan(span)
+	}
+}
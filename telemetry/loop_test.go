@@ -0,0 +1,89 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/codes"
+)
+
+func TestRunLoopSpansPerIterationAndLag(t *testing.T) {
+	tt := newTestTelemetry(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	now := start
+	ticks := make(chan time.Time)
+	ran := make(chan int, 1)
+	iteration := 0
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		runLoop(ctx, tt, "reconcile", ticks, func() time.Time { return now }, func(context.Context) error {
+			iteration++
+			ran <- iteration
+			if iteration == 2 {
+				return errors.New("reconcile failed")
+			}
+			return nil
+		})
+	}()
+
+	// The first tick runs on schedule, the second 25ms late and the third 300µs late
+	for i, lag := range []time.Duration{0, 25 * time.Millisecond, 300 * time.Microsecond} {
+		tick := start.Add(time.Duration(i) * time.Second)
+		now = tick.Add(lag)
+		ticks <- tick
+		<-ran
+	}
+	cancel()
+	<-done
+
+	spans := tt.endedSpans("reconcile")
+	if len(spans) != 3 {
+		t.Fatalf("got %d loop spans, want one per iteration", len(spans))
+	}
+	for i, span := range spans {
+		if got, want := attrMap(span.Attributes())["loop.iteration"], strconv.Itoa(i+1); got != want {
+			t.Errorf("span %d loop.iteration = %q, want %q", i, got, want)
+		}
+	}
+	if got := spans[1].Status(); got.Code != codes.Error || got.Description != "reconcile failed" {
+		t.Errorf("failed iteration status = %+v, want the error", got)
+	}
+	if spans[0].Status().Code == codes.Error || spans[2].Status().Code == codes.Error {
+		t.Error("successful iterations have an error status")
+	}
+
+	points := gaugePoints(t, tt.metric(t, "loop.lag_ms"))
+	if len(points) != 1 || points[0].Value != 25 {
+		t.Fatalf("loop.lag_ms = %+v, want only the 25ms lag", points)
+	}
+	if name := attrMap(points[0].Attributes.ToSlice())["loop.name"]; name != "reconcile" {
+		t.Errorf("loop.name = %q, want reconcile", name)
+	}
+}
+
+func TestInstrumentLoopStopsOnCancel(t *testing.T) {
+	tt := newTestTelemetry(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		InstrumentLoop(ctx, tt, "reconcile", time.Hour, func(context.Context) error { return nil })
+	}()
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("InstrumentLoop did not return after the context was cancelled")
+	}
+	if spans := tt.endedSpans("reconcile"); len(spans) != 0 {
+		t.Errorf("got %d loop spans before the first tick, want none", len(spans))
+	}
+}
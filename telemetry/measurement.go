@@ -0,0 +1,59 @@
+// measurement.go - Fluent builder for metric measurements with dynamic dimensions
+
+package telemetry
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Measurement builds the attributes of a metric measurement and records it with the
+// instrument matching the recording method:
+//
+//	t.Measure("http.requests").WithTag("method", "GET").WithTag("status", "200").Inc(ctx)
+//
+// A Measurement is immutable, so a partially built one can be shared and extended.
+type Measurement struct {
+	o          *OpenTelemetry
+	name       string
+	attributes []attribute.KeyValue
+}
+
+// Measure starts building a measurement of the named metric
+func (o *OpenTelemetry) Measure(name string) Measurement {
+	return Measurement{o: o, name: name}
+}
+
+// WithTag returns a copy of the measurement with a string attribute added
+func (m Measurement) WithTag(key, value string) Measurement {
+	return m.WithAttributes(attribute.String(key, value))
+}
+
+// WithAttributes returns a copy of the measurement with the attributes added
+func (m Measurement) WithAttributes(attributes ...attribute.KeyValue) Measurement {
+	combined := make([]attribute.KeyValue, 0, len(m.attributes)+len(attributes))
+	combined = append(combined, m.attributes...)
+	m.attributes = append(combined, attributes...)
+	return m
+}
+
+// Inc increments the metric as a counter by one
+func (m Measurement) Inc(ctx context.Context) {
+	m.o.IncrementCounter(ctx, m.name, 1, m.attributes...)
+}
+
+// Add increments the metric as a counter by value
+func (m Measurement) Add(ctx context.Context, value float64) {
+	m.o.IncrementCounter(ctx, m.name, value, m.attributes...)
+}
+
+// Set records value for the metric as a gauge
+func (m Measurement) Set(ctx context.Context, value float64) {
+	m.o.RecordGauge(ctx, m.name, value, m.attributes...)
+}
+
+// Observe records value for the metric as a histogram
+func (m Measurement) Observe(ctx context.Context, value float64) {
+	m.o.RecordHistogram(ctx, m.name, value, m.attributes...)
+}
@@ -0,0 +1,58 @@
+package telemetry
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+func TestMeasurementRecordsWithBuiltAttributes(t *testing.T) {
+	tt := newTestTelemetry(t)
+	ctx := context.Background()
+
+	requests := tt.Measure("http.requests").WithTag("service", "orders")
+	requests.WithTag("status", "200").Inc(ctx)
+	requests.WithTag("status", "200").Add(ctx, 2)
+	requests.WithTag("status", "500").Inc(ctx)
+	tt.Measure("queue.depth").WithAttributes(attribute.Int("partition", 3)).Set(ctx, 12)
+	tt.Measure("payload.size").WithTag("service", "orders").Observe(ctx, 512)
+
+	counts := make(map[string]float64)
+	for _, p := range sumPoints(t, tt.metric(t, "http.requests")) {
+		attrs := attrMap(p.Attributes.ToSlice())
+		if len(attrs) != 2 || attrs["service"] != "orders" {
+			t.Errorf("http.requests attributes = %v, want the shared service and the status", attrs)
+		}
+		counts[attrs["status"]] += p.Value
+	}
+	if len(counts) != 2 || counts["200"] != 3 || counts["500"] != 1 {
+		t.Errorf("http.requests = %v, want the extensions of the shared measurement kept apart", counts)
+	}
+
+	gauge := gaugePoints(t, tt.metric(t, "queue.depth"))
+	if len(gauge) != 1 || gauge[0].Value != 12 || attrMap(gauge[0].Attributes.ToSlice())["partition"] != "3" {
+		t.Errorf("queue.depth = %+v, want 12 for partition 3", gauge)
+	}
+	histogram := histogramPoints(t, tt.metric(t, "payload.size"))
+	if len(histogram) != 1 || histogram[0].Count != 1 || histogram[0].Sum != 512 {
+		t.Errorf("payload.size = %+v, want a single observation of 512", histogram)
+	}
+}
+
+func TestMeasurementWithAttributesDoesNotShareBackingArray(t *testing.T) {
+	tt := newTestTelemetry(t)
+	base := tt.Measure("jobs").WithTag("queue", "default").WithTag("region", "eu")
+	first := base.WithTag("state", "done")
+	second := base.WithTag("state", "failed")
+
+	if got := attrMap(first.attributes)["state"]; got != "done" {
+		t.Errorf("first measurement state = %q after extending the shared base again, want done", got)
+	}
+	if got := attrMap(second.attributes)["state"]; got != "failed" {
+		t.Errorf("second measurement state = %q, want failed", got)
+	}
+	if len(base.attributes) != 2 {
+		t.Errorf("base measurement has %d attributes, want it unchanged", len(base.attributes))
+	}
+}
@@ -0,0 +1,78 @@
+// messaging.go - Messaging instrumentation helpers for producers and consumers
+
+package telemetry
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// RecordConsumerLag records the number of messages a consumer is behind on a topic
+// partition as the messaging.consumer.lag gauge. The messaging.system attribute is
+// set from WithMessagingSystem (default: kafka).
+func (o *OpenTelemetry) RecordConsumerLag(ctx context.Context, topic string, partition int, lag int64) {
+	o.RecordGauge(ctx, "messaging.consumer.lag", float64(lag),
+		semconv.MessagingSystemKey.String(o.config.messagingSystem),
+		semconv.MessagingSourceNameKey.String(topic),
+		semconv.MessagingKafkaSourcePartitionKey.Int(partition),
+	)
+}
+
+// StartPublishSpan starts a producer span named "<destination> publish" for a
+// message published to destination, with the messaging.system,
+// messaging.destination.name and messaging.operation=publish attributes. Inject the
+// returned context into the message headers to link consumers to it, and end the
+// span with EndPublishSpan.
+func (o *OpenTelemetry) StartPublishSpan(ctx context.Context, system, destination string) (context.Context, trace.Span) {
+	if !o.traceEnabled {
+		return ctx, noop.Span{}
+	}
+	return o.tracer.Start(ctx, sanitizeString(destination)+" publish",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(sanitizeAttributes([]attribute.KeyValue{
+			semconv.MessagingSystemKey.String(system),
+			semconv.MessagingDestinationNameKey.String(destination),
+			semconv.MessagingOperationPublish,
+		})...))
+}
+
+// EndPublishSpan ends a span started with StartPublishSpan, setting the
+// messaging.message.id attribute if messageID is not empty and recording err, if
+// any, as the publish failure
+func (o *OpenTelemetry) EndPublishSpan(span trace.Span, messageID string, err error) {
+	if span == nil {
+		return
+	}
+	if messageID != "" {
+		span.SetAttributes(semconv.MessagingMessageIDKey.String(sanitizeString(messageID)))
+	}
+	o.RecordError(trace.ContextWithSpan(context.Background(), span), err)
+	span.End()
+}
+
+// StartConsumeSpan starts a consumer span named "<source> process" for processing
+// a message received from source, with the messaging.system,
+// messaging.source.name and messaging.operation=process attributes. Extract the
+// message headers into ctx with ExtractContext first: the span then continues the
+// producer's trace and links to the publish span.
+func (o *OpenTelemetry) StartConsumeSpan(ctx context.Context, system, source string) (context.Context, trace.Span) {
+	if !o.traceEnabled {
+		return ctx, noop.Span{}
+	}
+	opts := []trace.SpanStartOption{
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(sanitizeAttributes([]attribute.KeyValue{
+			semconv.MessagingSystemKey.String(system),
+			semconv.MessagingSourceNameKey.String(source),
+			semconv.MessagingOperationProcess,
+		})...),
+	}
+	if producer := trace.SpanContextFromContext(ctx); producer.IsValid() {
+		opts = append(opts, trace.WithLinks(trace.Link{SpanContext: producer}))
+	}
+	return o.tracer.Start(ctx, sanitizeString(source)+" process", opts...)
+}
@@ -0,0 +1,137 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestRecordConsumerLagGauge(t *testing.T) {
+	tests := []struct {
+		name   string
+		opts   []Option
+		system string
+	}{
+		{"default system", nil, "kafka"},
+		{"configured system", []Option{WithMessagingSystem("rabbitmq")}, "rabbitmq"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tt := newTestTelemetry(t, tc.opts...)
+			ctx := context.Background()
+
+			tt.RecordConsumerLag(ctx, "orders", 3, 120)
+			tt.RecordConsumerLag(ctx, "orders", 3, 80)
+			tt.RecordConsumerLag(ctx, "orders", 4, 5)
+
+			points := gaugePoints(t, tt.metric(t, "messaging.consumer.lag"))
+			if len(points) != 2 {
+				t.Fatalf("got %d data points, want one per partition: %+v", len(points), points)
+			}
+			want := map[string]float64{"3": 80, "4": 5}
+			for _, p := range points {
+				attrs := attrMap(p.Attributes.ToSlice())
+				if attrs["messaging.system"] != tc.system || attrs["messaging.source.name"] != "orders" {
+					t.Errorf("attributes = %v, want messaging.system=%s and the topic", attrs, tc.system)
+				}
+				partition := attrs["messaging.kafka.source.partition"]
+				if lag, ok := want[partition]; !ok || p.Value != lag {
+					t.Errorf("partition %q lag = %v, want %v", partition, p.Value, lag)
+				}
+			}
+		})
+	}
+}
+
+func TestPublishSpan(t *testing.T) {
+	tt := newTestTelemetry(t)
+	parent, root := tt.StartSpan(context.Background(), "place order")
+
+	_, published := tt.StartPublishSpan(parent, "kafka", "orders")
+	tt.EndPublishSpan(published, "msg-17", nil)
+	_, failed := tt.StartPublishSpan(parent, "kafka", "orders")
+	tt.EndPublishSpan(failed, "", errors.New("broker unavailable"))
+	tt.EndSpan(root)
+
+	spans := tt.endedSpans("orders publish")
+	if len(spans) != 2 {
+		t.Fatalf("got %d publish spans, want 2", len(spans))
+	}
+	ok := spans[0]
+	if ok.SpanKind() != trace.SpanKindProducer || ok.Parent().SpanID() != root.SpanContext().SpanID() {
+		t.Errorf("publish span kind %v with parent %v, want a producer child of the caller's span", ok.SpanKind(), ok.Parent())
+	}
+	attrs := attrMap(ok.Attributes())
+	for key, want := range map[string]string{
+		"messaging.system":           "kafka",
+		"messaging.destination.name": "orders",
+		"messaging.operation":        "publish",
+		"messaging.message.id":       "msg-17",
+	} {
+		if attrs[key] != want {
+			t.Errorf("%s = %q, want %q", key, attrs[key], want)
+		}
+	}
+	if ok.Status().Code == codes.Error {
+		t.Errorf("successful publish status = %v", ok.Status())
+	}
+
+	if _, hasID := attrMap(spans[1].Attributes())["messaging.message.id"]; hasID {
+		t.Error("failed publish has a message ID, want none for an empty ID")
+	}
+	if got := spans[1].Status(); got.Code != codes.Error || got.Description != "broker unavailable" {
+		t.Errorf("failed publish status = %+v, want the publish error", got)
+	}
+
+	tt.EndPublishSpan(nil, "msg-18", errors.New("ignored"))
+}
+
+func TestConsumeSpanLinksToProducerTrace(t *testing.T) {
+	tt := newTestTelemetry(t)
+	const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	headers := propagation.MapCarrier{"traceparent": traceparent}
+
+	ctx := tt.ExtractContext(context.Background(), headers)
+	_, span := tt.StartConsumeSpan(ctx, "kafka", "orders")
+	tt.EndSpan(span)
+
+	consumed := tt.endedSpan(t, "orders process")
+	if consumed.SpanKind() != trace.SpanKindConsumer {
+		t.Errorf("span kind = %v, want consumer", consumed.SpanKind())
+	}
+	if got := consumed.SpanContext().TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("consume span trace ID = %s, want the producer's trace", got)
+	}
+	if got := consumed.Parent().SpanID().String(); got != "00f067aa0ba902b7" || !consumed.Parent().IsRemote() {
+		t.Errorf("consume span parent = %v, want the remote publish span", consumed.Parent())
+	}
+	links := consumed.Links()
+	if len(links) != 1 || links[0].SpanContext.SpanID().String() != "00f067aa0ba902b7" {
+		t.Fatalf("consume span links = %+v, want one link to the publish span", links)
+	}
+	attrs := attrMap(consumed.Attributes())
+	for key, want := range map[string]string{
+		"messaging.system":      "kafka",
+		"messaging.source.name": "orders",
+		"messaging.operation":   "process",
+	} {
+		if attrs[key] != want {
+			t.Errorf("%s = %q, want %q", key, attrs[key], want)
+		}
+	}
+}
+
+func TestConsumeSpanWithoutProducerHasNoLinks(t *testing.T) {
+	tt := newTestTelemetry(t)
+	_, span := tt.StartConsumeSpan(context.Background(), "kafka", "orders")
+	tt.EndSpan(span)
+
+	consumed := tt.endedSpan(t, "orders process")
+	if consumed.Parent().IsValid() || len(consumed.Links()) != 0 {
+		t.Errorf("consume span without headers has parent %v and links %+v, want a new root", consumed.Parent(), consumed.Links())
+	}
+}
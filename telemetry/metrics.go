@@ -0,0 +1,211 @@
+// metrics.go - Higher-level metric recording helpers built on the core instruments
+
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sadco-io/sad-go-logger/logger"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.uber.org/zap"
+)
+
+type suppressMetricsKey struct{}
+
+// durationMillis converts d to milliseconds for duration histograms, keeping the
+// fraction so sub-millisecond durations are not all recorded as zero
+func durationMillis(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// SuppressMetrics returns a context in which metric recording calls are no-ops,
+// e.g. for a high-volume endpoint that should not produce metrics
+func SuppressMetrics(ctx context.Context) context.Context {
+	return context.WithValue(ctx, suppressMetricsKey{}, true)
+}
+
+// metricsSuppressed reports whether metrics are suppressed for ctx
+func metricsSuppressed(ctx context.Context) bool {
+	suppressed, _ := ctx.Value(suppressMetricsKey{}).(bool)
+	return suppressed
+}
+
+// RecordDelta records the increase of a cumulative value observed from an external
+// source as a counter increment. The last observed value is tracked per key; the
+// first observation and any decrease (a counter reset) only set a new baseline.
+func (o *OpenTelemetry) RecordDelta(ctx context.Context, key, name string, currentCumulative float64, attributes ...attribute.KeyValue) {
+	if !o.metricsEnabled || metricsSuppressed(ctx) {
+		return
+	}
+
+	o.deltaMu.Lock()
+	if o.lastCumulative == nil {
+		o.lastCumulative = make(map[string]float64)
+	}
+	last, seen := o.lastCumulative[key]
+	o.lastCumulative[key] = currentCumulative
+	o.deltaMu.Unlock()
+
+	if !seen || currentCumulative < last {
+		return
+	}
+	if delta := currentCumulative - last; delta > 0 {
+		o.IncrementCounter(ctx, name, delta, attributes...)
+	}
+}
+
+// RecordPercentage records a ratio as a gauge with unit "1". Values in [0,1] are
+// recorded as is and values in (1,100] are treated as percentages and divided by
+// 100. Out-of-range values are clamped, logging a warning once per metric name.
+func (o *OpenTelemetry) RecordPercentage(ctx context.Context, name string, value float64, attributes ...attribute.KeyValue) {
+	if !o.metricsEnabled || metricsSuppressed(ctx) {
+		return
+	}
+
+	ratio := value
+	switch {
+	case value >= 0 && value <= 1:
+	case value > 1 && value <= 100:
+		ratio = value / 100
+	default:
+		if _, warned := o.percentageWarned.LoadOrStore(name, struct{}{}); !warned {
+			logger.Log.Warn("Percentage metric value out of range, clamping",
+				zap.String("name", name),
+				zap.Float64("value", value))
+		}
+		ratio = 0
+		if value > 100 {
+			ratio = 1
+		}
+	}
+
+	if keep, err := o.checkInstrumentKind(name, InstrumentKindGauge); !keep {
+		if err != nil {
+			logger.Log.Error("Dropping metric value", zap.Error(err))
+		}
+		return
+	}
+	attrs, keep := o.metricAttributes(name, attributes)
+	if !keep {
+		return
+	}
+	instrument, err := cachedInstrument(o, InstrumentKindGauge, name, "1", func() (metric.Float64Gauge, error) {
+		return o.meter.Float64Gauge(name, metric.WithUnit("1"))
+	})
+	if err != nil {
+		logger.Log.Error("Failed to create gauge instrument", zap.Error(err))
+		return
+	}
+	instrument.Record(ctx, ratio, metric.WithAttributes(attrs...))
+}
+
+// percentilesKey is the attribute carrying the percentiles a backend should compute
+// for a distribution
+const percentilesKey = attribute.Key("percentiles")
+
+// RecordDistribution records value to the histogram name, tagged with a percentiles
+// attribute listing the percentiles to compute for backends that support them per
+// metric, such as Datadog distributions. Other backends see a regular histogram.
+func (o *OpenTelemetry) RecordDistribution(ctx context.Context, name string, value float64, percentiles []float64, attributes ...attribute.KeyValue) {
+	if len(percentiles) > 0 {
+		attributes = append(attributes[:len(attributes):len(attributes)], percentilesKey.Float64Slice(percentiles))
+	}
+	o.RecordHistogram(ctx, name, value, attributes...)
+}
+
+// RegisterObservableGauge registers a gauge whose value is read from cb on every
+// metric collection, for values such as queue depths that are cheaper to sample
+// than to record on every change. Use RecordGauge to record values synchronously.
+func (o *OpenTelemetry) RegisterObservableGauge(name string, cb func() float64) error {
+	if !o.metricsEnabled {
+		return nil
+	}
+	_, err := o.meter.Float64ObservableGauge(name, metric.WithFloat64Callback(
+		func(_ context.Context, observer metric.Float64Observer) error {
+			observer.Observe(cb())
+			return nil
+		}))
+	if err != nil {
+		return fmt.Errorf("failed to register observable gauge: %w", err)
+	}
+	return nil
+}
+
+// intervalCount accumulates the values of an interval count per attribute set
+// between two collections
+type intervalCount struct {
+	mu     sync.Mutex
+	values map[attribute.Distinct]*accumulatedValue
+}
+
+// accumulatedValue is the value accumulated for one attribute set
+type accumulatedValue struct {
+	attrs attribute.Set
+	value float64
+}
+
+// add adds n to the value of attrs
+func (c *intervalCount) add(attrs attribute.Set, n float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	v, ok := c.values[attrs.Equivalent()]
+	if !ok {
+		v = &accumulatedValue{attrs: attrs}
+		c.values[attrs.Equivalent()] = v
+	}
+	v.value += n
+}
+
+// observe reports the accumulated values and resets them to zero
+func (c *intervalCount) observe(_ context.Context, observer metric.Float64Observer) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, v := range c.values {
+		observer.Observe(v.value, metric.WithAttributeSet(v.attrs))
+		v.value = 0
+	}
+	return nil
+}
+
+// RecordIntervalCount adds n to a gauge reporting the total added since the previous
+// metric collection, e.g. for "events since last export" values. The total is reset
+// to zero when it is collected, so attribute sets without additions report 0. With
+// several metric readers, such as WithPrometheusText, a collection by any of them
+// resets the total.
+func (o *OpenTelemetry) RecordIntervalCount(ctx context.Context, name string, n float64, attributes ...attribute.KeyValue) {
+	if !o.metricsEnabled || metricsSuppressed(ctx) {
+		return
+	}
+	if keep, err := o.checkInstrumentKind(name, InstrumentKindGauge); !keep {
+		if err != nil {
+			logger.Log.Error("Dropping metric value", zap.Error(err))
+		}
+		return
+	}
+	attrs, keep := o.metricAttributes(name, attributes)
+	if !keep {
+		return
+	}
+
+	o.intervalCountsMu.Lock()
+	count, ok := o.intervalCounts[name]
+	if !ok {
+		count = &intervalCount{values: make(map[attribute.Distinct]*accumulatedValue)}
+		if _, err := o.meter.Float64ObservableGauge(name, metric.WithFloat64Callback(count.observe)); err != nil {
+			o.intervalCountsMu.Unlock()
+			logger.Log.Error("Failed to create interval count instrument", zap.Error(err))
+			return
+		}
+		if o.intervalCounts == nil {
+			o.intervalCounts = make(map[string]*intervalCount)
+		}
+		o.intervalCounts[name] = count
+	}
+	o.intervalCountsMu.Unlock()
+
+	count.add(attribute.NewSet(attrs...), n)
+}
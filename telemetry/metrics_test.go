@@ -0,0 +1,270 @@
+package telemetry
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+func TestRecordDeltaIncrementsAndResets(t *testing.T) {
+	tt := newTestTelemetry(t)
+	ctx := context.Background()
+	total := func() float64 {
+		t.Helper()
+		points := sumPoints(t, tt.metric(t, "upstream.requests"))
+		if len(points) != 1 {
+			t.Fatalf("got %d data points, want 1", len(points))
+		}
+		return points[0].Value
+	}
+
+	tt.RecordDelta(ctx, "upstream", "upstream.requests", 100)
+	if _, ok := findMetric(tt.collect(t), "upstream.requests"); ok {
+		t.Fatal("first observation recorded a value, want it to only set the baseline")
+	}
+
+	tt.RecordDelta(ctx, "upstream", "upstream.requests", 150)
+	tt.RecordDelta(ctx, "upstream", "upstream.requests", 175)
+	if got := total(); got != 75 {
+		t.Fatalf("after increments total = %v, want 75", got)
+	}
+
+	// The source restarted: 10 is the new baseline, not a negative delta
+	tt.RecordDelta(ctx, "upstream", "upstream.requests", 10)
+	if got := total(); got != 75 {
+		t.Fatalf("after reset total = %v, want 75", got)
+	}
+	tt.RecordDelta(ctx, "upstream", "upstream.requests", 30)
+	if got := total(); got != 95 {
+		t.Fatalf("after increment past the reset total = %v, want 95", got)
+	}
+}
+
+func TestRecordPercentageNormalizesToRatio(t *testing.T) {
+	tests := []struct {
+		name  string
+		value float64
+		want  float64
+		warn  bool
+	}{
+		{"ratio", 0.25, 0.25, false},
+		{"ratio upper bound", 1, 1, false},
+		{"percentage", 42, 0.42, false},
+		{"percentage upper bound", 100, 1, false},
+		{"zero", 0, 0, false},
+		{"above range", 250, 1, true},
+		{"below range", -3, 0, true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			logs := observeLogs(t)
+			tt := newTestTelemetry(t)
+
+			tt.RecordPercentage(context.Background(), "cache.hit_rate", tc.value)
+			tt.RecordPercentage(context.Background(), "cache.hit_rate", tc.value)
+
+			m := tt.metric(t, "cache.hit_rate")
+			if m.Unit != "1" {
+				t.Errorf("unit = %q, want 1", m.Unit)
+			}
+			points := gaugePoints(t, m)
+			if len(points) != 1 || points[0].Value != tc.want {
+				t.Fatalf("cache.hit_rate points = %+v, want %v", points, tc.want)
+			}
+			want := 0
+			if tc.warn {
+				want = 1
+			}
+			if warnings := logs.FilterMessage("Percentage metric value out of range, clamping").Len(); warnings != want {
+				t.Errorf("logged %d out-of-range warnings, want %d", warnings, want)
+			}
+		})
+	}
+}
+
+func TestSuppressMetricsDropsRecordings(t *testing.T) {
+	tt := newTestTelemetry(t)
+	suppressed := SuppressMetrics(context.Background())
+	ctx := context.Background()
+
+	for _, c := range []context.Context{suppressed, ctx} {
+		tt.RecordMetric(c, "metric.recorded", 1)
+		tt.IncrementCounter(c, "counter.incremented", 1)
+		tt.RecordGauge(c, "gauge.recorded", 5)
+		tt.RecordHistogram(c, "histogram.recorded", 2)
+	}
+
+	if points := sumPoints(t, tt.metric(t, "metric.recorded")); len(points) != 1 || points[0].Value != 1 {
+		t.Errorf("metric.recorded = %+v, want only the unsuppressed recording", points)
+	}
+	if points := sumPoints(t, tt.metric(t, "counter.incremented")); len(points) != 1 || points[0].Value != 1 {
+		t.Errorf("counter.incremented = %+v, want only the unsuppressed increment", points)
+	}
+	if points := gaugePoints(t, tt.metric(t, "gauge.recorded")); len(points) != 1 || points[0].Value != 5 {
+		t.Errorf("gauge.recorded = %+v, want the unsuppressed value", points)
+	}
+	if points := histogramPoints(t, tt.metric(t, "histogram.recorded")); len(points) != 1 || points[0].Count != 1 {
+		t.Errorf("histogram.recorded = %+v, want only the unsuppressed recording", points)
+	}
+}
+
+func TestSuppressMetricsOnlyDropsMetrics(t *testing.T) {
+	tt := newTestTelemetry(t)
+	ctx := SuppressMetrics(context.Background())
+
+	tt.IncrementCounter(ctx, "jobs", 1)
+	_, span := tt.StartSpan(ctx, "job")
+	tt.EndSpan(span)
+
+	if _, ok := findMetric(tt.collect(t), "jobs"); ok {
+		t.Error("jobs recorded under a suppressed context")
+	}
+	tt.endedSpan(t, "job")
+}
+
+func TestRecordGaugeKeepsLatestValuePerAttributeSet(t *testing.T) {
+	tt := newTestTelemetry(t)
+	ctx := context.Background()
+
+	tt.RecordGauge(ctx, "pool.connections", 3, attribute.String("pool", "primary"))
+	tt.RecordGauge(ctx, "pool.connections", 7, attribute.String("pool", "primary"))
+	tt.RecordGauge(ctx, "pool.connections", 2, attribute.String("pool", "replica"))
+
+	got := make(map[string]float64)
+	for _, p := range gaugePoints(t, tt.metric(t, "pool.connections")) {
+		pool, _ := p.Attributes.Value("pool")
+		got[pool.AsString()] = p.Value
+	}
+	if len(got) != 2 || got["primary"] != 7 || got["replica"] != 2 {
+		t.Errorf("pool.connections = %v, want the latest value of each pool", got)
+	}
+}
+
+func TestRegisterObservableGaugeReadsCallbackOnCollection(t *testing.T) {
+	tt := newTestTelemetry(t)
+	depth := 4.0
+	if err := tt.RegisterObservableGauge("queue.depth", func() float64 { return depth }); err != nil {
+		t.Fatalf("RegisterObservableGauge() error = %v", err)
+	}
+
+	for _, want := range []float64{4, 9} {
+		depth = want
+		points := gaugePoints(t, tt.metric(t, "queue.depth"))
+		if len(points) != 1 || points[0].Value != want {
+			t.Errorf("queue.depth = %+v, want a single point with %v", points, want)
+		}
+	}
+}
+
+func TestRecordDistributionTagsPercentiles(t *testing.T) {
+	tt := newTestTelemetry(t)
+	ctx := context.Background()
+	attrs := make([]attribute.KeyValue, 1, 4)
+	attrs[0] = attribute.String("region", "eu")
+
+	tt.RecordDistribution(ctx, "checkout.latency", 120, []float64{0.5, 0.99}, attrs...)
+	tt.RecordDistribution(ctx, "checkout.latency", 80, []float64{0.5, 0.99}, attrs...)
+	tt.RecordDistribution(ctx, "checkout.latency", 40, nil, attrs...)
+	if len(attrs) != 1 || attrs[:2][1].Key != "" {
+		t.Error("RecordDistribution appended the percentiles into the caller's attributes")
+	}
+
+	points := histogramPoints(t, tt.metric(t, "checkout.latency"))
+	if len(points) != 2 {
+		t.Fatalf("got %d data points, want one with and one without percentiles", len(points))
+	}
+	for _, p := range points {
+		percentiles, tagged := p.Attributes.Value(percentilesKey)
+		region, _ := p.Attributes.Value("region")
+		if region.AsString() != "eu" {
+			t.Errorf("data point region = %q, want eu", region.AsString())
+		}
+		switch {
+		case tagged && (p.Count != 2 || p.Sum != 200):
+			t.Errorf("tagged point count, sum = %d, %v, want 2, 200", p.Count, p.Sum)
+		case tagged && len(percentiles.AsFloat64Slice()) != 2:
+			t.Errorf("percentiles = %v, want [0.5 0.99]", percentiles.AsFloat64Slice())
+		case !tagged && (p.Count != 1 || p.Sum != 40):
+			t.Errorf("untagged point count, sum = %d, %v, want 1, 40", p.Count, p.Sum)
+		}
+	}
+}
+
+func TestRecordMetricERecordsCounter(t *testing.T) {
+	tt := newTestTelemetry(t)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if err := tt.RecordMetricE(ctx, "orders.placed", 2, attribute.String("region", "eu")); err != nil {
+			t.Fatalf("RecordMetricE() error = %v", err)
+		}
+	}
+	points := sumPoints(t, tt.metric(t, "orders.placed"))
+	if len(points) != 1 || points[0].Value != 4 {
+		t.Errorf("orders.placed = %+v, want a single series at 4", points)
+	}
+}
+
+func TestRecordMetricEReturnsInstrumentCreationError(t *testing.T) {
+	logs := observeLogs(t)
+	tt := newTestTelemetry(t)
+	ctx := context.Background()
+
+	// Instrument names must start with a letter, so the SDK rejects the counter
+	err := tt.RecordMetricE(ctx, "1st.orders", 1)
+	if err == nil || !strings.Contains(err.Error(), "failed to create metric instrument") {
+		t.Fatalf("RecordMetricE() error = %v, want the instrument creation failure", err)
+	}
+	if _, ok := findMetric(tt.collect(t), "1st.orders"); ok {
+		t.Error("1st.orders recorded despite the creation failure")
+	}
+
+	tt.RecordMetric(ctx, "1st.orders", 1)
+	if n := logs.FilterMessage("Dropping metric value").Len(); n != 1 {
+		t.Errorf("RecordMetric logged %d dropped values, want 1", n)
+	}
+}
+
+func TestRecordMetricEWithoutMetricsReturnsNil(t *testing.T) {
+	tt := newTestTelemetry(t, WithMetricsEnabled(false))
+	if err := tt.RecordMetricE(context.Background(), "1st.orders", 1); err != nil {
+		t.Errorf("RecordMetricE() error = %v with metrics disabled, want nil", err)
+	}
+	if err := (NoopTelemetry{}).RecordMetricE(context.Background(), "orders.placed", 1); err != nil {
+		t.Errorf("NoopTelemetry.RecordMetricE() error = %v, want nil", err)
+	}
+}
+
+// intervalCounts returns the values of an interval count by its region attribute
+func intervalCounts(t *testing.T, tt *testTelemetry, name string) map[string]float64 {
+	t.Helper()
+	values := make(map[string]float64)
+	for _, p := range gaugePoints(t, tt.metric(t, name)) {
+		values[attrMap(p.Attributes.ToSlice())["region"]] = p.Value
+	}
+	return values
+}
+
+func TestRecordIntervalCountResetsAfterCollection(t *testing.T) {
+	tt := newTestTelemetry(t)
+	ctx := context.Background()
+	eu, us := attribute.String("region", "eu"), attribute.String("region", "us")
+
+	tt.RecordIntervalCount(ctx, "events.since_export", 3, eu)
+	tt.RecordIntervalCount(ctx, "events.since_export", 4, eu)
+	tt.RecordIntervalCount(ctx, "events.since_export", 1, us)
+	if got := intervalCounts(t, tt, "events.since_export"); got["eu"] != 7 || got["us"] != 1 {
+		t.Fatalf("first collection = %v, want eu=7 and us=1", got)
+	}
+
+	if got := intervalCounts(t, tt, "events.since_export"); got["eu"] != 0 || got["us"] != 0 {
+		t.Errorf("collection without additions = %v, want both reset to 0", got)
+	}
+
+	tt.RecordIntervalCount(ctx, "events.since_export", 2, us)
+	if got := intervalCounts(t, tt, "events.since_export"); got["eu"] != 0 || got["us"] != 2 {
+		t.Errorf("third collection = %v, want eu=0 and us=2", got)
+	}
+}
@@ -0,0 +1,102 @@
+// middleware.go - net/http middleware creating a server span per request
+
+package telemetry
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// HTTPMiddleware wraps next so that each request runs in a server span continuing
+// the incoming trace context, named after the method and, when next is an
+// *http.ServeMux, the matched route pattern. The status code written by next is set
+// on the span, 5xx responses are recorded as errors, and the request duration is
+// recorded in the http.server.duration histogram in milliseconds.
+func (o *OpenTelemetry) HTTPMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !o.traceEnabled && !o.metricsEnabled {
+			next.ServeHTTP(w, r)
+			return
+		}
+		start := time.Now()
+		ctx := o.ExtractHTTPContext(r.Context(), r.Header)
+		ctx = o.ContextWithHeaderSamplingPriority(ctx, r.Header)
+
+		name := r.Method
+		attrs := []attribute.KeyValue{semconv.HTTPMethodKey.String(r.Method)}
+		if route := serveMuxRoute(next, r); route != "" {
+			name += " " + route
+			attrs = append(attrs, semconv.HTTPRouteKey.String(route))
+		}
+
+		var span trace.Span
+		if o.traceEnabled {
+			startAttrs := append(attrs[:len(attrs):len(attrs)], semconv.HTTPTargetKey.String(r.URL.RequestURI()))
+			ctx, span = o.tracer.Start(ctx, name,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(sanitizeAttributes(startAttrs)...))
+			defer o.EndSpan(span)
+		}
+
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r.WithContext(ctx))
+
+		attrs = append(attrs, semconv.HTTPStatusCodeKey.Int(recorder.status))
+		if span != nil {
+			span.SetAttributes(semconv.HTTPStatusCodeKey.Int(recorder.status))
+			if recorder.status >= http.StatusInternalServerError {
+				o.RecordError(ctx, fmt.Errorf("HTTP %d %s", recorder.status, http.StatusText(recorder.status)))
+			}
+		}
+		o.RecordHistogram(ctx, "http.server.duration", durationMillis(time.Since(start)), attrs...)
+	})
+}
+
+// serveMuxRoute returns the path of the pattern that mux, if next is one, routes r
+// to, without the method and host parts of the pattern
+func serveMuxRoute(next http.Handler, r *http.Request) string {
+	mux, ok := next.(*http.ServeMux)
+	if !ok {
+		return ""
+	}
+	_, pattern := mux.Handler(r)
+	if i := strings.IndexByte(pattern, ' '); i >= 0 {
+		pattern = strings.TrimSpace(pattern[i+1:])
+	}
+	if i := strings.IndexByte(pattern, '/'); i > 0 {
+		pattern = pattern[i:]
+	}
+	return pattern
+}
+
+// statusRecorder is a ResponseWriter remembering the status code written through it
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+// WriteHeader records the status code and writes it
+func (w *statusRecorder) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.status, w.wroteHeader = status, true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Write writes the body, implicitly with status 200 if no status was written
+func (w *statusRecorder) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap returns the wrapped ResponseWriter, for http.ResponseController
+func (w *statusRecorder) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
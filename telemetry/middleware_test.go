@@ -0,0 +1,144 @@
+package telemetry
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestHTTPMiddlewareSamplingPriorityHeader(t *testing.T) {
+	tests := []struct {
+		name    string
+		sampler sdktrace.Sampler
+		opts    []Option
+		header  string
+		value   string
+		want    int
+	}{
+		{"high priority overrides never sample", sdktrace.NeverSample(), nil, DefaultSamplingPriorityHeader, "high", 1},
+		{"positive integer is high priority", sdktrace.NeverSample(), nil, DefaultSamplingPriorityHeader, "1", 1},
+		{"no header uses the sampler", sdktrace.NeverSample(), nil, "", "", 0},
+		{"low priority drops new traces", sdktrace.AlwaysSample(), nil, DefaultSamplingPriorityHeader, "0", 0},
+		{"configured header name", sdktrace.NeverSample(), []Option{WithSamplingPriorityHeader("X-Debug-Trace")}, "X-Debug-Trace", "high", 1},
+		{"default header ignored when renamed", sdktrace.NeverSample(), []Option{WithSamplingPriorityHeader("X-Debug-Trace")}, DefaultSamplingPriorityHeader, "high", 0},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tt := newTestTelemetry(t, append([]Option{WithSampler(tc.sampler)}, tc.opts...)...)
+			handler := tt.HTTPMiddleware(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+
+			req := httptest.NewRequest(http.MethodGet, "/checkout", nil)
+			if tc.header != "" {
+				req.Header.Set(tc.header, tc.value)
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			spans := tt.endedSpans("GET")
+			if len(spans) != tc.want {
+				t.Fatalf("got %d sampled spans, want %d", len(spans), tc.want)
+			}
+			if tc.want == 1 && !spans[0].SpanContext().IsSampled() {
+				t.Error("span is recorded but not sampled")
+			}
+		})
+	}
+}
+
+func TestHTTPMiddlewareRouteSamplingProportions(t *testing.T) {
+	tt := newTestTelemetry(t,
+		WithSampler(sdktrace.AlwaysSample()),
+		WithRouteSampling(map[string]float64{"/healthz": 0, "/search": 0.5}))
+	mux := http.NewServeMux()
+	noop := func(http.ResponseWriter, *http.Request) {}
+	mux.HandleFunc("/healthz", noop)
+	mux.HandleFunc("/search", noop)
+	mux.HandleFunc("/orders/{id}", noop)
+	handler := tt.HTTPMiddleware(mux)
+
+	const requests = 1000
+	for _, path := range []string{"/healthz", "/search", "/orders/42"} {
+		for i := 0; i < requests; i++ {
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+		}
+	}
+
+	if n := len(tt.endedSpans("GET /healthz")); n != 0 {
+		t.Errorf("sampled %d /healthz requests, want none", n)
+	}
+	if n := len(tt.endedSpans("GET /search")); n < 400 || n > 600 {
+		t.Errorf("sampled %d of %d /search requests, want about half", n, requests)
+	}
+	if n := len(tt.endedSpans("GET /orders/{id}")); n != requests {
+		t.Errorf("sampled %d of %d /orders/{id} requests, want all with the base sampler", n, requests)
+	}
+}
+
+func TestHTTPMiddlewareServerSpanOverHTTP(t *testing.T) {
+	tt := newTestTelemetry(t)
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /orders/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if !trace.SpanFromContext(r.Context()).IsRecording() {
+			t.Error("handler context has no recording span")
+		}
+		if r.PathValue("id") == "missing" {
+			http.Error(w, "lookup failed", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	})
+	server := httptest.NewServer(tt.HTTPMiddleware(mux))
+	defer server.Close()
+
+	const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	for _, path := range []string{"/orders/42", "/orders/missing"} {
+		req, err := http.NewRequest(http.MethodGet, server.URL+path, nil)
+		if err != nil {
+			t.Fatalf("NewRequest() error = %v", err)
+		}
+		req.Header.Set("traceparent", traceparent)
+		resp, err := server.Client().Do(req)
+		if err != nil {
+			t.Fatalf("GET %s error = %v", path, err)
+		}
+		resp.Body.Close()
+	}
+
+	spans := tt.endedSpans("GET /orders/{id}")
+	if len(spans) != 2 {
+		t.Fatalf("got %d server spans named after the route, want 2", len(spans))
+	}
+	for i, want := range []struct {
+		status string
+		target string
+		failed bool
+	}{{"201", "/orders/42", false}, {"500", "/orders/missing", true}} {
+		span := spans[i]
+		attrs := attrMap(span.Attributes())
+		if span.SpanKind() != trace.SpanKindServer {
+			t.Errorf("%s span kind = %v, want server", want.target, span.SpanKind())
+		}
+		if attrs["http.status_code"] != want.status || attrs["http.target"] != want.target || attrs["http.route"] != "/orders/{id}" {
+			t.Errorf("%s span attributes = %v", want.target, attrs)
+		}
+		if span.Parent().TraceID().String() != "4bf92f3577b34da6a3ce929d0e0e4736" || !span.Parent().IsRemote() {
+			t.Errorf("%s span parent = %v, want the incoming traceparent", want.target, span.Parent())
+		}
+		if failed := span.Status().Code == codes.Error; failed != want.failed {
+			t.Errorf("%s span status = %v, want error %v", want.target, span.Status(), want.failed)
+		}
+	}
+
+	points := histogramPoints(t, tt.metric(t, "http.server.duration"))
+	if len(points) != 2 {
+		t.Fatalf("got %d http.server.duration points, want one per status code", len(points))
+	}
+	for _, p := range points {
+		if attrs := attrMap(p.Attributes.ToSlice()); attrs["http.route"] != "/orders/{id}" || p.Count != 1 {
+			t.Errorf("http.server.duration point %v with count %d, want one request of the route", attrs, p.Count)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+// noop.go - No-op implementation of the Telemetry interface
+
+package telemetry
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// NoopTelemetry implements the Telemetry interface without recording anything. It
+// is used when telemetry is disabled and in tests; spans it returns are non-nil
+// no-op spans, so callers never need nil checks.
+type NoopTelemetry struct{}
+
+var _ Telemetry = NoopTelemetry{}
+
+// NewNoop returns a Telemetry that discards everything
+func NewNoop() Telemetry {
+	return NoopTelemetry{}
+}
+
+// StartSpan returns ctx and a no-op span
+func (NoopTelemetry) StartSpan(ctx context.Context, _ string) (context.Context, trace.Span) {
+	return ctx, noop.Span{}
+}
+
+// StartSpanWithKind returns ctx and a no-op span
+func (NoopTelemetry) StartSpanWithKind(ctx context.Context, _ string, _ trace.SpanKind, _ ...attribute.KeyValue) (context.Context, trace.Span) {
+	return ctx, noop.Span{}
+}
+
+// StartSpanWithLinks returns ctx and a no-op span, ignoring links
+func (NoopTelemetry) StartSpanWithLinks(ctx context.Context, _ string, _ []trace.Link) (context.Context, trace.Span) {
+	return ctx, noop.Span{}
+}
+
+// EndSpan does nothing
+func (NoopTelemetry) EndSpan(trace.Span) {}
+
+// WithSpan runs fn with ctx
+func (NoopTelemetry) WithSpan(ctx context.Context, _ string, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+// AddEvent does nothing
+func (NoopTelemetry) AddEvent(trace.Span, string, ...attribute.KeyValue) {}
+
+// RecordMetric does nothing
+func (NoopTelemetry) RecordMetric(context.Context, string, float64, ...attribute.KeyValue) {}
+
+// RecordMetricE does nothing and returns nil
+func (NoopTelemetry) RecordMetricE(context.Context, string, float64, ...attribute.KeyValue) error {
+	return nil
+}
+
+// PostEvent does nothing
+func (NoopTelemetry) PostEvent(context.Context, string, map[string]string) {}
+
+// PostTrace does nothing
+func (NoopTelemetry) PostTrace(context.Context, string, string, map[string]string) {}
+
+// RecordError does nothing
+func (NoopTelemetry) RecordError(context.Context, error, ...attribute.KeyValue) {}
+
+// IncrementCounter does nothing
+func (NoopTelemetry) IncrementCounter(context.Context, string, float64, ...attribute.KeyValue) {}
+
+// RecordGauge does nothing
+func (NoopTelemetry) RecordGauge(context.Context, string, float64, ...attribute.KeyValue) {}
+
+// LogInfo does nothing
+func (NoopTelemetry) LogInfo(context.Context, string, ...attribute.KeyValue) {}
+
+// LogWarning does nothing
+func (NoopTelemetry) LogWarning(context.Context, string, ...attribute.KeyValue) {}
+
+// LogError does nothing
+func (NoopTelemetry) LogError(context.Context, string, error, ...attribute.KeyValue) {}
+
+// TrackRequest does nothing
+func (NoopTelemetry) TrackRequest(context.Context, string, string, time.Duration, int) {}
+
+// TrackDependency does nothing
+func (NoopTelemetry) TrackDependency(context.Context, string, string, time.Duration, bool) {}
+
+// TrackAvailability does nothing
+func (NoopTelemetry) TrackAvailability(context.Context, string, time.Duration, bool) {}
+
+// SetUser does nothing
+func (NoopTelemetry) SetUser(context.Context, string) {}
+
+// SetSession does nothing
+func (NoopTelemetry) SetSession(context.Context, string) {}
+
+// TracingEnabled reports false
+func (NoopTelemetry) TracingEnabled() bool { return false }
+
+// MetricsEnabled reports false
+func (NoopTelemetry) MetricsEnabled() bool { return false }
+
+// Shutdown does nothing
+func (NoopTelemetry) Shutdown(context.Context) error { return nil }
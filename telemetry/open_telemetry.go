@@ -4,22 +4,28 @@ package telemetry
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/sadco-io/sad-go-logger/logger"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
-	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
-	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	otelprom "go.opentelemetry.io/otel/exporters/prometheus"
 	"go.opentelemetry.io/otel/metric"
 	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
-	"go.opentelemetry.io/otel/sdk/resource"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
 	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
 	"go.uber.org/zap"
+	"google.golang.org/grpc"
 )
 
 // OpenTelemetry implements the Telemetry interface
@@ -30,10 +36,89 @@ type OpenTelemetry struct {
 	meterProvider  *sdkmetric.MeterProvider
 	traceEnabled   bool
 	metricsEnabled bool
+	config         otelConfig
+
+	prometheusReader   *sdkmetric.ManualReader
+	prometheusRegistry *prometheus.Registry
+	summaries          *summaryProducer
+	recentErrors       *errorRing
+
+	deltaMu          sync.Mutex
+	lastCumulative   map[string]float64
+	percentageWarned sync.Map
+
+	intervalCountsMu sync.Mutex
+	intervalCounts   map[string]*intervalCount
+
+	dailyKPIsMu sync.Mutex
+	dailyKPIs   map[string]*dailyKPI
+
+	dbPoolsMu sync.Mutex
+	dbPools   []metric.Registration
+
+	instrumentsMu            sync.RWMutex
+	instrumentKinds          map[string]InstrumentKind
+	instrumentKindCallers    map[string]string
+	instruments              map[string]*instrumentEntry
+	instrumentKindWarned     sync.Map
+	instrumentConflictWarned sync.Map
+
+	batchCounts sync.Map
+
+	invalidTraceparentLogged atomic.Int64
+	exportErrorsPending      atomic.Int64
+	exportErrorsHandling     atomic.Bool
+
+	asyncMu        sync.Mutex
+	asyncRecorders []*AsyncRecorder
+
+	startedAt  time.Time
+	readyOnce  sync.Once
+	runSummary *SummaryCollector
+
+	subscribers       *subscriberProcessor
+	liveSpans         *liveSpanProcessor
+	sampler           *switchableSampler
+	boosts            *boostSampler
+	samplingWatchMu   sync.Mutex
+	samplingWatchStop chan struct{}
+
+	exporterConns     []*exporterConnection
+	stopExporterWatch context.CancelFunc
 }
 
-// NewOpenTelemetry creates and initializes a new OpenTelemetry instance
-func NewOpenTelemetry(serviceName, traceEndpoint, metricEndpoint string, traceEnabled, metricsEnabled bool) (*OpenTelemetry, error) {
+var _ Telemetry = (*OpenTelemetry)(nil)
+
+// NewOpenTelemetry creates and initializes a new OpenTelemetry instance. The
+// positional arguments are applied before opts, which take precedence over them.
+func NewOpenTelemetry(serviceName, traceEndpoint, metricEndpoint string, traceEnabled, metricsEnabled bool, opts ...Option) (*OpenTelemetry, error) {
+	positional := []Option{
+		WithTraceEndpoint(traceEndpoint),
+		WithMetricEndpoint(metricEndpoint),
+		WithTracingEnabled(traceEnabled),
+		WithMetricsEnabled(metricsEnabled),
+	}
+	return newOpenTelemetry(serviceName, append(positional, opts...))
+}
+
+// NewOpenTelemetryWithOptions creates and initializes a new OpenTelemetry instance
+// configured entirely through options. Tracing and metrics are disabled unless
+// enabled with WithTracingEnabled and WithMetricsEnabled.
+func NewOpenTelemetryWithOptions(opts ...Option) (*OpenTelemetry, error) {
+	return newOpenTelemetry("", opts)
+}
+
+// newOpenTelemetry creates an OpenTelemetry instance from the service name argument
+// of NewOpenTelemetry, if any, and the options
+func newOpenTelemetry(serviceName string, opts []Option) (*OpenTelemetry, error) {
+	start := time.Now()
+	cfg := defaultOtelConfig()
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	traceEndpoint, metricEndpoint := cfg.traceEndpoint, cfg.metricEndpoint
+	traceEnabled, metricsEnabled := cfg.traceEnabled, cfg.metricsEnabled
+
 	logger.Log.Info("OpenTelemetry Configuration ",
 		zap.String("serviceName", serviceName),
 		zap.String("traceEndpoint", traceEndpoint),
@@ -42,75 +127,210 @@ func NewOpenTelemetry(serviceName, traceEndpoint, metricEndpoint string, traceEn
 		zap.Bool("metricsEnabled", metricsEnabled))
 	ctx := context.Background()
 
-	res, err := resource.New(ctx,
-		resource.WithAttributes(
-			semconv.ServiceNameKey.String(serviceName),
-		),
-	)
+	if err := validateExporterProtocol(cfg.exporterProtocol); err != nil {
+		return nil, err
+	}
+	tlsConfig, err := loadTLSConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	cfg.tlsConfig = tlsConfig
+
+	res, serviceName, err := newResource(ctx, serviceName, cfg)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create resource: %w", err)
 	}
+	traceRes, traceServiceName, err := signalResource(res, serviceName, cfg.traceServiceName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace resource: %w", err)
+	}
+	metricRes, metricServiceName, err := signalResource(res, serviceName, cfg.metricServiceName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create metric resource: %w", err)
+	}
+	if cfg.runID == "" {
+		cfg.runID = newRunID()
+	}
+	if cfg.pushgatewayURL != "" && cfg.pushgatewayJob == "" {
+		cfg.pushgatewayJob = serviceName
+	}
+	if cfg.maxConcurrentExports > 0 {
+		cfg.exportSlots = make(chan struct{}, cfg.maxConcurrentExports)
+	}
 
 	var tp *sdktrace.TracerProvider
 	var mp *sdkmetric.MeterProvider
+	var promReader *sdkmetric.ManualReader
+	var summaries *summaryProducer
+	var switchable *switchableSampler
+	var boosts *boostSampler
+	batchProcessor := &batchSpanProcessor{}
+	durationProcessor := &spanDurationProcessor{}
+	var subscribers *subscriberProcessor
+	var liveSpans *liveSpanProcessor
+	var exporterConns []*exporterConnection
 
 	if traceEnabled {
-		traceExporter, err := otlptracegrpc.New(ctx,
-			otlptracegrpc.WithEndpoint(traceEndpoint),
-			otlptracegrpc.WithInsecure(),
-		)
-		if err != nil {
-			logger.Log.Error("Failed to create OpenTelemetry exporter",
-				zap.Error(err),
-				zap.String("endpoint", metricEndpoint),
-				zap.Bool("metricsEnabled", metricsEnabled))
-			return nil, fmt.Errorf("failed to create metric exporter: %w", err)
+		sampler, strictParent := samplerChain(cfg.sampler, cfg.routeSampling)
+		switchable = newSwitchableSampler(sampler)
+		boosts = newBoostSampler(switchable)
+		tpOpts := []sdktrace.TracerProviderOption{
+			sdktrace.WithResource(traceRes),
+			sdktrace.WithSampler(newPrioritySampler(boosts, strictParent)),
+			sdktrace.WithSpanProcessor(batchProcessor),
+		}
+		subscribers = newSubscriberProcessor()
+		tpOpts = append(tpOpts, sdktrace.WithSpanProcessor(subscribers))
+		for _, processor := range cfg.spanProcessors {
+			tpOpts = append(tpOpts, sdktrace.WithSpanProcessor(processor))
+		}
+		if cfg.spanLeakThreshold > 0 {
+			liveSpans = newLiveSpanProcessor(cfg.spanLeakThreshold)
+			tpOpts = append(tpOpts, sdktrace.WithSpanProcessor(liveSpans))
+		}
+		if cfg.spanDurationMetrics && metricsEnabled {
+			tpOpts = append(tpOpts, sdktrace.WithSpanProcessor(durationProcessor))
+		}
+		var exportProcessors []sdktrace.SpanProcessor
+		for _, endpoint := range splitEndpoints(traceEndpoint) {
+			var conn *grpc.ClientConn
+			if cfg.exporterConnMetrics && cfg.exporterProtocol == ProtocolGRPC {
+				exporterConn, err := newExporterConnection(endpoint, cfg)
+				if err != nil {
+					return nil, fmt.Errorf("failed to create trace exporter connection: %w", err)
+				}
+				exporterConns = append(exporterConns, exporterConn)
+				conn = exporterConn.conn
+			}
+			spanExporter, err := newSpanExporter(ctx, endpoint, conn, cfg)
+			if err != nil {
+				logger.Log.Error("Failed to create OpenTelemetry exporter",
+					zap.Error(err),
+					zap.String("endpoint", endpoint),
+					zap.Bool("traceEnabled", traceEnabled))
+				return nil, fmt.Errorf("failed to create trace exporter: %w", err)
+			}
+			exportProcessors = append(exportProcessors, newExportProcessor(spanExporter, cfg))
+		}
+		if cfg.tenantRouter != nil {
+			exportProcessors = []sdktrace.SpanProcessor{newTenantRoutingProcessor(cfg.tenantRouter, exportProcessors, cfg)}
+		}
+		for _, processor := range exportProcessors {
+			tpOpts = append(tpOpts, sdktrace.WithSpanProcessor(processor))
+		}
+		if cfg.traceCapturePath != "" {
+			captureExporter, err := newTraceCaptureExporter(ctx, cfg.traceCapturePath)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create trace capture exporter: %w", err)
+			}
+			tpOpts = append(tpOpts, sdktrace.WithSpanProcessor(newExportProcessor(captureExporter, cfg)))
 		}
 
-		tp = sdktrace.NewTracerProvider(
-			sdktrace.WithBatcher(traceExporter),
-			sdktrace.WithResource(res),
-		)
+		tp = sdktrace.NewTracerProvider(tpOpts...)
 		otel.SetTracerProvider(tp)
 	}
 
 	if metricsEnabled {
-		metricExporter, err := otlpmetricgrpc.New(ctx,
-			otlpmetricgrpc.WithEndpoint(metricEndpoint),
-			otlpmetricgrpc.WithInsecure(),
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to create metric exporter: %w", err)
+		summaries = newSummaryProducer(metricServiceName)
+		mpOpts := []sdkmetric.Option{sdkmetric.WithResource(metricRes)}
+		if !cfg.prometheusOnly {
+			metricExporter, err := newMetricExporter(ctx, metricEndpoint, cfg)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create metric exporter: %w", err)
+			}
+			mpOpts = append(mpOpts, sdkmetric.WithReader(sdkmetric.NewPeriodicReader(metricExporter, sdkmetric.WithProducer(summaries))))
 		}
-
-		mp = sdkmetric.NewMeterProvider(
-			sdkmetric.WithReader(sdkmetric.NewPeriodicReader(metricExporter)),
-			sdkmetric.WithResource(res),
-		)
+		if cfg.prometheusText {
+			promReader = sdkmetric.NewManualReader(sdkmetric.WithProducer(summaries))
+			mpOpts = append(mpOpts, sdkmetric.WithReader(promReader))
+		}
+		if cfg.pushgatewayURL != "" && cfg.prometheusRegistry == nil {
+			cfg.prometheusRegistry = prometheus.NewRegistry()
+		}
+		if cfg.prometheusRegistry != nil {
+			promExporter, err := otelprom.New(otelprom.WithRegisterer(cfg.prometheusRegistry))
+			if err != nil {
+				return nil, fmt.Errorf("failed to create prometheus exporter: %w", err)
+			}
+			mpOpts = append(mpOpts, sdkmetric.WithReader(promExporter))
+		}
+		for _, reader := range cfg.metricReaders {
+			mpOpts = append(mpOpts, sdkmetric.WithReader(reader))
+		}
+		mp = sdkmetric.NewMeterProvider(mpOpts...)
 		otel.SetMeterProvider(mp)
 	}
+	otel.SetTextMapPropagator(textMapPropagator)
 
-	tracer := otel.Tracer(serviceName)
-	meter := otel.Meter(serviceName)
+	tracer := otel.Tracer(traceServiceName, trace.WithSchemaURL(cfg.schemaURL))
+	meter := otel.Meter(metricServiceName, metric.WithSchemaURL(cfg.schemaURL))
 
-	return &OpenTelemetry{
+	o := &OpenTelemetry{
 		tracer:         tracer,
 		meter:          meter,
 		traceProvider:  tp,
 		meterProvider:  mp,
 		traceEnabled:   traceEnabled,
 		metricsEnabled: metricsEnabled,
-	}, nil
+		config:         cfg,
+
+		prometheusReader:      promReader,
+		prometheusRegistry:    cfg.prometheusRegistry,
+		summaries:             summaries,
+		sampler:               switchable,
+		boosts:                boosts,
+		runSummary:            NewSummaryCollector(),
+		subscribers:           subscribers,
+		liveSpans:             liveSpans,
+		exporterConns:         exporterConns,
+		instrumentKinds:       make(map[string]InstrumentKind, len(cfg.instrumentKinds)),
+		instrumentKindCallers: make(map[string]string, len(cfg.instrumentKinds)),
+		instruments:           make(map[string]*instrumentEntry),
+
+		startedAt: start,
+	}
+	for name, kind := range cfg.instrumentKinds {
+		o.instrumentKinds[name] = kind
+		o.instrumentKindCallers[name] = "WithInstrumentKinds"
+	}
+	if cfg.recentErrors > 0 {
+		o.recentErrors = newErrorRing(cfg.recentErrors)
+	}
+	batchProcessor.o = o
+	durationProcessor.o = o
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(o.handleOTelError))
+	if err := o.watchExporterConnections(ctx); err != nil {
+		return nil, fmt.Errorf("failed to register exporter connection gauge: %w", err)
+	}
+
+	if cfg.startupSelfTest {
+		o.runSelfTest(ctx)
+	}
+
+	return o, nil
 }
 
-// StartSpan starts a new span and returns the context and the span
+// StartSpan starts a new span and returns the context and the span. With tracing
+// disabled the span is a no-op span, so callers need no nil checks.
 func (o *OpenTelemetry) StartSpan(ctx context.Context, name string) (context.Context, trace.Span) {
 	if !o.traceEnabled {
-		return ctx, nil
+		return ctx, noop.Span{}
 	}
 	return o.tracer.Start(ctx, name)
 }
 
+// StartSpanWithKind starts a new span of the given kind with the given attributes,
+// which are available to samplers, and returns the context and the span. With
+// tracing disabled the span is a no-op span, so callers need no nil checks.
+func (o *OpenTelemetry) StartSpanWithKind(ctx context.Context, name string, kind trace.SpanKind, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	if !o.traceEnabled {
+		return ctx, noop.Span{}
+	}
+	return o.tracer.Start(ctx, name,
+		trace.WithSpanKind(kind),
+		trace.WithAttributes(sanitizeAttributes(attributes)...))
+}
+
 // EndSpan ends the given span
 func (o *OpenTelemetry) EndSpan(span trace.Span) {
 	if span != nil {
@@ -118,15 +338,31 @@ func (o *OpenTelemetry) EndSpan(span trace.Span) {
 	}
 }
 
+// WithSpan starts a span named name, runs fn with the span's context and ends the
+// span when fn returns. An error returned by fn is recorded on the span and sets its
+// error status, except context cancellations, which are recorded as such.
+func (o *OpenTelemetry) WithSpan(ctx context.Context, name string, fn func(ctx context.Context) error) error {
+	ctx, span := o.StartSpan(ctx, name)
+	defer o.EndSpan(span)
+
+	err := fn(ctx)
+	if err != nil && !o.recordCancellation(ctx, err) {
+		o.RecordError(ctx, err)
+	}
+	return err
+}
+
 // AddEvent adds an event to the given span
 func (o *OpenTelemetry) AddEvent(span trace.Span, name string, attributes ...attribute.KeyValue) {
 	if span != nil {
+		attributes = sanitizeAttributes(attributes)
 		span.AddEvent(name, trace.WithAttributes(attributes...))
+		o.logEvent(span, name, attributes)
 	}
 }
 
-// PostEvent posts an event with the given name and properties
-func (o *OpenTelemetry) PostEvent(name string, properties map[string]string) {
+// PostEvent posts an event with the given name and properties on the span in ctx
+func (o *OpenTelemetry) PostEvent(ctx context.Context, name string, properties map[string]string) {
 	if !o.traceEnabled {
 		return
 	}
@@ -134,69 +370,203 @@ func (o *OpenTelemetry) PostEvent(name string, properties map[string]string) {
 	for k, v := range properties {
 		attrs = append(attrs, attribute.String(k, v))
 	}
-	span := trace.SpanFromContext(context.Background())
+	attrs = sanitizeAttributes(attrs)
+	span := trace.SpanFromContext(ctx)
 	if span.IsRecording() {
 		span.AddEvent(name, trace.WithAttributes(attrs...))
 	}
+	o.logEvent(span, name, attrs)
+}
+
+// logEvent emits a span event as a log line correlated with the span, when
+// WithEventsAsLogs is enabled
+func (o *OpenTelemetry) logEvent(span trace.Span, name string, attributes []attribute.KeyValue) {
+	if !o.config.eventsAsLogs {
+		return
+	}
+	sc := span.SpanContext()
+	logger.Log.Info("Span event",
+		zap.String("event", name),
+		zap.String("trace_id", sc.TraceID().String()),
+		zap.String("span_id", sc.SpanID().String()),
+		zap.Any("attributes", attributes))
 }
 
 // RecordMetric records a metric with the given name and value
 func (o *OpenTelemetry) RecordMetric(ctx context.Context, name string, value float64, attributes ...attribute.KeyValue) {
-	if !o.metricsEnabled {
-		return
+	if err := o.RecordMetricE(ctx, name, value, attributes...); err != nil {
+		logger.Log.Error("Dropping metric value", zap.Error(err))
+	}
+}
+
+// RecordMetricE records a metric like RecordMetric, returning the error if the
+// counter cannot be created or, with WithStrictInstruments, if the name is
+// registered with another instrument kind
+func (o *OpenTelemetry) RecordMetricE(ctx context.Context, name string, value float64, attributes ...attribute.KeyValue) error {
+	if !o.metricsEnabled || metricsSuppressed(ctx) {
+		return nil
+	}
+	if keep, err := o.checkInstrumentKind(name, InstrumentKindCounter); !keep {
+		return err
 	}
-	instrument, err := o.meter.Float64Counter(name)
+	attrs, keep := o.metricAttributes(name, attributes)
+	if !keep {
+		return nil
+	}
+	instrument, err := cachedInstrument(o, InstrumentKindCounter, name, "", func() (metric.Float64Counter, error) {
+		return o.meter.Float64Counter(name)
+	})
 	if err != nil {
-		logger.Log.Error("Failed to create metric instrument", zap.Error(err))
-		return
+		return fmt.Errorf("failed to create metric instrument: %w", err)
 	}
-	instrument.Add(ctx, value, metric.WithAttributes(attributes...))
+	instrument.Add(ctx, value, metric.WithAttributes(attrs...))
+	return nil
 }
 
-// RecordError records an error as a span event and sets the span status
+// RecordError records an error as a span event and sets the span status. The full
+// message is kept in the event's exception.message attribute, while the status
+// description is normalized to a single line and truncated.
 func (o *OpenTelemetry) RecordError(ctx context.Context, err error, attributes ...attribute.KeyValue) {
+	if err == nil {
+		return
+	}
+	o.rememberError(ctx, err)
+	if !o.traceEnabled {
+		return
+	}
+	span := trace.SpanFromContext(ctx)
+	if span.IsRecording() {
+		span.RecordError(err, trace.WithAttributes(sanitizeAttributes(attributes)...))
+		span.SetStatus(codes.Error, normalizeStatusDescription(err.Error(), o.config.statusDescriptionLimit))
+	}
+}
+
+// RecordException records err as an exception span event following the OpenTelemetry
+// exception conventions (type, message, stacktrace and escaped) and sets the span status
+func (o *OpenTelemetry) RecordException(ctx context.Context, err error, escaped bool, attributes ...attribute.KeyValue) {
 	if !o.traceEnabled || err == nil {
 		return
 	}
 	span := trace.SpanFromContext(ctx)
+	if !span.IsRecording() {
+		return
+	}
+	attrs := make([]attribute.KeyValue, 0, len(attributes)+4)
+	attrs = append(attrs,
+		semconv.ExceptionTypeKey.String(fmt.Sprintf("%T", err)),
+		semconv.ExceptionMessageKey.String(err.Error()),
+		semconv.ExceptionStacktraceKey.String(string(debug.Stack())),
+		semconv.ExceptionEscapedKey.Bool(escaped),
+	)
+	attrs = append(attrs, attributes...)
+	span.AddEvent(semconv.ExceptionEventName, trace.WithAttributes(sanitizeAttributes(attrs)...))
+	span.SetStatus(codes.Error, normalizeStatusDescription(err.Error(), o.config.statusDescriptionLimit))
+}
+
+// RecordErrorUnlessExpected records err like RecordError unless it matches one of
+// the errors registered with WithExpectedErrors, in which case it is added as a
+// plain span event without marking the span as failed
+func (o *OpenTelemetry) RecordErrorUnlessExpected(ctx context.Context, err error, attributes ...attribute.KeyValue) {
+	if err == nil {
+		return
+	}
+	if !o.isExpectedError(err) {
+		o.RecordError(ctx, err, attributes...)
+		return
+	}
+	if !o.traceEnabled {
+		return
+	}
+	span := trace.SpanFromContext(ctx)
 	if span.IsRecording() {
-		span.RecordError(err, trace.WithAttributes(attributes...))
-		span.SetStatus(codes.Error, err.Error())
+		attrs := make([]attribute.KeyValue, 0, len(attributes)+1)
+		attrs = append(attrs, attribute.String("error.message", err.Error()))
+		attrs = append(attrs, attributes...)
+		span.AddEvent("expected_error", trace.WithAttributes(sanitizeAttributes(attrs)...))
 	}
 }
 
+// isExpectedError reports whether err matches one of the configured expected errors
+func (o *OpenTelemetry) isExpectedError(err error) bool {
+	for _, expected := range o.config.expectedErrors {
+		if errors.Is(err, expected) {
+			return true
+		}
+	}
+	return false
+}
+
 // IncrementCounter increments a counter metric
 func (o *OpenTelemetry) IncrementCounter(ctx context.Context, name string, increment float64, attributes ...attribute.KeyValue) {
 	o.RecordMetric(ctx, name, increment, attributes...)
 }
 
 func (o *OpenTelemetry) RecordGauge(ctx context.Context, name string, value float64, attributes ...attribute.KeyValue) {
-	if !o.metricsEnabled {
+	if !o.metricsEnabled || metricsSuppressed(ctx) {
+		return
+	}
+	if keep, err := o.checkInstrumentKind(name, InstrumentKindGauge); !keep {
+		if err != nil {
+			logger.Log.Error("Dropping metric value", zap.Error(err))
+		}
 		return
 	}
 
-	instrument, err := o.meter.Float64Gauge(name)
+	attrs, keep := o.metricAttributes(name, attributes)
+	if !keep {
+		return
+	}
+	instrument, err := cachedInstrument(o, InstrumentKindGauge, name, "", func() (metric.Float64Gauge, error) {
+		return o.meter.Float64Gauge(name)
+	})
 	if err != nil {
 		logger.Log.Error("Failed to create gauge instrument", zap.Error(err))
 		return
 	}
 
-	instrument.Record(ctx, value, metric.WithAttributes(attributes...))
+	instrument.Record(ctx, value, metric.WithAttributes(attrs...))
+}
+
+// RecordHistogram records a value in a histogram metric
+func (o *OpenTelemetry) RecordHistogram(ctx context.Context, name string, value float64, attributes ...attribute.KeyValue) {
+	if !o.metricsEnabled || metricsSuppressed(ctx) {
+		return
+	}
+	if keep, err := o.checkInstrumentKind(name, InstrumentKindHistogram); !keep {
+		if err != nil {
+			logger.Log.Error("Dropping metric value", zap.Error(err))
+		}
+		return
+	}
+
+	attrs, keep := o.metricAttributes(name, attributes)
+	if !keep {
+		return
+	}
+	instrument, err := cachedInstrument(o, InstrumentKindHistogram, name, "", func() (metric.Float64Histogram, error) {
+		return o.meter.Float64Histogram(name)
+	})
+	if err != nil {
+		logger.Log.Error("Failed to create histogram instrument", zap.Error(err))
+		return
+	}
+
+	instrument.Record(ctx, value, metric.WithAttributes(attrs...))
 }
 
 // LogInfo logs an info message
 func (o *OpenTelemetry) LogInfo(ctx context.Context, message string, attributes ...attribute.KeyValue) {
-	logger.Log.Info(message, zap.Any("attributes", attributes))
+	logger.Log.Log(SeverityInformation.zapLevel(), message, zap.Any("attributes", attributes))
 }
 
 // LogWarning logs a warning message
 func (o *OpenTelemetry) LogWarning(ctx context.Context, message string, attributes ...attribute.KeyValue) {
-	logger.Log.Warn(message, zap.Any("attributes", attributes))
+	logger.Log.Log(SeverityWarning.zapLevel(), message, zap.Any("attributes", attributes))
 }
 
 // LogError logs an error message
 func (o *OpenTelemetry) LogError(ctx context.Context, message string, err error, attributes ...attribute.KeyValue) {
-	logger.Log.Error(message, zap.Error(err), zap.Any("attributes", attributes))
+	logger.Log.Log(SeverityError.zapLevel(), message, zap.Error(err), zap.Any("attributes", attributes))
 	o.RecordError(ctx, err, attributes...)
 }
 
@@ -208,29 +578,45 @@ func (o *OpenTelemetry) TrackRequest(ctx context.Context, method, url string, du
 	ctx, span := o.StartSpan(ctx, "HTTP Request")
 	defer o.EndSpan(span)
 
-	span.SetAttributes(
+	span.SetAttributes(sanitizeAttributes([]attribute.KeyValue{
 		semconv.HTTPMethodKey.String(method),
 		semconv.HTTPURLKey.String(url),
 		semconv.HTTPStatusCodeKey.Int(statusCode),
-	)
+	})...)
 	span.SetStatus(httpStatusToSpanStatus(statusCode))
 	span.SetAttributes(attribute.Int64("http.duration_ms", duration.Milliseconds()))
 }
 
-// TrackDependency records a dependency call as a span
+// TrackDependency records a dependency call as a span, and as the dependency.calls
+// counter and dependency.duration histogram when metrics are enabled
 func (o *OpenTelemetry) TrackDependency(ctx context.Context, dependencyType, target string, duration time.Duration, success bool) {
+	o.trackDependency(ctx, dependencyType, target, duration, success)
+}
+
+// trackDependency records a dependency call, adding spanAttributes to the span only
+func (o *OpenTelemetry) trackDependency(ctx context.Context, dependencyType, target string, duration time.Duration, success bool, spanAttributes ...attribute.KeyValue) {
+	attributes := []attribute.KeyValue{
+		attribute.String("dependency.type", dependencyType),
+		attribute.String("dependency.target", target),
+		attribute.Bool("dependency.success", success),
+	}
+
+	if o.metricsEnabled && o.config.dependencyMetrics {
+		o.IncrementCounter(ctx, "dependency.calls", 1, attributes...)
+		o.RecordHistogram(ctx, "dependency.duration", durationMillis(duration), attributes...)
+	}
+
 	if !o.traceEnabled {
 		return
 	}
 	ctx, span := o.StartSpan(ctx, "Dependency Call")
 	defer o.EndSpan(span)
 
-	span.SetAttributes(
-		attribute.String("dependency.type", dependencyType),
-		attribute.String("dependency.target", target),
-		attribute.Int64("dependency.duration_ms", duration.Milliseconds()),
-		attribute.Bool("dependency.success", success),
-	)
+	span.SetAttributes(sanitizeAttributes(attributes)...)
+	span.SetAttributes(attribute.Int64("dependency.duration_ms", duration.Milliseconds()))
+	if len(spanAttributes) > 0 {
+		span.SetAttributes(sanitizeAttributes(spanAttributes)...)
+	}
 	if !success {
 		span.SetStatus(codes.Error, "Dependency call failed")
 	}
@@ -256,7 +642,7 @@ func (o *OpenTelemetry) SetUser(ctx context.Context, id string) {
 	}
 	span := trace.SpanFromContext(ctx)
 	if span.IsRecording() {
-		span.SetAttributes(attribute.String("user.id", id))
+		span.SetAttributes(attribute.String("user.id", sanitizeString(id)))
 	}
 }
 
@@ -267,33 +653,141 @@ func (o *OpenTelemetry) SetSession(ctx context.Context, id string) {
 	}
 	span := trace.SpanFromContext(ctx)
 	if span.IsRecording() {
-		span.SetAttributes(attribute.String("session.id", id))
+		span.SetAttributes(attribute.String("session.id", sanitizeString(id)))
+	}
+}
+
+// SetAttributes sets the given attributes on the span in the current context
+// with a single call to the span
+func (o *OpenTelemetry) SetAttributes(ctx context.Context, attributes ...attribute.KeyValue) {
+	if !o.traceEnabled || len(attributes) == 0 {
+		return
+	}
+	span := trace.SpanFromContext(ctx)
+	if span.IsRecording() {
+		span.SetAttributes(sanitizeAttributes(attributes)...)
+	}
+}
+
+// SetAttributesMap sets the given properties as string attributes on the span
+// in the current context with a single call to the span
+func (o *OpenTelemetry) SetAttributesMap(ctx context.Context, properties map[string]string) {
+	attrs := make([]attribute.KeyValue, 0, len(properties))
+	for k, v := range properties {
+		attrs = append(attrs, attribute.String(k, v))
+	}
+	o.SetAttributes(ctx, attrs...)
+}
+
+// RecordFeatureFlag records a feature flag evaluation as a feature_flag event on
+// the span in the current context, following the OpenTelemetry feature flag conventions
+func (o *OpenTelemetry) RecordFeatureFlag(ctx context.Context, flagKey, variant string) {
+	if !o.traceEnabled {
+		return
+	}
+	span := trace.SpanFromContext(ctx)
+	if span.IsRecording() {
+		span.AddEvent("feature_flag", trace.WithAttributes(sanitizeAttributes([]attribute.KeyValue{
+			semconv.FeatureFlagKey(flagKey),
+			semconv.FeatureFlagVariant(variant),
+		})...))
 	}
 }
 
-// PostTrace posts a trace message with the given severity and properties
-func (o *OpenTelemetry) PostTrace(message string, severity string, properties map[string]string) {
+// PostTrace posts a trace message with the given severity and properties on the
+// span in ctx. The severity is parsed with ParseSeverityLevel, defaulting to
+// SeverityInformation; an unrecognised severity is kept in the severity_text
+// attribute.
+func (o *OpenTelemetry) PostTrace(ctx context.Context, message string, severity string, properties map[string]string) {
 	if !o.traceEnabled {
 		return
 	}
-	attrs := make([]attribute.KeyValue, 0, len(properties)+2)
-	attrs = append(attrs, attribute.String("message", message), attribute.String("severity", severity))
+	level, ok := ParseSeverityLevel(severity)
+	attrs := make([]attribute.KeyValue, 0, len(properties)+4)
+	attrs = append(attrs,
+		attribute.String("message", message),
+		attribute.String("severity", level.String()),
+		attribute.Int("severity_number", level.ToOTelSeverity()))
+	if !ok {
+		attrs = append(attrs, attribute.String("severity_text", severity))
+	}
 	for k, v := range properties {
 		attrs = append(attrs, attribute.String(k, v))
 	}
-	span := trace.SpanFromContext(context.Background())
+	span := trace.SpanFromContext(ctx)
 	if span.IsRecording() {
-		span.AddEvent("Trace", trace.WithAttributes(attrs...))
+		span.AddEvent("Trace", trace.WithAttributes(sanitizeAttributes(attrs)...))
 	}
-	logger.Log.Info("Trace recorded", zap.Any("attributes", attrs))
+	logger.Log.Log(level.zapLevel(), "Trace recorded", zap.Any("attributes", attrs))
 }
 
-// Shutdown shuts down the telemetry provider
+// forceFlush exports the buffered spans and metrics of the providers, without
+// emitting the run summary or pushing to the Pushgateway like Flush
+func (o *OpenTelemetry) forceFlush(ctx context.Context) error {
+	var err error
+	if o.traceProvider != nil {
+		err = o.traceProvider.ForceFlush(ctx)
+	}
+	if o.meterProvider != nil {
+		if mErr := o.meterProvider.ForceFlush(ctx); mErr != nil {
+			err = fmt.Errorf("trace: %v, metric: %w", err, mErr)
+		}
+	}
+	return err
+}
+
+// TracingEnabled reports whether tracing is enabled
+func (o *OpenTelemetry) TracingEnabled() bool {
+	return o.traceEnabled
+}
+
+// MetricsEnabled reports whether metrics are enabled
+func (o *OpenTelemetry) MetricsEnabled() bool {
+	return o.metricsEnabled
+}
+
+// Flush forces the export of all buffered spans and metrics
+func (o *OpenTelemetry) Flush(ctx context.Context) error {
+	o.emitRunSummary(ctx)
+
+	err := o.forceFlush(ctx)
+	if o.config.pushgatewayURL != "" && o.prometheusRegistry != nil {
+		if pErr := o.pushMetrics(ctx); pErr != nil {
+			err = fmt.Errorf("flush: %v, pushgateway: %w", err, pErr)
+		}
+	}
+	return err
+}
+
+// Shutdown shuts down the telemetry provider. If ctx has no deadline, the
+// configured shutdown timeout is applied so a hung exporter cannot block forever.
 func (o *OpenTelemetry) Shutdown(ctx context.Context) error {
+	if _, ok := ctx.Deadline(); !ok && o.config.shutdownTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, o.config.shutdownTimeout)
+		defer cancel()
+	}
+
+	o.stopSamplingWatch()
+	o.emitRunSummary(ctx)
+	if aErr := o.closeAsyncRecorders(ctx); aErr != nil {
+		logger.Log.Warn("Failed to drain async metric recorders", zap.Error(aErr))
+	}
+	if dErr := o.unregisterDBPools(); dErr != nil {
+		logger.Log.Warn("Failed to unregister database pool metrics", zap.Error(dErr))
+	}
+
+	if o.config.pushgatewayURL != "" && o.prometheusRegistry != nil {
+		if pErr := o.pushMetrics(ctx); pErr != nil {
+			logger.Log.Error("Failed to push metrics to pushgateway", zap.Error(pErr))
+		}
+	}
+
 	var err error
 	if o.traceProvider != nil {
 		err = o.traceProvider.Shutdown(ctx)
 	}
+	o.closeExporterConnections()
 	if o.meterProvider != nil {
 		if mErr := o.meterProvider.Shutdown(ctx); mErr != nil {
 			err = fmt.Errorf("trace: %v, metric: %w", err, mErr)
@@ -302,6 +796,20 @@ func (o *OpenTelemetry) Shutdown(ctx context.Context) error {
 	return err
 }
 
+// normalizeStatusDescription collapses whitespace (including newlines) into single
+// spaces and truncates the result to limit characters
+func normalizeStatusDescription(description string, limit int) string {
+	description = strings.Join(strings.Fields(sanitizeString(description)), " ")
+	if limit <= 0 {
+		return description
+	}
+	runes := []rune(description)
+	if len(runes) <= limit {
+		return description
+	}
+	return string(runes[:limit]) + "..."
+}
+
 // httpStatusToSpanStatus converts an HTTP status code to a span status
 func httpStatusToSpanStatus(statusCode int) (codes.Code, string) {
 	if statusCode >= 400 {
@@ -0,0 +1,762 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sadco-io/sad-go-logger/logger"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	collectormetrics "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	collectortrace "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+	"google.golang.org/grpc"
+)
+
+// testTelemetry is an OpenTelemetry instance whose spans are kept by an in-memory
+// span recorder and whose metrics are collected on demand by a manual reader
+type testTelemetry struct {
+	*OpenTelemetry
+	spans  *tracetest.SpanRecorder
+	reader *sdkmetric.ManualReader
+}
+
+// newTestTelemetry returns a testTelemetry with tracing and metrics enabled and the
+// OTLP exporters pointed at an unused local port. opts are applied last.
+func newTestTelemetry(t *testing.T, opts ...Option) *testTelemetry {
+	t.Helper()
+	tt := &testTelemetry{
+		spans:  tracetest.NewSpanRecorder(),
+		reader: sdkmetric.NewManualReader(),
+	}
+	base := []Option{
+		WithServiceName("telemetry-test"),
+		WithTraceEndpoint("127.0.0.1:1"),
+		WithMetricEndpoint("127.0.0.1:1"),
+		WithInsecure(),
+		WithTracingEnabled(true),
+		WithMetricsEnabled(true),
+		WithKubernetesDetector(false),
+		WithSpanProcessor(tt.spans),
+		WithMetricReader(tt.reader),
+	}
+	o, err := NewOpenTelemetryWithOptions(append(base, opts...)...)
+	if err != nil {
+		t.Fatalf("NewOpenTelemetryWithOptions() error = %v", err)
+	}
+	tt.OpenTelemetry = o
+	t.Cleanup(func() {
+		// Nothing listens on the OTLP endpoints, so do not wait for final exports
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_ = o.Shutdown(ctx)
+	})
+	return tt
+}
+
+// endedSpans returns the ended spans named name
+func (tt *testTelemetry) endedSpans(name string) []sdktrace.ReadOnlySpan {
+	var spans []sdktrace.ReadOnlySpan
+	for _, s := range tt.spans.Ended() {
+		if s.Name() == name {
+			spans = append(spans, s)
+		}
+	}
+	return spans
+}
+
+// endedSpan returns the only ended span named name
+func (tt *testTelemetry) endedSpan(t *testing.T, name string) sdktrace.ReadOnlySpan {
+	t.Helper()
+	spans := tt.endedSpans(name)
+	if len(spans) != 1 {
+		t.Fatalf("got %d ended spans named %q, want 1", len(spans), name)
+	}
+	return spans[0]
+}
+
+// collect collects the current metrics from the manual reader
+func (tt *testTelemetry) collect(t *testing.T) metricdata.ResourceMetrics {
+	t.Helper()
+	var rm metricdata.ResourceMetrics
+	if err := tt.reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+	return rm
+}
+
+// metric collects the current metrics and returns the one named name
+func (tt *testTelemetry) metric(t *testing.T, name string) metricdata.Metrics {
+	t.Helper()
+	m, ok := findMetric(tt.collect(t), name)
+	if !ok {
+		t.Fatalf("metric %q not collected", name)
+	}
+	return m
+}
+
+// findMetric returns the metric named name in rm
+func findMetric(rm metricdata.ResourceMetrics, name string) (metricdata.Metrics, bool) {
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name == name {
+				return m, true
+			}
+		}
+	}
+	return metricdata.Metrics{}, false
+}
+
+// sumPoints returns the data points of a float64 sum metric
+func sumPoints(t *testing.T, m metricdata.Metrics) []metricdata.DataPoint[float64] {
+	t.Helper()
+	sum, ok := m.Data.(metricdata.Sum[float64])
+	if !ok {
+		t.Fatalf("metric %q is a %T, want a float64 sum", m.Name, m.Data)
+	}
+	return sum.DataPoints
+}
+
+// histogramPoints returns the data points of a float64 histogram metric
+func histogramPoints(t *testing.T, m metricdata.Metrics) []metricdata.HistogramDataPoint[float64] {
+	t.Helper()
+	histogram, ok := m.Data.(metricdata.Histogram[float64])
+	if !ok {
+		t.Fatalf("metric %q is a %T, want a float64 histogram", m.Name, m.Data)
+	}
+	return histogram.DataPoints
+}
+
+// gaugePoints returns the data points of a float64 gauge metric
+func gaugePoints(t *testing.T, m metricdata.Metrics) []metricdata.DataPoint[float64] {
+	t.Helper()
+	gauge, ok := m.Data.(metricdata.Gauge[float64])
+	if !ok {
+		t.Fatalf("metric %q is a %T, want a float64 gauge", m.Name, m.Data)
+	}
+	return gauge.DataPoints
+}
+
+// attrMap returns the attributes as a map from key to emitted value
+func attrMap(attrs []attribute.KeyValue) map[string]string {
+	m := make(map[string]string, len(attrs))
+	for _, kv := range attrs {
+		m[string(kv.Key)] = kv.Value.Emit()
+	}
+	return m
+}
+
+// observeLogs replaces the logger for the rest of the test and returns the entries
+// it receives
+func observeLogs(t *testing.T) *observer.ObservedLogs {
+	t.Helper()
+	core, logs := observer.New(zap.DebugLevel)
+	previous := logger.Log
+	logger.Log = zap.New(core)
+	t.Cleanup(func() { logger.Log = previous })
+	return logs
+}
+
+// stubCollector is an in-process OTLP/gRPC collector accepting spans and metrics
+type stubCollector struct {
+	addr   string
+	server *grpc.Server
+
+	mu            sync.Mutex
+	spanNames     []string
+	metricExports int
+	metrics       map[string]*metricspb.Metric
+}
+
+// newStubCollector starts a stub collector on a free local port, stopped when the
+// test ends
+func newStubCollector(t *testing.T) *stubCollector {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	c := &stubCollector{
+		addr:    lis.Addr().String(),
+		server:  grpc.NewServer(),
+		metrics: make(map[string]*metricspb.Metric),
+	}
+	collectortrace.RegisterTraceServiceServer(c.server, stubTraceService{c: c})
+	collectormetrics.RegisterMetricsServiceServer(c.server, stubMetricsService{c: c})
+	go func() { _ = c.server.Serve(lis) }()
+	t.Cleanup(c.server.Stop)
+	return c
+}
+
+// receivedSpans returns the names of the spans received so far
+func (c *stubCollector) receivedSpans() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.spanNames...)
+}
+
+// receivedMetric returns the latest metric received named name, or nil
+func (c *stubCollector) receivedMetric(name string) *metricspb.Metric {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.metrics[name]
+}
+
+// receivedMetricExports returns the number of metric export requests received
+func (c *stubCollector) receivedMetricExports() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.metricExports
+}
+
+type stubTraceService struct {
+	collectortrace.UnimplementedTraceServiceServer
+	c *stubCollector
+}
+
+func (s stubTraceService) Export(_ context.Context, req *collectortrace.ExportTraceServiceRequest) (*collectortrace.ExportTraceServiceResponse, error) {
+	s.c.mu.Lock()
+	defer s.c.mu.Unlock()
+	for _, rs := range req.GetResourceSpans() {
+		for _, ss := range rs.GetScopeSpans() {
+			for _, span := range ss.GetSpans() {
+				s.c.spanNames = append(s.c.spanNames, span.GetName())
+			}
+		}
+	}
+	return &collectortrace.ExportTraceServiceResponse{}, nil
+}
+
+type stubMetricsService struct {
+	collectormetrics.UnimplementedMetricsServiceServer
+	c *stubCollector
+}
+
+func (s stubMetricsService) Export(_ context.Context, req *collectormetrics.ExportMetricsServiceRequest) (*collectormetrics.ExportMetricsServiceResponse, error) {
+	s.c.mu.Lock()
+	defer s.c.mu.Unlock()
+	s.c.metricExports++
+	for _, rm := range req.GetResourceMetrics() {
+		for _, sm := range rm.GetScopeMetrics() {
+			for _, m := range sm.GetMetrics() {
+				s.c.metrics[m.GetName()] = m
+			}
+		}
+	}
+	return &collectormetrics.ExportMetricsServiceResponse{}, nil
+}
+
+func TestTrackDependencyRecordsSpanCounterAndHistogram(t *testing.T) {
+	tt := newTestTelemetry(t)
+
+	tt.TrackDependency(context.Background(), "sql", "orders-db", 1500*time.Microsecond, false)
+
+	span := tt.endedSpan(t, "Dependency Call")
+	attrs := attrMap(span.Attributes())
+	for key, want := range map[string]string{
+		"dependency.type":    "sql",
+		"dependency.target":  "orders-db",
+		"dependency.success": "false",
+	} {
+		if attrs[key] != want {
+			t.Errorf("span attribute %s = %q, want %q", key, attrs[key], want)
+		}
+	}
+
+	calls := sumPoints(t, tt.metric(t, "dependency.calls"))
+	if len(calls) != 1 || calls[0].Value != 1 {
+		t.Fatalf("dependency.calls = %+v, want a single point with value 1", calls)
+	}
+	if v, _ := calls[0].Attributes.Value("dependency.target"); v.AsString() != "orders-db" {
+		t.Errorf("dependency.calls target = %q, want orders-db", v.AsString())
+	}
+
+	durations := histogramPoints(t, tt.metric(t, "dependency.duration"))
+	if len(durations) != 1 || durations[0].Count != 1 {
+		t.Fatalf("dependency.duration = %+v, want a single point with one value", durations)
+	}
+	if durations[0].Sum != 1.5 {
+		t.Errorf("dependency.duration sum = %v, want 1.5 (fractional milliseconds)", durations[0].Sum)
+	}
+}
+
+func TestTrackDependencyMetricsOptOut(t *testing.T) {
+	tt := newTestTelemetry(t, WithDependencyMetrics(false))
+
+	tt.TrackDependency(context.Background(), "http", "payments", time.Millisecond, true)
+
+	tt.endedSpan(t, "Dependency Call")
+	rm := tt.collect(t)
+	for _, name := range []string{"dependency.calls", "dependency.duration"} {
+		if _, ok := findMetric(rm, name); ok {
+			t.Errorf("metric %q recorded with dependency metrics disabled", name)
+		}
+	}
+}
+
+func TestRecordErrorTruncatesStatusDescription(t *testing.T) {
+	tt := newTestTelemetry(t, WithStatusDescriptionLimit(32))
+	message := "decode response:\n\t" + strings.Repeat("payload ", 20) + "\nend"
+
+	ctx, span := tt.StartSpan(context.Background(), "decode")
+	tt.RecordError(ctx, errors.New(message))
+	tt.EndSpan(span)
+
+	status := tt.endedSpan(t, "decode").Status()
+	if status.Code != codes.Error {
+		t.Fatalf("status code = %v, want Error", status.Code)
+	}
+	want := "decode response: payload payload..."
+	if status.Description != want {
+		t.Errorf("status description = %q, want %q", status.Description, want)
+	}
+	if strings.ContainsAny(status.Description, "\n\t") {
+		t.Errorf("status description %q is not a single line", status.Description)
+	}
+
+	events := tt.endedSpan(t, "decode").Events()
+	if len(events) != 1 {
+		t.Fatalf("got %d events, want the exception event", len(events))
+	}
+	if got := attrMap(events[0].Attributes)["exception.message"]; got != message {
+		t.Errorf("exception.message = %q, want the full message", got)
+	}
+}
+
+// blockingExporter is a span exporter whose exports block until release is closed
+type blockingExporter struct {
+	release chan struct{}
+}
+
+func (e blockingExporter) ExportSpans(context.Context, []sdktrace.ReadOnlySpan) error {
+	<-e.release
+	return nil
+}
+
+func (e blockingExporter) Shutdown(context.Context) error { return nil }
+
+func TestShutdownTimeoutBoundsBlockedExporter(t *testing.T) {
+	exporter := blockingExporter{release: make(chan struct{})}
+	defer close(exporter.release)
+	tt := newTestTelemetry(t,
+		WithMetricsEnabled(false),
+		WithSpanProcessor(sdktrace.NewBatchSpanProcessor(exporter)),
+		WithShutdownTimeout(100*time.Millisecond))
+
+	_, span := tt.StartSpan(context.Background(), "pending")
+	tt.EndSpan(span)
+
+	start := time.Now()
+	err := tt.Shutdown(context.Background())
+	elapsed := time.Since(start)
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Shutdown() error = %v, want the deadline to be exceeded", err)
+	}
+	if elapsed > time.Second {
+		t.Errorf("Shutdown() took %v with a 100ms timeout", elapsed)
+	}
+}
+
+func TestRecordErrorUnlessExpected(t *testing.T) {
+	errNotFound := errors.New("not found")
+	tt := newTestTelemetry(t, WithExpectedErrors(errNotFound))
+
+	ctx, span := tt.StartSpan(context.Background(), "lookup")
+	tt.RecordErrorUnlessExpected(ctx, fmt.Errorf("user 7: %w", errNotFound))
+	tt.EndSpan(span)
+	expected := tt.endedSpan(t, "lookup")
+	if expected.Status().Code == codes.Error {
+		t.Error("expected error set the error status")
+	}
+	if events := expected.Events(); len(events) != 1 || events[0].Name != "expected_error" {
+		t.Errorf("events = %+v, want a single expected_error event", events)
+	}
+
+	ctx, span = tt.StartSpan(context.Background(), "store")
+	tt.RecordErrorUnlessExpected(ctx, errors.New("disk full"))
+	tt.EndSpan(span)
+	unexpected := tt.endedSpan(t, "store")
+	if unexpected.Status().Code != codes.Error {
+		t.Error("unexpected error did not set the error status")
+	}
+	if events := unexpected.Events(); len(events) != 1 || events[0].Name != "exception" {
+		t.Errorf("events = %+v, want a single exception event", events)
+	}
+}
+
+func TestRecordErrorUnlessExpectedMatchesRecordErrorWithoutTracing(t *testing.T) {
+	errNotFound := errors.New("not found")
+	tt := newTestTelemetry(t,
+		WithTracingEnabled(false),
+		WithExpectedErrors(errNotFound),
+		WithRecentErrors(4))
+	ctx := context.Background()
+
+	tt.RecordErrorUnlessExpected(ctx, errNotFound)
+	tt.RecordErrorUnlessExpected(ctx, errors.New("disk full"))
+	tt.RecordError(ctx, errors.New("timeout"))
+
+	records := tt.RecentErrors()
+	if len(records) != 2 || records[0].Message != "timeout" || records[1].Message != "disk full" {
+		t.Errorf("RecentErrors() = %+v, want the two unexpected errors", records)
+	}
+}
+
+func TestSignalEnabledAccessors(t *testing.T) {
+	tests := []struct {
+		tracing, metrics bool
+	}{
+		{true, true},
+		{true, false},
+		{false, true},
+	}
+	for _, tc := range tests {
+		var tel Telemetry = newTestTelemetry(t, WithTracingEnabled(tc.tracing), WithMetricsEnabled(tc.metrics)).OpenTelemetry
+		if tel.TracingEnabled() != tc.tracing || tel.MetricsEnabled() != tc.metrics {
+			t.Errorf("configured tracing=%v metrics=%v, accessors report tracing=%v metrics=%v",
+				tc.tracing, tc.metrics, tel.TracingEnabled(), tel.MetricsEnabled())
+		}
+	}
+
+	noop := NewNoop()
+	if noop.TracingEnabled() || noop.MetricsEnabled() {
+		t.Error("NoopTelemetry reports an enabled signal")
+	}
+}
+
+func TestRecordExceptionFollowsConventions(t *testing.T) {
+	tt := newTestTelemetry(t)
+	_, err := os.Open(filepath.Join(t.TempDir(), "missing.json"))
+
+	ctx, span := tt.StartSpan(context.Background(), "load")
+	tt.RecordException(ctx, err, true, attribute.String("config.name", "missing.json"))
+	tt.EndSpan(span)
+
+	s := tt.endedSpan(t, "load")
+	if s.Status().Code != codes.Error {
+		t.Errorf("status = %v, want Error", s.Status().Code)
+	}
+	if len(s.Events()) != 1 || s.Events()[0].Name != "exception" {
+		t.Fatalf("events = %+v, want a single exception event", s.Events())
+	}
+	attrs := attrMap(s.Events()[0].Attributes)
+	if attrs["exception.type"] != "*fs.PathError" {
+		t.Errorf("exception.type = %q, want the concrete error type", attrs["exception.type"])
+	}
+	if attrs["exception.message"] != err.Error() {
+		t.Errorf("exception.message = %q, want %q", attrs["exception.message"], err.Error())
+	}
+	if !strings.Contains(attrs["exception.stacktrace"], "TestRecordExceptionFollowsConventions") {
+		t.Errorf("exception.stacktrace does not contain the caller:\n%s", attrs["exception.stacktrace"])
+	}
+	if attrs["exception.escaped"] != "true" {
+		t.Errorf("exception.escaped = %q, want true", attrs["exception.escaped"])
+	}
+	if attrs["config.name"] != "missing.json" {
+		t.Errorf("extra attribute config.name = %q, want missing.json", attrs["config.name"])
+	}
+}
+
+// countingSpan wraps a span, counting its SetAttributes calls
+type countingSpan struct {
+	trace.Span
+	setAttributesCalls int
+}
+
+func (s *countingSpan) SetAttributes(kv ...attribute.KeyValue) {
+	s.setAttributesCalls++
+	s.Span.SetAttributes(kv...)
+}
+
+func TestSetAttributesUsesSingleSpanCall(t *testing.T) {
+	tt := newTestTelemetry(t)
+	_, span := tt.StartSpan(context.Background(), "checkout")
+	counting := &countingSpan{Span: span}
+	ctx := trace.ContextWithSpan(context.Background(), counting)
+
+	tt.SetAttributes(ctx, attribute.String("cart.id", "c1"), attribute.Int("cart.items", 3), attribute.Bool("cart.gift", false))
+	if counting.setAttributesCalls != 1 {
+		t.Errorf("SetAttributes made %d span calls, want 1", counting.setAttributesCalls)
+	}
+	tt.SetAttributesMap(ctx, map[string]string{"user.tier": "gold", "user.region": "eu", "user.locale": "de"})
+	if counting.setAttributesCalls != 2 {
+		t.Errorf("SetAttributesMap made %d span calls, want 1", counting.setAttributesCalls-1)
+	}
+	tt.EndSpan(span)
+
+	attrs := attrMap(tt.endedSpan(t, "checkout").Attributes())
+	for key, want := range map[string]string{
+		"cart.id": "c1", "cart.items": "3", "cart.gift": "false",
+		"user.tier": "gold", "user.region": "eu", "user.locale": "de",
+	} {
+		if attrs[key] != want {
+			t.Errorf("attribute %s = %q, want %q", key, attrs[key], want)
+		}
+	}
+}
+
+func TestRecordFeatureFlagAddsEvent(t *testing.T) {
+	tt := newTestTelemetry(t)
+
+	ctx, span := tt.StartSpan(context.Background(), "checkout")
+	tt.RecordFeatureFlag(ctx, "new-checkout", "treatment")
+	tt.RecordFeatureFlag(ctx, "dark-mode", "off")
+	tt.EndSpan(span)
+
+	events := tt.endedSpan(t, "checkout").Events()
+	if len(events) != 2 {
+		t.Fatalf("got %d events, want 2: %+v", len(events), events)
+	}
+	for i, want := range []map[string]string{
+		{"feature_flag.key": "new-checkout", "feature_flag.variant": "treatment"},
+		{"feature_flag.key": "dark-mode", "feature_flag.variant": "off"},
+	} {
+		if events[i].Name != "feature_flag" {
+			t.Errorf("event %d name = %q, want feature_flag", i, events[i].Name)
+		}
+		attrs := attrMap(events[i].Attributes)
+		for key, value := range want {
+			if attrs[key] != value {
+				t.Errorf("event %d attribute %s = %q, want %q", i, key, attrs[key], value)
+			}
+		}
+	}
+}
+
+func TestEventsAsLogs(t *testing.T) {
+	for _, enabled := range []bool{true, false} {
+		t.Run(fmt.Sprintf("enabled=%v", enabled), func(t *testing.T) {
+			logs := observeLogs(t)
+			tt := newTestTelemetry(t, WithEventsAsLogs(enabled))
+
+			ctx, span := tt.StartSpan(context.Background(), "order")
+			tt.AddEvent(span, "order.validated", attribute.String("order.id", "42"))
+			tt.PostEvent(ctx, "order.shipped", map[string]string{"carrier": "dhl"})
+			tt.EndSpan(span)
+
+			ended := tt.endedSpan(t, "order")
+			if events := ended.Events(); len(events) != 2 || events[0].Name != "order.validated" || events[1].Name != "order.shipped" {
+				t.Errorf("span events = %+v, want both events", events)
+			}
+
+			entries := logs.FilterMessage("Span event").All()
+			if !enabled {
+				if len(entries) != 0 {
+					t.Errorf("logged %d events, want none with the option off", len(entries))
+				}
+				return
+			}
+			if len(entries) != 2 {
+				t.Fatalf("logged %d events, want 2", len(entries))
+			}
+			for i, want := range []string{"order.validated", "order.shipped"} {
+				fields := entries[i].ContextMap()
+				if fields["event"] != want {
+					t.Errorf("log %d event = %v, want %s", i, fields["event"], want)
+				}
+				if fields["trace_id"] != ended.SpanContext().TraceID().String() ||
+					fields["span_id"] != ended.SpanContext().SpanID().String() {
+					t.Errorf("log %d = %v, want the trace and span IDs of the span", i, fields)
+				}
+			}
+		})
+	}
+}
+
+func TestWithSpanEndsSpanAndRecordsError(t *testing.T) {
+	tt := newTestTelemetry(t)
+	var inner trace.SpanContext
+
+	err := tt.WithSpan(context.Background(), "load profile", func(ctx context.Context) error {
+		inner = trace.SpanContextFromContext(ctx)
+		_, child := tt.StartSpan(ctx, "query")
+		tt.EndSpan(child)
+		return errors.New("profile not found")
+	})
+	if err == nil || err.Error() != "profile not found" {
+		t.Fatalf("WithSpan() error = %v, want the error of fn", err)
+	}
+
+	span := tt.endedSpan(t, "load profile")
+	if span.SpanContext().SpanID() != inner.SpanID() {
+		t.Error("fn did not run in the context of the span")
+	}
+	if span.Status().Code != codes.Error || span.Status().Description != "profile not found" {
+		t.Errorf("span status = %+v, want the error of fn", span.Status())
+	}
+	if child := tt.endedSpan(t, "query"); child.Parent().SpanID() != span.SpanContext().SpanID() {
+		t.Error("span started in fn is not a child of the WithSpan span")
+	}
+
+	if err := tt.WithSpan(context.Background(), "save profile", func(context.Context) error { return nil }); err != nil {
+		t.Fatalf("WithSpan() error = %v", err)
+	}
+	if ok := tt.endedSpan(t, "save profile"); ok.Status().Code == codes.Error || len(ok.Events()) != 0 {
+		t.Errorf("successful span status = %+v with events %v, want no error", ok.Status(), ok.Events())
+	}
+}
+
+func TestWithSpanRunsFnWithoutTracing(t *testing.T) {
+	impls := map[string]Telemetry{
+		"OpenTelemetry": newTestTelemetry(t, WithTracingEnabled(false)).OpenTelemetry,
+		"Noop":          NewNoop(),
+	}
+	for name, impl := range impls {
+		ran := false
+		err := impl.WithSpan(context.Background(), "operation", func(context.Context) error {
+			ran = true
+			return errors.New("failed")
+		})
+		if !ran || err == nil {
+			t.Errorf("%s: WithSpan() ran fn = %v with error %v, want fn run and its error returned", name, ran, err)
+		}
+	}
+}
+
+func TestStartSpanWithKindSetsKind(t *testing.T) {
+	tt := newTestTelemetry(t)
+	ctx := context.Background()
+
+	for _, kind := range []trace.SpanKind{
+		trace.SpanKindInternal,
+		trace.SpanKindServer,
+		trace.SpanKindClient,
+		trace.SpanKindProducer,
+		trace.SpanKindConsumer,
+	} {
+		name := "operation " + kind.String()
+		_, span := tt.StartSpanWithKind(ctx, name, kind, attribute.String("peer.service", "billing"))
+		tt.EndSpan(span)
+
+		ended := tt.endedSpan(t, name)
+		if ended.SpanKind() != kind {
+			t.Errorf("%s: span kind = %v, want %v", name, ended.SpanKind(), kind)
+		}
+		if got := attrMap(ended.Attributes())["peer.service"]; got != "billing" {
+			t.Errorf("%s: peer.service = %q, want billing", name, got)
+		}
+	}
+
+	_, span := tt.StartSpan(ctx, "default kind")
+	tt.EndSpan(span)
+	if kind := tt.endedSpan(t, "default kind").SpanKind(); kind != trace.SpanKindInternal {
+		t.Errorf("StartSpan kind = %v, want internal", kind)
+	}
+}
+
+func TestPostEventLandsOnSpanInContext(t *testing.T) {
+	tt := newTestTelemetry(t)
+	ctx, parent := tt.StartSpan(context.Background(), "checkout")
+	childCtx, child := tt.StartSpan(ctx, "charge card")
+
+	tt.PostEvent(childCtx, "payment.authorized", map[string]string{"provider": "stripe"})
+	tt.PostEvent(context.Background(), "dropped", nil)
+	tt.EndSpan(child)
+	tt.EndSpan(parent)
+
+	events := tt.endedSpan(t, "charge card").Events()
+	if len(events) != 1 || events[0].Name != "payment.authorized" {
+		t.Fatalf("child span events = %+v, want the posted event", events)
+	}
+	if got := attrMap(events[0].Attributes)["provider"]; got != "stripe" {
+		t.Errorf("event provider = %q, want stripe", got)
+	}
+	if events := tt.endedSpan(t, "checkout").Events(); len(events) != 0 {
+		t.Errorf("parent span events = %+v, want none", events)
+	}
+}
+
+func TestPostTraceLandsOnSpanInContext(t *testing.T) {
+	logs := observeLogs(t)
+	tt := newTestTelemetry(t)
+	ctx, parent := tt.StartSpan(context.Background(), "sync inventory")
+	childCtx, child := tt.StartSpan(ctx, "fetch stock")
+
+	tt.PostTrace(childCtx, "stock feed is stale", "warn", map[string]string{"feed": "eu-warehouse"})
+	tt.EndSpan(child)
+	tt.EndSpan(parent)
+
+	events := tt.endedSpan(t, "fetch stock").Events()
+	if len(events) != 1 || events[0].Name != "Trace" {
+		t.Fatalf("child span events = %+v, want the trace event", events)
+	}
+	attrs := attrMap(events[0].Attributes)
+	for key, want := range map[string]string{
+		"message":         "stock feed is stale",
+		"severity":        "warning",
+		"severity_number": strconv.Itoa(SeverityWarning.ToOTelSeverity()),
+		"feed":            "eu-warehouse",
+	} {
+		if attrs[key] != want {
+			t.Errorf("trace event %s = %q, want %q", key, attrs[key], want)
+		}
+	}
+	if events := tt.endedSpan(t, "sync inventory").Events(); len(events) != 0 {
+		t.Errorf("parent span events = %+v, want none", events)
+	}
+	if entries := logs.FilterMessage("Trace recorded").All(); len(entries) != 1 || entries[0].Level != zap.WarnLevel {
+		t.Errorf("trace log entries = %+v, want one warning", entries)
+	}
+}
+
+func TestStartSpanOnMetricsOnlyInstance(t *testing.T) {
+	tt := newTestTelemetry(t, WithTracingEnabled(false))
+	ctx := context.Background()
+
+	gotCtx, span := tt.StartSpan(ctx, "metrics only")
+	span.SetAttributes(attribute.String("key", "value"))
+	span.End()
+	if gotCtx != ctx || span.IsRecording() {
+		t.Errorf("StartSpan() = %v, %v, want the caller's context and a no-op span", gotCtx, span)
+	}
+
+	// runLoop sets the iteration attribute on the span without checking it for nil
+	loopCtx, cancel := context.WithCancel(ctx)
+	ticks := make(chan time.Time, 1)
+	ticks <- time.Now()
+	runLoop(loopCtx, tt, "metrics only", ticks, time.Now, func(context.Context) error {
+		cancel()
+		return nil
+	})
+	if n := len(tt.spans.Ended()); n != 0 {
+		t.Errorf("recorded %d spans with tracing disabled, want 0", n)
+	}
+}
+
+func TestPostTraceKeepsUnrecognisedSeverity(t *testing.T) {
+	tt := newTestTelemetry(t)
+	ctx, span := tt.StartSpan(context.Background(), "sync inventory")
+
+	tt.PostTrace(ctx, "feed checked", "notice", nil)
+	tt.PostTrace(ctx, "feed stale", "warning", nil)
+	tt.EndSpan(span)
+
+	events := tt.endedSpan(t, "sync inventory").Events()
+	if len(events) != 2 {
+		t.Fatalf("span events = %+v, want both trace events", events)
+	}
+	notice := attrMap(events[0].Attributes)
+	if notice["severity"] != "information" || notice["severity_text"] != "notice" {
+		t.Errorf("unrecognised severity attributes = %v, want information with severity_text=notice", notice)
+	}
+	if _, ok := attrMap(events[1].Attributes)["severity_text"]; ok {
+		t.Error("recognised severity has a severity_text attribute, want none")
+	}
+}
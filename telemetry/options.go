@@ -0,0 +1,542 @@
+// options.go - Functional options for configuring the OpenTelemetry implementation
+
+package telemetry
+
+import (
+	"crypto/tls"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+)
+
+// otelConfig holds the optional settings applied when constructing an OpenTelemetry instance
+type otelConfig struct {
+	serviceName            string
+	traceServiceName       string
+	metricServiceName      string
+	resource               *resource.Resource
+	resourceAttributes     []attribute.KeyValue
+	kubernetesDetector     bool
+	schemaURL              string
+	runID                  string
+	traceEndpoint          string
+	metricEndpoint         string
+	traceEnabled           bool
+	metricsEnabled         bool
+	exporterProtocol       string
+	tlsConfig              *tls.Config
+	caCertFile             string
+	insecure               bool
+	dependencyMetrics      bool
+	statusDescriptionLimit int
+	startupSelfTest        bool
+	attributeKeyAliases    map[string]string
+	samplingPriorityHeader string
+	shutdownTimeout        time.Duration
+	expectedErrors         []error
+	prometheusText         bool
+	prometheusOnly         bool
+	prometheusRegistry     *prometheus.Registry
+	batchThroughputMetrics bool
+	sampler                sdktrace.Sampler
+	pushgatewayURL         string
+	pushgatewayJob         string
+	maxConcurrentExports   int
+	exportSlots            chan struct{}
+	exporterConnMetrics    bool
+	tenantRouter           TenantRouter
+	kpiLocation            *time.Location
+	slowSpanThreshold      time.Duration
+	spanLeakThreshold      time.Duration
+	recentErrors           int
+	routeSampling          map[string]float64
+	samplingPollInterval   time.Duration
+	eventsAsLogs           bool
+	messagingSystem        string
+	metricReaders          []sdkmetric.Reader
+	spanProcessors         []sdktrace.SpanProcessor
+	instrumentKinds        map[string]InstrumentKind
+	strictInstruments      bool
+	traceCapturePath       string
+	metricDropRules        map[string][]metricDropRule
+	spanDurationMetrics    bool
+
+	httpDependencyFailureStatus   int
+	httpDependencySuccessStatuses []int
+}
+
+// Option configures optional behavior of an OpenTelemetry instance
+type Option func(*otelConfig)
+
+// defaultOtelConfig returns the configuration used when no options are given
+func defaultOtelConfig() otelConfig {
+	aliases := make(map[string]string, len(defaultAttributeKeyAliases))
+	for k, v := range defaultAttributeKeyAliases {
+		aliases[k] = v
+	}
+	return otelConfig{
+		exporterProtocol:       ProtocolGRPC,
+		schemaURL:              semconv.SchemaURL,
+		kubernetesDetector:     true,
+		kpiLocation:            time.UTC,
+		dependencyMetrics:      true,
+		statusDescriptionLimit: 256,
+		attributeKeyAliases:    aliases,
+		samplingPriorityHeader: DefaultSamplingPriorityHeader,
+		shutdownTimeout:        5 * time.Second,
+		sampler:                sdktrace.ParentBased(sdktrace.AlwaysSample()),
+		messagingSystem:        "kafka",
+		samplingPollInterval:   5 * time.Second,
+
+		httpDependencyFailureStatus: 400,
+	}
+}
+
+// WithTraceEndpoint sets the OTLP gRPC endpoint spans are exported to. A
+// comma-separated list exports every span to each endpoint.
+func WithTraceEndpoint(endpoint string) Option {
+	return func(c *otelConfig) {
+		c.traceEndpoint = endpoint
+	}
+}
+
+// WithMetricEndpoint sets the OTLP gRPC endpoint metrics are exported to
+func WithMetricEndpoint(endpoint string) Option {
+	return func(c *otelConfig) {
+		c.metricEndpoint = endpoint
+	}
+}
+
+// WithTracingEnabled controls whether spans are recorded and exported (default: false)
+func WithTracingEnabled(enabled bool) Option {
+	return func(c *otelConfig) {
+		c.traceEnabled = enabled
+	}
+}
+
+// WithMetricsEnabled controls whether metrics are recorded and exported (default: false)
+func WithMetricsEnabled(enabled bool) Option {
+	return func(c *otelConfig) {
+		c.metricsEnabled = enabled
+	}
+}
+
+// WithExporterProtocol sets the OTLP protocol of the trace and metric exporters,
+// ProtocolGRPC or ProtocolHTTPProtobuf (default: grpc). With HTTP, an endpoint may
+// be a full URL such as https://collector:4318/v1/traces to set scheme and path.
+func WithExporterProtocol(protocol string) Option {
+	return func(c *otelConfig) {
+		c.exporterProtocol = protocol
+	}
+}
+
+// WithTLSConfig sets the TLS configuration of the trace and metric exporters. It
+// takes precedence over WithInsecure.
+func WithTLSConfig(config *tls.Config) Option {
+	return func(c *otelConfig) {
+		c.tlsConfig = config
+	}
+}
+
+// WithCACertFile verifies the collector against the PEM-encoded CA certificates in
+// the file at path instead of the system roots. It takes precedence over WithInsecure.
+func WithCACertFile(path string) Option {
+	return func(c *otelConfig) {
+		c.caCertFile = path
+	}
+}
+
+// WithInsecure sends traces and metrics without TLS, e.g. to a collector sidecar
+// (default: TLS with the system roots)
+func WithInsecure() Option {
+	return func(c *otelConfig) {
+		c.insecure = true
+	}
+}
+
+// WithDependencyMetrics controls whether TrackDependency also records the
+// dependency.calls counter and dependency.duration histogram (default: true)
+func WithDependencyMetrics(enabled bool) Option {
+	return func(c *otelConfig) {
+		c.dependencyMetrics = enabled
+	}
+}
+
+// WithStatusDescriptionLimit sets the maximum length in characters of span status
+// descriptions set by RecordError (default: 256). A value <= 0 disables truncation.
+func WithStatusDescriptionLimit(limit int) Option {
+	return func(c *otelConfig) {
+		c.statusDescriptionLimit = limit
+	}
+}
+
+// WithStartupSelfTest emits a canary span and counter after initialization and
+// flushes them, logging whether the export succeeded (default: false)
+func WithStartupSelfTest(enabled bool) Option {
+	return func(c *otelConfig) {
+		c.startupSelfTest = enabled
+	}
+}
+
+// WithAttributeKeyAliases adds attribute key aliases, mapping an alias to its
+// canonical key, on top of the HTTP and DB defaults. Metric attributes using an
+// alias are recorded under the canonical key.
+func WithAttributeKeyAliases(aliases map[string]string) Option {
+	return func(c *otelConfig) {
+		for alias, canonical := range aliases {
+			c.attributeKeyAliases[alias] = canonical
+		}
+	}
+}
+
+// WithoutDefaultAttributeKeyAliases removes the built-in HTTP and DB attribute key
+// aliases. Aliases added by WithAttributeKeyAliases after this option still apply.
+func WithoutDefaultAttributeKeyAliases() Option {
+	return func(c *otelConfig) {
+		c.attributeKeyAliases = make(map[string]string)
+	}
+}
+
+// WithSamplingPriorityHeader sets the request header read for a client-requested
+// sampling priority (default: X-Sampling-Priority)
+func WithSamplingPriorityHeader(name string) Option {
+	return func(c *otelConfig) {
+		c.samplingPriorityHeader = name
+	}
+}
+
+// WithShutdownTimeout sets the timeout applied by Shutdown when the passed context
+// has no deadline (default: 5s). A value <= 0 disables the timeout.
+func WithShutdownTimeout(timeout time.Duration) Option {
+	return func(c *otelConfig) {
+		c.shutdownTimeout = timeout
+	}
+}
+
+// WithExpectedErrors registers errors that RecordErrorUnlessExpected treats as
+// expected outcomes rather than failures. Matching uses errors.Is.
+func WithExpectedErrors(errs ...error) Option {
+	return func(c *otelConfig) {
+		c.expectedErrors = append(c.expectedErrors, errs...)
+	}
+}
+
+// WithPrometheusText installs an additional manual metric reader so that
+// RenderPrometheus can render the current metrics on demand (default: false)
+func WithPrometheusText(enabled bool) Option {
+	return func(c *otelConfig) {
+		c.prometheusText = enabled
+	}
+}
+
+// WithPrometheusExporter registers the metrics with registry through the
+// OpenTelemetry Prometheus exporter instead of exporting them over OTLP, for
+// pipelines that scrape Prometheus endpoints. PrometheusHandler serves registry;
+// a nil registry uses a new one. Summaries have no equivalent in the exporter
+// and are not exposed.
+func WithPrometheusExporter(registry *prometheus.Registry) Option {
+	return func(c *otelConfig) {
+		if registry == nil {
+			registry = prometheus.NewRegistry()
+		}
+		c.prometheusRegistry = registry
+		c.prometheusOnly = true
+	}
+}
+
+// WithBatchThroughputMetrics records the batch.throughput histogram, in items per
+// second, when a span tracked with IncrementProcessed ends (default: false)
+func WithBatchThroughputMetrics(enabled bool) Option {
+	return func(c *otelConfig) {
+		c.batchThroughputMetrics = enabled
+	}
+}
+
+// WithSampler sets the sampler used by the tracer provider (default: parent-based
+// always-on). Context-scoped sampling priorities are applied on top of it.
+func WithSampler(sampler sdktrace.Sampler) Option {
+	return func(c *otelConfig) {
+		c.sampler = sampler
+	}
+}
+
+// WithPushgateway pushes the current metrics to the Prometheus Pushgateway at url
+// under the given job on Flush and Shutdown, gathered from the WithPrometheusExporter
+// registry or a private one. An empty job defaults to the service name.
+func WithPushgateway(url, job string) Option {
+	return func(c *otelConfig) {
+		c.pushgatewayURL = url
+		c.pushgatewayJob = job
+	}
+}
+
+// WithMaxConcurrentExports limits the number of span exports running at once
+// across all trace endpoints, including tenant endpoints, queuing the rest, so
+// bursts cannot overwhelm the collectors (default: unlimited)
+func WithMaxConcurrentExports(limit int) Option {
+	return func(c *otelConfig) {
+		c.maxConcurrentExports = limit
+	}
+}
+
+// WithExporterConnectionMetrics records the telemetry.exporter.connected gauge, 1
+// while the gRPC connection to a trace endpoint is ready and 0 otherwise, with an
+// endpoint attribute. Connections are kept open so the gauge tracks collector
+// connectivity between exports. Not supported with http/protobuf (default: false).
+func WithExporterConnectionMetrics(enabled bool) Option {
+	return func(c *otelConfig) {
+		c.exporterConnMetrics = enabled
+	}
+}
+
+// WithTenantRouting exports the spans for which route returns an endpoint to that
+// endpoint instead of the default trace endpoints, e.g. to keep the data of some
+// tenants in their region. route is called with the context each span is started
+// in; an exporter is created per endpoint on first use, with the same protocol,
+// transport security and processing as the default ones.
+func WithTenantRouting(route TenantRouter) Option {
+	return func(c *otelConfig) {
+		c.tenantRouter = route
+	}
+}
+
+// WithKPILocation sets the time zone whose midnight starts the days of the totals
+// recorded with RecordDailyKPI (default: UTC)
+func WithKPILocation(loc *time.Location) Option {
+	return func(c *otelConfig) {
+		if loc != nil {
+			c.kpiLocation = loc
+		}
+	}
+}
+
+// WithBaggageSampler samples root spans at baseRatio, or at the highest ratio of the
+// rules matching the baggage in the context, and follows the parent decision otherwise
+func WithBaggageSampler(baseRatio float64, rules map[BaggageKeyValue]float64) Option {
+	return func(c *otelConfig) {
+		c.sampler = sdktrace.ParentBased(BaggageSampler(baseRatio, rules))
+	}
+}
+
+// WithSlowSpanThreshold exports only spans lasting at least threshold, dropping
+// faster ones when they end (default: 0, all spans are exported)
+func WithSlowSpanThreshold(threshold time.Duration) Option {
+	return func(c *otelConfig) {
+		c.slowSpanThreshold = threshold
+	}
+}
+
+// WithSpanLeakDetection tracks spans that are started but not ended, listed by
+// LiveSpans, and logs a warning for each span still live after threshold
+// (default: 0, disabled)
+func WithSpanLeakDetection(threshold time.Duration) Option {
+	return func(c *otelConfig) {
+		c.spanLeakThreshold = threshold
+	}
+}
+
+// WithRecentErrors keeps the last capacity errors passed to RecordError for
+// retrieval via RecentErrors (default: 0, disabled)
+func WithRecentErrors(capacity int) Option {
+	return func(c *otelConfig) {
+		c.recentErrors = capacity
+	}
+}
+
+// WithRouteSampling sets sampling ratios per HTTP route template, e.g. 0 for
+// /healthz and 1 for rare important routes. It applies to root spans started
+// with an http.route attribute, such as the spans of the HTTP middleware.
+func WithRouteSampling(ratios map[string]float64) Option {
+	return func(c *otelConfig) {
+		c.routeSampling = ratios
+	}
+}
+
+// WithSamplingConfigPollInterval sets how often the file watched with
+// WatchSamplingConfig is checked for changes (default: 5s). Non-positive
+// intervals are ignored.
+func WithSamplingConfigPollInterval(interval time.Duration) Option {
+	return func(c *otelConfig) {
+		if interval > 0 {
+			c.samplingPollInterval = interval
+		}
+	}
+}
+
+// WithHTTPDependencyFailureStatus sets the lowest status code TrackHTTPDependency
+// treats as a failure (default: 400)
+func WithHTTPDependencyFailureStatus(statusCode int) Option {
+	return func(c *otelConfig) {
+		c.httpDependencyFailureStatus = statusCode
+	}
+}
+
+// WithHTTPDependencySuccessStatuses marks status codes at or above the failure
+// threshold that TrackHTTPDependency still treats as success, e.g. 404 for lookups
+func WithHTTPDependencySuccessStatuses(statusCodes ...int) Option {
+	return func(c *otelConfig) {
+		c.httpDependencySuccessStatuses = append(c.httpDependencySuccessStatuses, statusCodes...)
+	}
+}
+
+// WithServiceName sets the service name, taking precedence over the serviceName
+// argument and the OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES variables
+func WithServiceName(name string) Option {
+	return func(c *otelConfig) {
+		c.serviceName = name
+	}
+}
+
+// WithTraceServiceName sets a service.name for spans that differs from the service
+// name used for metrics, e.g. for one of several logical services in a process
+// (default: the service name)
+func WithTraceServiceName(name string) Option {
+	return func(c *otelConfig) {
+		c.traceServiceName = name
+	}
+}
+
+// WithMetricServiceName sets a service.name for metrics that differs from the
+// service name used for spans (default: the service name)
+func WithMetricServiceName(name string) Option {
+	return func(c *otelConfig) {
+		c.metricServiceName = name
+	}
+}
+
+// WithEventsAsLogs also emits every event added through AddEvent or PostEvent as
+// a log line carrying the trace and span IDs, for log-only backends (default: false)
+func WithEventsAsLogs(enabled bool) Option {
+	return func(c *otelConfig) {
+		c.eventsAsLogs = enabled
+	}
+}
+
+// WithMessagingSystem sets the messaging.system attribute of consumer lag metrics
+// (default: kafka)
+func WithMessagingSystem(system string) Option {
+	return func(c *otelConfig) {
+		c.messagingSystem = system
+	}
+}
+
+// WithMetricReader registers an additional metric reader on the meter provider,
+// e.g. a manual reader collecting metrics on demand in tests
+func WithMetricReader(reader sdkmetric.Reader) Option {
+	return func(c *otelConfig) {
+		c.metricReaders = append(c.metricReaders, reader)
+	}
+}
+
+// WithSpanProcessor registers an additional span processor on the tracer provider,
+// e.g. a span recorder keeping finished spans in memory in tests
+func WithSpanProcessor(processor sdktrace.SpanProcessor) Option {
+	return func(c *otelConfig) {
+		c.spanProcessors = append(c.spanProcessors, processor)
+	}
+}
+
+// WithInstrumentKinds declares the intended instrument kind of metric names, so that
+// recording one with a mismatched method is detected even on its first use
+func WithInstrumentKinds(kinds map[string]InstrumentKind) Option {
+	return func(c *otelConfig) {
+		if c.instrumentKinds == nil {
+			c.instrumentKinds = make(map[string]InstrumentKind, len(kinds))
+		}
+		for name, kind := range kinds {
+			c.instrumentKinds[name] = kind
+		}
+	}
+}
+
+// WithStrictInstruments reports metric values recorded with an instrument kind that
+// does not match the metric name as errors, logging each dropped value and failing
+// RecordMetricE, instead of only warning once about the mismatch (default: false)
+func WithStrictInstruments(strict bool) Option {
+	return func(c *otelConfig) {
+		c.strictInstruments = strict
+	}
+}
+
+// WithTraceCapture additionally writes finished spans to the file at path as
+// length-delimited OTLP protobuf, for later replay with ReplayTraceFile
+func WithTraceCapture(path string) Option {
+	return func(c *otelConfig) {
+		c.traceCapturePath = path
+	}
+}
+
+// WithMetricDropRule drops data points of the named instrument whose attribute
+// attrKey, after alias normalization, has the value attrValue, e.g. requests made
+// by an internal health-check user agent
+func WithMetricDropRule(instrument, attrKey, attrValue string) Option {
+	return func(c *otelConfig) {
+		if c.metricDropRules == nil {
+			c.metricDropRules = make(map[string][]metricDropRule)
+		}
+		c.metricDropRules[instrument] = append(c.metricDropRules[instrument], metricDropRule{
+			key:   attribute.Key(attrKey),
+			value: attrValue,
+		})
+	}
+}
+
+// WithSpanDurationMetrics records the span.duration histogram, in milliseconds with
+// a span.name attribute, for every ended span when metrics are enabled. ID-like path
+// segments of span names are replaced to bound cardinality (default: false).
+func WithSpanDurationMetrics(enabled bool) Option {
+	return func(c *otelConfig) {
+		c.spanDurationMetrics = enabled
+	}
+}
+
+// WithResource uses res as the resource of the tracer and meter providers instead of
+// detecting one from the environment. The configured service name, if any, is merged
+// into it; otherwise its own service.name is kept.
+func WithResource(res *resource.Resource) Option {
+	return func(c *otelConfig) {
+		c.resource = res
+	}
+}
+
+// WithResourceAttributes adds attributes such as deployment.environment or
+// service.instance.id to the resource of the tracer and meter providers. They take
+// precedence over OTEL_RESOURCE_ATTRIBUTES and OTEL_SERVICE_VERSION, but not over
+// the configured service name. Repeated calls accumulate attributes.
+func WithResourceAttributes(attrs ...attribute.KeyValue) Option {
+	return func(c *otelConfig) {
+		c.resourceAttributes = append(c.resourceAttributes, attrs...)
+	}
+}
+
+// WithKubernetesDetector sets whether the k8s.pod.name, k8s.namespace.name,
+// k8s.node.name and k8s.pod.uid resource attributes are read from the POD_NAME,
+// POD_NAMESPACE, NODE_NAME and POD_UID variables set through the downward API
+// (default: true)
+func WithKubernetesDetector(enabled bool) Option {
+	return func(c *otelConfig) {
+		c.kubernetesDetector = enabled
+	}
+}
+
+// WithSchemaURL sets the OpenTelemetry schema URL of the resource and of the tracer
+// and meter scopes, for attributes following a different semantic conventions
+// version (default: the semconv v1.17.0 schema URL)
+func WithSchemaURL(schemaURL string) Option {
+	return func(c *otelConfig) {
+		c.schemaURL = schemaURL
+	}
+}
+
+// WithRunID sets the run.id attribute of the run summary metrics recorded from
+// AddToSummary totals (default: a random ID per instance)
+func WithRunID(id string) Option {
+	return func(c *otelConfig) {
+		c.runID = id
+	}
+}
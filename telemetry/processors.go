@@ -0,0 +1,55 @@
+// processors.go - Span processors wrapping the export pipeline
+
+package telemetry
+
+import (
+	"context"
+	"time"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// slowSpanProcessor forwards only spans lasting at least threshold to the wrapped
+// processor, so fast spans are never exported
+type slowSpanProcessor struct {
+	next      sdktrace.SpanProcessor
+	threshold time.Duration
+}
+
+// newSlowSpanProcessor wraps next so it only receives spans slower than threshold
+func newSlowSpanProcessor(next sdktrace.SpanProcessor, threshold time.Duration) *slowSpanProcessor {
+	return &slowSpanProcessor{next: next, threshold: threshold}
+}
+
+// OnStart forwards the started span to the wrapped processor
+func (p *slowSpanProcessor) OnStart(parent context.Context, s sdktrace.ReadWriteSpan) {
+	p.next.OnStart(parent, s)
+}
+
+// OnEnd forwards the span to the wrapped processor only if it exceeded the threshold
+func (p *slowSpanProcessor) OnEnd(s sdktrace.ReadOnlySpan) {
+	if s.EndTime().Sub(s.StartTime()) < p.threshold {
+		return
+	}
+	p.next.OnEnd(s)
+}
+
+// Shutdown shuts down the wrapped processor
+func (p *slowSpanProcessor) Shutdown(ctx context.Context) error {
+	return p.next.Shutdown(ctx)
+}
+
+// ForceFlush flushes the wrapped processor
+func (p *slowSpanProcessor) ForceFlush(ctx context.Context) error {
+	return p.next.ForceFlush(ctx)
+}
+
+// newExportProcessor returns the batching processor for an exporter, wrapped
+// according to the configuration
+func newExportProcessor(exporter sdktrace.SpanExporter, cfg otelConfig) sdktrace.SpanProcessor {
+	var processor sdktrace.SpanProcessor = sdktrace.NewBatchSpanProcessor(exporter)
+	if cfg.slowSpanThreshold > 0 {
+		processor = newSlowSpanProcessor(processor, cfg.slowSpanThreshold)
+	}
+	return processor
+}
@@ -0,0 +1,39 @@
+package telemetry
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestSlowSpanThresholdExportsOnlySlowSpans(t *testing.T) {
+	collector := newStubCollector(t)
+	tt := newTestTelemetry(t,
+		WithTraceEndpoint(collector.addr),
+		WithMetricEndpoint(collector.addr),
+		WithSlowSpanThreshold(100*time.Millisecond))
+	ctx := context.Background()
+
+	start := time.Now()
+	for name, duration := range map[string]time.Duration{
+		"fast":      10 * time.Millisecond,
+		"threshold": 100 * time.Millisecond,
+		"slow":      time.Second,
+	} {
+		_, span := tt.tracer.Start(ctx, name, trace.WithTimestamp(start))
+		span.End(trace.WithTimestamp(start.Add(duration)))
+	}
+	if err := tt.forceFlush(ctx); err != nil {
+		t.Fatalf("forceFlush() error = %v", err)
+	}
+
+	exported := make(map[string]bool)
+	for _, name := range collector.receivedSpans() {
+		exported[name] = true
+	}
+	if len(exported) != 2 || !exported["slow"] || !exported["threshold"] {
+		t.Errorf("exported spans %v, want only the spans lasting at least the threshold", collector.receivedSpans())
+	}
+}
@@ -0,0 +1,273 @@
+// prometheus.go - Rendering and serving of collected metrics in the Prometheus exposition format
+
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"google.golang.org/protobuf/proto"
+)
+
+// errPrometheusDisabled is returned when metrics are rendered without a Prometheus reader installed
+var errPrometheusDisabled = errors.New("prometheus rendering is not enabled, use WithPrometheusText(true) with metrics enabled")
+
+// RenderPrometheus collects the current metrics and formats them in the Prometheus
+// text exposition format. It requires metrics and WithPrometheusText to be enabled.
+func (o *OpenTelemetry) RenderPrometheus(ctx context.Context) (string, error) {
+	families, err := o.gatherPrometheus(ctx)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	if err := encodePrometheus(&b, expfmt.NewFormat(expfmt.TypeTextPlain), families); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// PrometheusHandler returns an HTTP handler serving the current metrics in the
+// Prometheus exposition format, to be mounted on /metrics. With
+// WithPrometheusExporter it serves the exporter's registry, otherwise it serves the
+// metrics of RenderPrometheus, in the format negotiated from the Accept header, and
+// requires WithPrometheusText. Metrics must be enabled.
+func (o *OpenTelemetry) PrometheusHandler() http.Handler {
+	if o.prometheusRegistry != nil {
+		return promhttp.HandlerFor(o.prometheusRegistry, promhttp.HandlerOpts{})
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		families, err := o.gatherPrometheus(r.Context())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		format := expfmt.Negotiate(r.Header)
+		w.Header().Set("Content-Type", string(format))
+		_ = encodePrometheus(w, format, families)
+	})
+}
+
+// gatherPrometheus collects the current metrics from the Prometheus reader as
+// metric families
+func (o *OpenTelemetry) gatherPrometheus(ctx context.Context) ([]*dto.MetricFamily, error) {
+	if o.prometheusReader == nil {
+		return nil, errPrometheusDisabled
+	}
+	var rm metricdata.ResourceMetrics
+	if err := o.prometheusReader.Collect(ctx, &rm); err != nil {
+		return nil, fmt.Errorf("failed to collect metrics: %w", err)
+	}
+	return prometheusFamilies(rm), nil
+}
+
+// encodePrometheus writes the metric families to w in the given exposition format
+func encodePrometheus(w io.Writer, format expfmt.Format, families []*dto.MetricFamily) error {
+	enc := expfmt.NewEncoder(w, format)
+	for _, mf := range families {
+		if err := enc.Encode(mf); err != nil {
+			return fmt.Errorf("failed to encode metrics: %w", err)
+		}
+	}
+	if closer, ok := enc.(expfmt.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// prometheusFamilies converts collected metrics to Prometheus metric families.
+// Exponential histograms have no direct equivalent and are skipped.
+func prometheusFamilies(rm metricdata.ResourceMetrics) []*dto.MetricFamily {
+	var families []*dto.MetricFamily
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if mf := prometheusFamily(m); mf != nil {
+				families = append(families, mf)
+			}
+		}
+	}
+	return families
+}
+
+// prometheusFamily converts a single metric, returning nil for unsupported data
+func prometheusFamily(m metricdata.Metrics) *dto.MetricFamily {
+	name := sanitizePrometheusName(m.Name)
+	mf := &dto.MetricFamily{}
+
+	switch data := m.Data.(type) {
+	case metricdata.Sum[float64]:
+		name, mf.Type, mf.Metric = prometheusSum(name, data)
+	case metricdata.Sum[int64]:
+		name, mf.Type, mf.Metric = prometheusSum(name, data)
+	case metricdata.Gauge[float64]:
+		mf.Type, mf.Metric = dto.MetricType_GAUGE.Enum(), prometheusGauges(data.DataPoints)
+	case metricdata.Gauge[int64]:
+		mf.Type, mf.Metric = dto.MetricType_GAUGE.Enum(), prometheusGauges(data.DataPoints)
+	case metricdata.Histogram[float64]:
+		mf.Type, mf.Metric = dto.MetricType_HISTOGRAM.Enum(), prometheusHistograms(data.DataPoints)
+	case metricdata.Histogram[int64]:
+		mf.Type, mf.Metric = dto.MetricType_HISTOGRAM.Enum(), prometheusHistograms(data.DataPoints)
+	case metricdata.Summary:
+		mf.Type, mf.Metric = dto.MetricType_SUMMARY.Enum(), prometheusSummaries(data.DataPoints)
+	default:
+		return nil
+	}
+
+	mf.Name = proto.String(name)
+	if m.Description != "" {
+		mf.Help = proto.String(m.Description)
+	}
+	sort.Slice(mf.Metric, func(i, j int) bool {
+		return prometheusLabelKey(mf.Metric[i].Label) < prometheusLabelKey(mf.Metric[j].Label)
+	})
+	return mf
+}
+
+// prometheusSum returns the exposed name, type and metrics of a sum; monotonic sums
+// are counters with a _total suffix, others are gauges
+func prometheusSum[N int64 | float64](name string, data metricdata.Sum[N]) (string, *dto.MetricType, []*dto.Metric) {
+	if !data.IsMonotonic {
+		return name, dto.MetricType_GAUGE.Enum(), prometheusGauges(data.DataPoints)
+	}
+	if !strings.HasSuffix(name, "_total") {
+		name += "_total"
+	}
+	metrics := make([]*dto.Metric, 0, len(data.DataPoints))
+	for _, dp := range data.DataPoints {
+		metrics = append(metrics, &dto.Metric{
+			Label:   prometheusLabels(dp.Attributes),
+			Counter: &dto.Counter{Value: proto.Float64(float64(dp.Value))},
+		})
+	}
+	return name, dto.MetricType_COUNTER.Enum(), metrics
+}
+
+// prometheusGauges returns one gauge metric per data point
+func prometheusGauges[N int64 | float64](points []metricdata.DataPoint[N]) []*dto.Metric {
+	metrics := make([]*dto.Metric, 0, len(points))
+	for _, dp := range points {
+		metrics = append(metrics, &dto.Metric{
+			Label: prometheusLabels(dp.Attributes),
+			Gauge: &dto.Gauge{Value: proto.Float64(float64(dp.Value))},
+		})
+	}
+	return metrics
+}
+
+// prometheusHistograms returns one histogram metric with cumulative buckets per
+// data point; the encoder adds the +Inf bucket
+func prometheusHistograms[N int64 | float64](points []metricdata.HistogramDataPoint[N]) []*dto.Metric {
+	metrics := make([]*dto.Metric, 0, len(points))
+	for _, dp := range points {
+		buckets := make([]*dto.Bucket, 0, len(dp.Bounds))
+		var cumulative uint64
+		for i, bound := range dp.Bounds {
+			if i < len(dp.BucketCounts) {
+				cumulative += dp.BucketCounts[i]
+			}
+			buckets = append(buckets, &dto.Bucket{
+				UpperBound:      proto.Float64(bound),
+				CumulativeCount: proto.Uint64(cumulative),
+			})
+		}
+		metrics = append(metrics, &dto.Metric{
+			Label: prometheusLabels(dp.Attributes),
+			Histogram: &dto.Histogram{
+				SampleCount: proto.Uint64(dp.Count),
+				SampleSum:   proto.Float64(float64(dp.Sum)),
+				Bucket:      buckets,
+			},
+		})
+	}
+	return metrics
+}
+
+// prometheusSummaries returns one summary metric per data point
+func prometheusSummaries(points []metricdata.SummaryDataPoint) []*dto.Metric {
+	metrics := make([]*dto.Metric, 0, len(points))
+	for _, dp := range points {
+		quantiles := make([]*dto.Quantile, 0, len(dp.QuantileValues))
+		for _, qv := range dp.QuantileValues {
+			quantiles = append(quantiles, &dto.Quantile{
+				Quantile: proto.Float64(qv.Quantile),
+				Value:    proto.Float64(qv.Value),
+			})
+		}
+		metrics = append(metrics, &dto.Metric{
+			Label: prometheusLabels(dp.Attributes),
+			Summary: &dto.Summary{
+				SampleCount: proto.Uint64(dp.Count),
+				SampleSum:   proto.Float64(dp.Sum),
+				Quantile:    quantiles,
+			},
+		})
+	}
+	return metrics
+}
+
+// prometheusLabels converts an attribute set to label pairs
+func prometheusLabels(set attribute.Set) []*dto.LabelPair {
+	labels := make([]*dto.LabelPair, 0, set.Len())
+	iter := set.Iter()
+	for iter.Next() {
+		kv := iter.Attribute()
+		labels = append(labels, &dto.LabelPair{
+			Name:  proto.String(sanitizePrometheusLabelName(string(kv.Key))),
+			Value: proto.String(kv.Value.Emit()),
+		})
+	}
+	return labels
+}
+
+// prometheusLabelKey returns a sort key for the label pairs of a metric
+func prometheusLabelKey(labels []*dto.LabelPair) string {
+	var b strings.Builder
+	for _, label := range labels {
+		b.WriteString(label.GetName())
+		b.WriteByte(0)
+		b.WriteString(label.GetValue())
+		b.WriteByte(0)
+	}
+	return b.String()
+}
+
+// sanitizePrometheusName replaces characters not allowed in Prometheus metric
+// names with underscores
+func sanitizePrometheusName(name string) string {
+	return sanitizePrometheusIdentifier(name, true)
+}
+
+// sanitizePrometheusLabelName replaces characters not allowed in Prometheus label
+// names, which unlike metric names cannot contain colons, with underscores
+func sanitizePrometheusLabelName(name string) string {
+	return sanitizePrometheusIdentifier(name, false)
+}
+
+// sanitizePrometheusIdentifier replaces characters not allowed in a metric name or,
+// without colons, a label name with underscores
+func sanitizePrometheusIdentifier(name string, colons bool) string {
+	var b strings.Builder
+	for i, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r == '_', r == ':' && colons:
+			b.WriteRune(r)
+		case r >= '0' && r <= '9':
+			if i == 0 {
+				b.WriteByte('_')
+			}
+			b.WriteRune(r)
+		default:
+			b.WriteByte('_')
+		}
+	}
+	return b.String()
+}
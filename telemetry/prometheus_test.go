@@ -0,0 +1,150 @@
+package telemetry
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+func TestRenderPrometheusFormatsCounter(t *testing.T) {
+	tt := newTestTelemetry(t, WithPrometheusText(true))
+	ctx := context.Background()
+	tt.IncrementCounter(ctx, "http.requests", 2, attribute.String("region", "GET"))
+	tt.IncrementCounter(ctx, "http.requests", 1, attribute.String("region", `P"OST`))
+	tt.RecordGauge(ctx, "queue.depth", 7)
+
+	text, err := tt.RenderPrometheus(ctx)
+	if err != nil {
+		t.Fatalf("RenderPrometheus() error = %v", err)
+	}
+	for _, want := range []string{
+		"# TYPE http_requests_total counter\n",
+		`http_requests_total{region="GET"} 2` + "\n",
+		`http_requests_total{region="P\"OST"} 1` + "\n",
+		"# TYPE queue_depth gauge\n",
+		"queue_depth 7\n",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("exposition output does not contain %q:\n%s", want, text)
+		}
+	}
+}
+
+func TestRenderPrometheusSanitizesLabelNamesAndFormatsHistograms(t *testing.T) {
+	tt := newTestTelemetry(t, WithPrometheusText(true))
+	ctx := context.Background()
+	tt.IncrementCounter(ctx, "db.queries", 1, attribute.String("db:system", "postgres"))
+	tt.RecordHistogram(ctx, "job.seconds", 3)
+
+	text, err := tt.RenderPrometheus(ctx)
+	if err != nil {
+		t.Fatalf("RenderPrometheus() error = %v", err)
+	}
+	for _, want := range []string{
+		`db_queries_total{db_system="postgres"} 1` + "\n",
+		`job_seconds_bucket{le="5"} 1` + "\n",
+		`job_seconds_bucket{le="+Inf"} 1` + "\n",
+		"job_seconds_sum 3\n",
+		"job_seconds_count 1\n",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("exposition output does not contain %q:\n%s", want, text)
+		}
+	}
+}
+
+func TestPrometheusHandlerNegotiatesFormat(t *testing.T) {
+	tt := newTestTelemetry(t, WithPrometheusText(true))
+	tt.IncrementCounter(context.Background(), "orders.processed", 1)
+	srv := httptest.NewServer(tt.PrometheusHandler())
+	defer srv.Close()
+
+	for accept, want := range map[string]string{
+		"": "text/plain",
+		"application/vnd.google.protobuf;proto=io.prometheus.client.MetricFamily;encoding=delimited": "application/vnd.google.protobuf",
+	} {
+		req, err := http.NewRequest(http.MethodGet, srv.URL, nil)
+		if err != nil {
+			t.Fatalf("NewRequest() error = %v", err)
+		}
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("scrape failed: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), want) {
+			t.Errorf("Accept %q: status %d, Content-Type %q, want 200 and %s", accept, resp.StatusCode, resp.Header.Get("Content-Type"), want)
+		}
+	}
+}
+
+func TestRenderPrometheusRequiresReader(t *testing.T) {
+	tt := newTestTelemetry(t)
+	if _, err := tt.RenderPrometheus(context.Background()); err != errPrometheusDisabled {
+		t.Errorf("RenderPrometheus() error = %v, want errPrometheusDisabled", err)
+	}
+}
+
+func TestPrometheusExporterServesRegistry(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	tt := newTestTelemetry(t, WithPrometheusExporter(registry))
+	ctx := context.Background()
+	tt.IncrementCounter(ctx, "orders.processed", 3, attribute.String("region", "eu"))
+	tt.RecordGauge(ctx, "queue.depth", 7)
+
+	srv := httptest.NewServer(tt.PrometheusHandler())
+	defer srv.Close()
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("scrape failed: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("reading scrape body failed: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("scrape status = %d, want 200:\n%s", resp.StatusCode, body)
+	}
+	for _, want := range []string{
+		"# TYPE orders_processed_total counter\n",
+		`orders_processed_total{otel_scope_name=`,
+		`region="eu"} 3` + "\n",
+		"# TYPE queue_depth gauge\n",
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("scrape output does not contain %q:\n%s", want, body)
+		}
+	}
+
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("Gather() error = %v", err)
+	}
+	var found bool
+	for _, mf := range families {
+		found = found || mf.GetName() == "orders_processed_total"
+	}
+	if !found {
+		t.Error("metrics were not registered with the supplied registry")
+	}
+}
+
+func TestPrometheusExporterSkipsOTLPReader(t *testing.T) {
+	tt := newTestTelemetry(t, WithPrometheusExporter(nil))
+	if tt.prometheusRegistry == nil {
+		t.Fatal("WithPrometheusExporter(nil) did not create a registry")
+	}
+	if _, err := tt.RenderPrometheus(context.Background()); err != errPrometheusDisabled {
+		t.Errorf("RenderPrometheus() error = %v, want errPrometheusDisabled without WithPrometheusText", err)
+	}
+}
@@ -0,0 +1,123 @@
+// propagation.go - Propagation of trace context and baggage across process boundaries
+
+package telemetry
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/sadco-io/sad-go-logger/logger"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/propagation"
+	"go.uber.org/zap"
+)
+
+const (
+	traceparentHeader = "traceparent"
+
+	// invalidTraceparentLogInterval is the minimum time between two logs of a
+	// malformed traceparent header
+	invalidTraceparentLogInterval = time.Minute
+)
+
+// traceparentPattern matches a W3C traceparent header value: version, trace ID,
+// parent span ID and flags. Versions other than 00 may append further fields.
+var traceparentPattern = regexp.MustCompile(`^([0-9a-f]{2})-([0-9a-f]{32})-([0-9a-f]{16})-([0-9a-f]{2})(-.*)?$`)
+
+// textMapPropagator injects and extracts W3C trace context and baggage. It is also
+// installed as the global propagator.
+var textMapPropagator = propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+
+// InjectContext writes the W3C trace context and baggage of ctx to carrier, such as
+// the headers of an outbound request or of a produced message
+func (o *OpenTelemetry) InjectContext(ctx context.Context, carrier propagation.TextMapCarrier) {
+	textMapPropagator.Inject(ctx, carrier)
+}
+
+// ExtractContext returns ctx with the W3C trace context and baggage read from
+// carrier, such as the headers of a consumed message. Use ExtractHTTPContext for
+// incoming HTTP requests to also repair malformed traceparent headers.
+func (o *OpenTelemetry) ExtractContext(ctx context.Context, carrier propagation.TextMapCarrier) context.Context {
+	return textMapPropagator.Extract(ctx, carrier)
+}
+
+// SetBaggage returns ctx with the baggage member key set to value, propagated with
+// the trace context to downstream services by InjectContext. An invalid key, or
+// baggage exceeding the W3C size limits, is logged and ctx is returned unchanged.
+func SetBaggage(ctx context.Context, key, value string) context.Context {
+	member, err := baggage.NewMemberRaw(key, value)
+	if err == nil {
+		var b baggage.Baggage
+		if b, err = baggage.FromContext(ctx).SetMember(member); err == nil {
+			return baggage.ContextWithBaggage(ctx, b)
+		}
+	}
+	logger.Log.Warn("Failed to set baggage member", zap.Error(err), zap.String("key", key))
+	return ctx
+}
+
+// GetBaggage returns the value of the baggage member key in ctx, or "" if it is not set
+func GetBaggage(ctx context.Context, key string) string {
+	return baggage.FromContext(ctx).Member(key).Value()
+}
+
+// ExtractHTTPContext returns ctx with the trace context and baggage of the incoming
+// request headers. A traceparent that only differs from a valid one by case or
+// surrounding whitespace is repaired. Any other malformed traceparent is dropped, so
+// a new trace starts, and is counted in the telemetry.propagation.invalid counter
+// and logged with its value at most once per minute.
+func (o *OpenTelemetry) ExtractHTTPContext(ctx context.Context, header http.Header) context.Context {
+	carrier := propagation.HeaderCarrier(header)
+	if value := header.Get(traceparentHeader); value != "" {
+		repaired, ok := repairTraceparent(value)
+		switch {
+		case !ok:
+			o.recordInvalidTraceparent(ctx, value, false)
+			carrier = propagation.HeaderCarrier(header.Clone())
+			carrier.Set(traceparentHeader, "")
+		case repaired != value:
+			o.recordInvalidTraceparent(ctx, value, true)
+			carrier = propagation.HeaderCarrier(header.Clone())
+			carrier.Set(traceparentHeader, repaired)
+		}
+	}
+	return textMapPropagator.Extract(ctx, carrier)
+}
+
+// repairTraceparent normalizes the case and whitespace of a traceparent value and
+// reports whether the result is valid
+func repairTraceparent(value string) (string, bool) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	m := traceparentPattern.FindStringSubmatch(value)
+	if m == nil {
+		return value, false
+	}
+	version, traceID, spanID, extra := m[1], m[2], m[3], m[5]
+	switch {
+	case version == "ff":
+		return value, false
+	case version == "00" && extra != "":
+		return value, false
+	case strings.Trim(traceID, "0") == "", strings.Trim(spanID, "0") == "":
+		return value, false
+	}
+	return value, true
+}
+
+// recordInvalidTraceparent counts a malformed traceparent and logs it, rate limited
+func (o *OpenTelemetry) recordInvalidTraceparent(ctx context.Context, value string, repaired bool) {
+	o.IncrementCounter(ctx, "telemetry.propagation.invalid", 1, attribute.Bool("repaired", repaired))
+
+	now := time.Now().UnixNano()
+	last := o.invalidTraceparentLogged.Load()
+	if now-last < int64(invalidTraceparentLogInterval) || !o.invalidTraceparentLogged.CompareAndSwap(last, now) {
+		return
+	}
+	logger.Log.Warn("Malformed traceparent header in incoming request",
+		zap.String("traceparent", value),
+		zap.Bool("repaired", repaired))
+}
@@ -0,0 +1,133 @@
+package telemetry
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestExtractHTTPContextValidatesTraceparent(t *testing.T) {
+	logs := observeLogs(t)
+	tt := newTestTelemetry(t)
+	const valid = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+	tests := []struct {
+		name        string
+		traceparent string
+		wantValid   bool
+	}{
+		{"valid", valid, true},
+		{"uppercase with whitespace", "  00-4BF92F3577B34DA6A3CE929D0E0E4736-00F067AA0BA902B7-01 ", true},
+		{"zero trace ID", "00-00000000000000000000000000000000-00f067aa0ba902b7-01", false},
+		{"forbidden version", "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", false},
+		{"truncated", "00-4bf92f3577b34da6a3ce929d0e0e4736-01", false},
+	}
+	for _, tc := range tests {
+		header := http.Header{}
+		header.Set("traceparent", tc.traceparent)
+		sc := trace.SpanContextFromContext(tt.ExtractHTTPContext(context.Background(), header))
+		if sc.IsValid() != tc.wantValid {
+			t.Errorf("%s: extracted span context valid = %v, want %v", tc.name, sc.IsValid(), tc.wantValid)
+		}
+		if tc.wantValid && sc.TraceID().String() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+			t.Errorf("%s: trace ID = %s, want the header's", tc.name, sc.TraceID())
+		}
+		if header.Get("traceparent") != tc.traceparent {
+			t.Errorf("%s: request header modified to %q", tc.name, header.Get("traceparent"))
+		}
+	}
+
+	counts := make(map[string]float64)
+	for _, p := range sumPoints(t, tt.metric(t, "telemetry.propagation.invalid")) {
+		counts[attrMap(p.Attributes.ToSlice())["repaired"]] += p.Value
+	}
+	if counts["true"] != 1 || counts["false"] != 3 {
+		t.Errorf("telemetry.propagation.invalid = %v, want 1 repaired and 3 dropped", counts)
+	}
+	if n := len(logs.FilterMessage("Malformed traceparent header in incoming request").All()); n != 1 {
+		t.Errorf("logged %d malformed traceparents, want 1 within the rate limit interval", n)
+	}
+}
+
+func TestInjectExtractContextRoundTrip(t *testing.T) {
+	tt := newTestTelemetry(t)
+	ctx, producer := tt.StartSpan(context.Background(), "produce")
+	defer tt.EndSpan(producer)
+
+	headers := propagation.MapCarrier{}
+	tt.InjectContext(ctx, headers)
+	if headers.Get("traceparent") == "" {
+		t.Fatalf("injected headers = %v, want a traceparent", headers)
+	}
+
+	extracted := trace.SpanContextFromContext(tt.ExtractContext(context.Background(), headers))
+	want := producer.SpanContext()
+	if extracted.TraceID() != want.TraceID() || extracted.SpanID() != want.SpanID() || !extracted.IsRemote() || !extracted.IsSampled() {
+		t.Errorf("extracted span context = %+v, want the remote sampled producer span %+v", extracted, want)
+	}
+
+	_, consumer := tt.StartSpan(tt.ExtractContext(context.Background(), headers), "consume")
+	tt.EndSpan(consumer)
+	if got := tt.endedSpan(t, "consume"); got.Parent().SpanID() != want.SpanID() || got.SpanContext().TraceID() != want.TraceID() {
+		t.Error("span started from the extracted context does not continue the producer's trace")
+	}
+}
+
+func TestGlobalPropagatorIsInstalled(t *testing.T) {
+	newTestTelemetry(t)
+	fields := strings.Join(otel.GetTextMapPropagator().Fields(), ",")
+	for _, field := range []string{"traceparent", "tracestate", "baggage"} {
+		if !strings.Contains(fields, field) {
+			t.Errorf("global propagator fields = %s, want %s", fields, field)
+		}
+	}
+}
+
+func TestBaggageSurvivesHTTPInjectExtract(t *testing.T) {
+	tt := newTestTelemetry(t)
+	ctx := SetBaggage(context.Background(), "tenant.id", "acme")
+	ctx = SetBaggage(ctx, "tenant.region", "eu west/1")
+	ctx = SetBaggage(ctx, "tenant.id", "globex")
+	ctx, span := tt.StartSpan(ctx, "outbound")
+	defer tt.EndSpan(span)
+
+	header := http.Header{}
+	tt.InjectContext(ctx, propagation.HeaderCarrier(header))
+	if header.Get("baggage") == "" {
+		t.Fatalf("injected headers = %v, want a baggage header", header)
+	}
+
+	extracted := tt.ExtractHTTPContext(context.Background(), header)
+	for key, want := range map[string]string{
+		"tenant.id":     "globex",
+		"tenant.region": "eu west/1",
+		"tenant.plan":   "",
+	} {
+		if got := GetBaggage(extracted, key); got != want {
+			t.Errorf("GetBaggage(%q) = %q, want %q", key, got, want)
+		}
+	}
+	if trace.SpanContextFromContext(extracted).TraceID() != span.SpanContext().TraceID() {
+		t.Error("baggage was extracted without the trace context")
+	}
+}
+
+func TestSetBaggageRejectsInvalidKey(t *testing.T) {
+	logs := observeLogs(t)
+	ctx := SetBaggage(context.Background(), "tenant.id", "acme")
+
+	if got := SetBaggage(ctx, "tenant id", "globex"); got != ctx {
+		t.Error("SetBaggage() with an invalid key returned a new context")
+	}
+	if n := logs.FilterMessage("Failed to set baggage member").Len(); n != 1 {
+		t.Errorf("logged %d baggage warnings, want 1", n)
+	}
+	if got := GetBaggage(ctx, "tenant.id"); got != "acme" {
+		t.Errorf("GetBaggage() = %q after a rejected member, want acme", got)
+	}
+}
@@ -0,0 +1,29 @@
+// pushgateway.go - Pushing metrics to a Prometheus Pushgateway for short-lived jobs
+
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/push"
+)
+
+// pushgatewayClient pushes metrics to the Pushgateway; the timeout keeps an
+// unresponsive Pushgateway from blocking Flush and Shutdown indefinitely
+var pushgatewayClient = &http.Client{Timeout: 10 * time.Second}
+
+// pushMetrics replaces the metrics of the configured job on the Pushgateway with
+// the metrics gathered from the Prometheus registry
+func (o *OpenTelemetry) pushMetrics(ctx context.Context) error {
+	err := push.New(o.config.pushgatewayURL, o.config.pushgatewayJob).
+		Gatherer(o.prometheusRegistry).
+		Client(pushgatewayClient).
+		PushContext(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to push metrics: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package telemetry
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// pushRequest is a request received by a stub Pushgateway
+type pushRequest struct {
+	method      string
+	path        string
+	contentType string
+	body        string
+}
+
+// stubPushgateway is an HTTP server recording the pushes it receives
+type stubPushgateway struct {
+	*httptest.Server
+	mu     sync.Mutex
+	pushes []pushRequest
+}
+
+// newStubPushgateway starts a stub Pushgateway, closed when the test ends
+func newStubPushgateway(t *testing.T) *stubPushgateway {
+	t.Helper()
+	g := &stubPushgateway{}
+	g.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		g.mu.Lock()
+		g.pushes = append(g.pushes, pushRequest{
+			method:      r.Method,
+			path:        r.URL.Path,
+			contentType: r.Header.Get("Content-Type"),
+			body:        string(body),
+		})
+		g.mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(g.Close)
+	return g
+}
+
+// received returns the pushes received so far
+func (g *stubPushgateway) received() []pushRequest {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]pushRequest(nil), g.pushes...)
+}
+
+func TestFlushPushesMetricsToPushgateway(t *testing.T) {
+	collector := newStubCollector(t)
+	gateway := newStubPushgateway(t)
+	tt := newTestTelemetry(t,
+		WithTraceEndpoint(collector.addr),
+		WithMetricEndpoint(collector.addr),
+		WithPushgateway(gateway.URL, "nightly-import"))
+	ctx := context.Background()
+
+	tt.IncrementCounter(ctx, "rows.imported", 3, attribute.String("table", "orders"))
+	tt.RecordHistogram(ctx, "import.duration", 12.5)
+	_ = tt.Flush(ctx)
+
+	pushes := gateway.received()
+	if len(pushes) != 1 {
+		t.Fatalf("Pushgateway received %d pushes, want 1", len(pushes))
+	}
+	push := pushes[0]
+	if push.method != http.MethodPut || push.path != "/metrics/job/nightly-import" {
+		t.Errorf("push = %s %s, want PUT /metrics/job/nightly-import", push.method, push.path)
+	}
+	families := decodePushedFamilies(t, push)
+	rows, ok := families["rows_imported_total"]
+	if !ok {
+		t.Fatalf("push payload has no rows_imported_total family, got %v", familyNames(families))
+	}
+	if rows.GetType() != dto.MetricType_COUNTER || len(rows.GetMetric()) != 1 {
+		t.Fatalf("rows_imported_total = %v, want one counter series", rows)
+	}
+	if got := rows.GetMetric()[0].GetCounter().GetValue(); got != 3 {
+		t.Errorf("rows_imported_total = %v, want 3", got)
+	}
+	if !hasLabel(rows.GetMetric()[0], "table", "orders") {
+		t.Errorf("rows_imported_total labels = %v, want table=orders", rows.GetMetric()[0].GetLabel())
+	}
+	if duration, ok := families["import_duration"]; !ok || duration.GetType() != dto.MetricType_HISTOGRAM {
+		t.Errorf("push payload has no import_duration histogram, got %v", familyNames(families))
+	}
+}
+
+// decodePushedFamilies decodes the metric families of a push by name
+func decodePushedFamilies(t *testing.T, push pushRequest) map[string]*dto.MetricFamily {
+	t.Helper()
+	dec := expfmt.NewDecoder(strings.NewReader(push.body), expfmt.ResponseFormat(http.Header{"Content-Type": {push.contentType}}))
+	families := make(map[string]*dto.MetricFamily)
+	for {
+		mf := &dto.MetricFamily{}
+		if err := dec.Decode(mf); err == io.EOF {
+			return families
+		} else if err != nil {
+			t.Fatalf("failed to decode push payload (%s): %v", push.contentType, err)
+		}
+		families[mf.GetName()] = mf
+	}
+}
+
+// familyNames returns the sorted names of families
+func familyNames(families map[string]*dto.MetricFamily) []string {
+	names := make([]string, 0, len(families))
+	for name := range families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// hasLabel reports whether m has the label name set to value
+func hasLabel(m *dto.Metric, name, value string) bool {
+	for _, l := range m.GetLabel() {
+		if l.GetName() == name && l.GetValue() == value {
+			return true
+		}
+	}
+	return false
+}
+
+func TestFlushReportsPushgatewayErrors(t *testing.T) {
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer gateway.Close()
+	collector := newStubCollector(t)
+	tt := newTestTelemetry(t,
+		WithTraceEndpoint(collector.addr),
+		WithMetricEndpoint(collector.addr),
+		WithPushgateway(gateway.URL, "nightly-import"))
+
+	tt.IncrementCounter(context.Background(), "rows.imported", 1)
+	if err := tt.Flush(context.Background()); err == nil || !strings.Contains(err.Error(), "503") {
+		t.Errorf("Flush() error = %v, want the Pushgateway status", err)
+	}
+}
@@ -0,0 +1,84 @@
+// recent_errors.go - Bounded in-process history of recorded errors
+
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ErrorRecord describes an error passed to RecordError
+type ErrorRecord struct {
+	Time    time.Time
+	Message string
+	Type    string
+	TraceID string
+}
+
+// errorRing is a fixed-capacity ring buffer of the most recent error records
+type errorRing struct {
+	mu      sync.Mutex
+	records []ErrorRecord
+	next    int
+	full    bool
+}
+
+// newErrorRing returns a ring buffer holding up to capacity records
+func newErrorRing(capacity int) *errorRing {
+	return &errorRing{records: make([]ErrorRecord, capacity)}
+}
+
+// add stores a record, overwriting the oldest one when the buffer is full
+func (r *errorRing) add(record ErrorRecord) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.records[r.next] = record
+	r.next = (r.next + 1) % len(r.records)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// snapshot returns the stored records, newest first
+func (r *errorRing) snapshot() []ErrorRecord {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n := r.next
+	if r.full {
+		n = len(r.records)
+	}
+	result := make([]ErrorRecord, 0, n)
+	for i := 1; i <= n; i++ {
+		result = append(result, r.records[(r.next-i+len(r.records))%len(r.records)])
+	}
+	return result
+}
+
+// rememberError adds err to the recent errors buffer, if enabled
+func (o *OpenTelemetry) rememberError(ctx context.Context, err error) {
+	if o.recentErrors == nil {
+		return
+	}
+	record := ErrorRecord{
+		Time:    time.Now(),
+		Message: err.Error(),
+		Type:    fmt.Sprintf("%T", err),
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.HasTraceID() {
+		record.TraceID = sc.TraceID().String()
+	}
+	o.recentErrors.add(record)
+}
+
+// RecentErrors returns the most recent errors passed to RecordError, newest first.
+// It returns nil unless WithRecentErrors is enabled.
+func (o *OpenTelemetry) RecentErrors() []ErrorRecord {
+	if o.recentErrors == nil {
+		return nil
+	}
+	return o.recentErrors.snapshot()
+}
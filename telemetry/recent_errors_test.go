@@ -0,0 +1,52 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"testing"
+)
+
+func TestRecentErrorsNewestFirstWithTraceIDs(t *testing.T) {
+	tt := newTestTelemetry(t, WithRecentErrors(3))
+
+	var traceIDs []string
+	for i := 0; i < 4; i++ {
+		ctx, span := tt.StartSpan(context.Background(), "operation")
+		traceIDs = append(traceIDs, span.SpanContext().TraceID().String())
+		tt.RecordError(ctx, fmt.Errorf("failure %d", i))
+		tt.EndSpan(span)
+	}
+	tt.RecordError(context.Background(), &fs.PathError{Op: "open", Path: "/missing", Err: fs.ErrNotExist})
+
+	records := tt.RecentErrors()
+	if len(records) != 3 {
+		t.Fatalf("got %d records, want the last 3: %+v", len(records), records)
+	}
+	want := []struct{ message, typ, traceID string }{
+		{"open /missing: file does not exist", "*fs.PathError", ""},
+		{"failure 3", "*errors.errorString", traceIDs[3]},
+		{"failure 2", "*errors.errorString", traceIDs[2]},
+	}
+	for i, w := range want {
+		r := records[i]
+		if r.Message != w.message || r.Type != w.typ || r.TraceID != w.traceID {
+			t.Errorf("record %d = %+v, want message %q, type %q, trace ID %q", i, r, w.message, w.typ, w.traceID)
+		}
+		if r.Time.IsZero() {
+			t.Errorf("record %d has no timestamp", i)
+		}
+	}
+	if records[0].Time.Before(records[1].Time) {
+		t.Errorf("records are not newest first: %v after %v", records[1].Time, records[0].Time)
+	}
+}
+
+func TestRecentErrorsDisabledByDefault(t *testing.T) {
+	tt := newTestTelemetry(t)
+	tt.RecordError(context.Background(), errors.New("failure"))
+	if records := tt.RecentErrors(); records != nil {
+		t.Errorf("RecentErrors() = %+v, want nil without WithRecentErrors", records)
+	}
+}
@@ -0,0 +1,122 @@
+// resource.go - Construction of the resource shared by the tracer and meter providers
+
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"os"
+
+	"github.com/sadco-io/sad-go-logger/logger"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.uber.org/zap"
+)
+
+// defaultServiceName is used when no service name is configured by any source
+const defaultServiceName = "unknown-service"
+
+// newResource builds the telemetry resource and returns it with the resolved service
+// name. Sources take precedence in this order: the WithServiceName option, the
+// serviceName argument, OTEL_SERVICE_NAME/OTEL_RESOURCE_ATTRIBUTES, then detectors.
+// Other attributes come from WithResourceAttributes, OTEL_SERVICE_VERSION,
+// OTEL_RESOURCE_ATTRIBUTES, then the Kubernetes and SDK detectors, in order of
+// precedence. Since resource attributes are a set, each key, including service.name,
+// appears once. A malformed OTEL_RESOURCE_ATTRIBUTES is logged and the attributes
+// that could be parsed are kept. A resource given with WithResource replaces the
+// environment and detectors and keeps its own schema URL; otherwise the resource
+// carries the WithSchemaURL schema URL.
+func newResource(ctx context.Context, serviceName string, cfg otelConfig) (*resource.Resource, string, error) {
+	if cfg.serviceName != "" {
+		serviceName = cfg.serviceName
+	}
+	if cfg.resource != nil {
+		res, err := resource.Merge(cfg.resource, resource.NewSchemaless(cfg.resourceAttributes...))
+		if err != nil {
+			return nil, "", err
+		}
+		return mergeServiceName(res, serviceName)
+	}
+
+	// Later options override earlier ones, so they are listed from lowest to
+	// highest precedence.
+	opts := []resource.Option{resource.WithTelemetrySDK()}
+	if cfg.kubernetesDetector {
+		opts = append(opts, resource.WithDetectors(kubernetesDetector{}))
+	}
+	opts = append(opts, resource.WithFromEnv())
+	if version := os.Getenv("OTEL_SERVICE_VERSION"); version != "" {
+		opts = append(opts, resource.WithAttributes(semconv.ServiceVersionKey.String(version)))
+	}
+	if len(cfg.resourceAttributes) > 0 {
+		opts = append(opts, resource.WithAttributes(cfg.resourceAttributes...))
+	}
+	if serviceName != "" {
+		opts = append(opts, resource.WithAttributes(semconv.ServiceNameKey.String(serviceName)))
+	}
+	res, err := resource.New(ctx, opts...)
+	if errors.Is(err, resource.ErrPartialResource) {
+		logger.Log.Warn("Resource detection was incomplete, using the attributes detected", zap.Error(err))
+	} else if err != nil {
+		return nil, "", err
+	}
+	// Detectors stamp the schema URL of the semconv version they were built with,
+	// which need not match the one used for this package's attributes.
+	res = resource.NewWithAttributes(cfg.schemaURL, res.Attributes()...)
+
+	return mergeServiceName(res, "")
+}
+
+// mergeServiceName sets service.name on res to serviceName if it is not empty,
+// keeps the name of res otherwise, and falls back to defaultServiceName
+func mergeServiceName(res *resource.Resource, serviceName string) (*resource.Resource, string, error) {
+	if serviceName == "" {
+		if name, ok := res.Set().Value(semconv.ServiceNameKey); ok && name.AsString() != "" {
+			return res, name.AsString(), nil
+		}
+		serviceName = defaultServiceName
+	}
+	res, err := resource.Merge(res, resource.NewSchemaless(semconv.ServiceNameKey.String(serviceName)))
+	if err != nil {
+		return nil, "", err
+	}
+	return res, serviceName, nil
+}
+
+// signalResource returns the resource and service name of a single signal: res and
+// serviceName, or res with service.name replaced by override if it is not empty
+func signalResource(res *resource.Resource, serviceName, override string) (*resource.Resource, string, error) {
+	if override == "" {
+		return res, serviceName, nil
+	}
+	return mergeServiceName(res, override)
+}
+
+// kubernetesDetector detects the Kubernetes pod attributes exposed as environment
+// variables through the downward API
+type kubernetesDetector struct{}
+
+// kubernetesEnvAttributes maps the conventional downward API variables to the
+// resource attributes they set
+var kubernetesEnvAttributes = []struct {
+	env string
+	key attribute.Key
+}{
+	{"POD_NAME", semconv.K8SPodNameKey},
+	{"POD_NAMESPACE", semconv.K8SNamespaceNameKey},
+	{"NODE_NAME", semconv.K8SNodeNameKey},
+	{"POD_UID", semconv.K8SPodUIDKey},
+}
+
+// Detect returns a resource with the attributes of the variables that are set
+func (kubernetesDetector) Detect(context.Context) (*resource.Resource, error) {
+	var attrs []attribute.KeyValue
+	for _, a := range kubernetesEnvAttributes {
+		if value := os.Getenv(a.env); value != "" {
+			attrs = append(attrs, a.key.String(value))
+		}
+	}
+	// Schemaless, so that it merges with the resources of other detectors
+	return resource.NewSchemaless(attrs...), nil
+}
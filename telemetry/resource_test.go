@@ -0,0 +1,278 @@
+package telemetry
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+)
+
+func TestNewResourceServiceNamePrecedence(t *testing.T) {
+	tests := []struct {
+		name     string
+		option   string
+		argument string
+		envName  string
+		envAttrs string
+		want     string
+	}{
+		{"option wins", "option", "argument", "env", "service.name=attrs", "option"},
+		{"argument beats environment", "", "argument", "env", "service.name=attrs", "argument"},
+		{"OTEL_SERVICE_NAME beats OTEL_RESOURCE_ATTRIBUTES", "", "", "env", "service.name=attrs", "env"},
+		{"OTEL_RESOURCE_ATTRIBUTES", "", "", "", "service.name=attrs", "attrs"},
+		{"default", "", "", "", "", defaultServiceName},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("OTEL_SERVICE_NAME", tc.envName)
+			t.Setenv("OTEL_RESOURCE_ATTRIBUTES", tc.envAttrs)
+
+			res, serviceName, err := newResource(context.Background(), tc.argument, otelConfig{serviceName: tc.option})
+			if err != nil {
+				t.Fatalf("newResource() error = %v", err)
+			}
+			if serviceName != tc.want {
+				t.Errorf("service name = %q, want %q", serviceName, tc.want)
+			}
+			var names []string
+			for _, kv := range res.Attributes() {
+				if kv.Key == semconv.ServiceNameKey {
+					names = append(names, kv.Value.AsString())
+				}
+			}
+			if len(names) != 1 || names[0] != tc.want {
+				t.Errorf("service.name attributes = %q, want only %q", names, tc.want)
+			}
+		})
+	}
+}
+
+func TestNewResourceAttributePrecedence(t *testing.T) {
+	t.Setenv("POD_NAME", "detected-pod")
+	t.Setenv("POD_NAMESPACE", "detected-namespace")
+	t.Setenv("OTEL_SERVICE_VERSION", "1.2.3")
+	t.Setenv("OTEL_RESOURCE_ATTRIBUTES", "k8s.namespace.name=env-namespace,deployment.environment=staging,service.version=0.0.1")
+
+	res, _, err := newResource(context.Background(), "checkout", otelConfig{
+		kubernetesDetector: true,
+		resourceAttributes: []attribute.KeyValue{attribute.String("deployment.environment", "production")},
+	})
+	if err != nil {
+		t.Fatalf("newResource() error = %v", err)
+	}
+
+	attrs := attrMap(res.Attributes())
+	for key, want := range map[string]string{
+		"k8s.pod.name":           "detected-pod",
+		"k8s.namespace.name":     "env-namespace",
+		"service.version":        "1.2.3",
+		"deployment.environment": "production",
+		"service.name":           "checkout",
+	} {
+		if attrs[key] != want {
+			t.Errorf("%s = %q, want %q", key, attrs[key], want)
+		}
+	}
+}
+
+func TestMalformedResourceAttributesKeepPartialResource(t *testing.T) {
+	logs := observeLogs(t)
+	t.Setenv("OTEL_RESOURCE_ATTRIBUTES", "team=payments,malformed")
+
+	tt := newTestTelemetry(t)
+	if n := logs.FilterMessage("Resource detection was incomplete, using the attributes detected").Len(); n != 1 {
+		t.Errorf("logged %d partial resource warnings, want 1", n)
+	}
+	tt.IncrementCounter(context.Background(), "operations", 1)
+	if team := attrMap(tt.collect(t).Resource.Attributes())["team"]; team != "payments" {
+		t.Errorf("team = %q, want the well-formed attribute kept", team)
+	}
+}
+
+func TestResourceIsSetOnSpansAndMetrics(t *testing.T) {
+	t.Setenv("OTEL_RESOURCE_ATTRIBUTES", "service.name=from-env")
+	tt := newTestTelemetry(t)
+
+	_, span := tt.StartSpan(context.Background(), "operation")
+	tt.EndSpan(span)
+	tt.IncrementCounter(context.Background(), "operations", 1)
+
+	if name := attrMap(tt.endedSpan(t, "operation").Resource().Attributes())["service.name"]; name != "telemetry-test" {
+		t.Errorf("span service.name = %q, want the WithServiceName value", name)
+	}
+	if name := attrMap(tt.collect(t).Resource.Attributes())["service.name"]; name != "telemetry-test" {
+		t.Errorf("metric service.name = %q, want the WithServiceName value", name)
+	}
+}
+
+func TestWithResourceIsUsedByBothProviders(t *testing.T) {
+	t.Setenv("OTEL_RESOURCE_ATTRIBUTES", "team=from-env")
+	custom := resource.NewWithAttributes("https://opentelemetry.io/schemas/1.17.0",
+		attribute.String("team", "payments"),
+		attribute.String("service.name", "custom-name"),
+	)
+	tt := newTestTelemetry(t, WithResource(custom), WithResourceAttributes(attribute.String("region", "eu-west-1")))
+
+	_, span := tt.StartSpan(context.Background(), "operation")
+	tt.EndSpan(span)
+	tt.IncrementCounter(context.Background(), "operations", 1)
+
+	spanRes := tt.endedSpan(t, "operation").Resource()
+	metricRes := tt.collect(t).Resource
+	for signal, res := range map[string]*resource.Resource{"span": spanRes, "metric": metricRes} {
+		attrs := attrMap(res.Attributes())
+		// The configured service name is merged into the custom resource
+		for key, want := range map[string]string{"team": "payments", "region": "eu-west-1", "service.name": "telemetry-test"} {
+			if attrs[key] != want {
+				t.Errorf("%s resource %s = %q, want %q", signal, key, attrs[key], want)
+			}
+		}
+		if res.SchemaURL() != custom.SchemaURL() {
+			t.Errorf("%s resource schema URL = %q, want the custom resource's %q", signal, res.SchemaURL(), custom.SchemaURL())
+		}
+	}
+}
+
+func TestSchemaURLIsSetOnResourceAndScopes(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+		want string
+	}{
+		{"default", nil, semconv.SchemaURL},
+		{"WithSchemaURL", []Option{WithSchemaURL("https://opentelemetry.io/schemas/1.20.0")}, "https://opentelemetry.io/schemas/1.20.0"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tt := newTestTelemetry(t, tc.opts...)
+			_, span := tt.StartSpan(context.Background(), "operation")
+			tt.EndSpan(span)
+			tt.IncrementCounter(context.Background(), "operations", 1)
+
+			ended := tt.endedSpan(t, "operation")
+			if got := ended.Resource().SchemaURL(); got != tc.want {
+				t.Errorf("span resource schema URL = %q, want %q", got, tc.want)
+			}
+			if got := ended.InstrumentationScope().SchemaURL; got != tc.want {
+				t.Errorf("tracer schema URL = %q, want %q", got, tc.want)
+			}
+			rm := tt.collect(t)
+			if got := rm.Resource.SchemaURL(); got != tc.want {
+				t.Errorf("metric resource schema URL = %q, want %q", got, tc.want)
+			}
+			for _, sm := range rm.ScopeMetrics {
+				if got := sm.Scope.SchemaURL; got != tc.want {
+					t.Errorf("meter %s schema URL = %q, want %q", sm.Scope.Name, got, tc.want)
+				}
+			}
+		})
+	}
+}
+
+func TestPerSignalServiceNames(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        []Option
+		wantTrace   string
+		wantMetrics string
+	}{
+		{"no overrides", nil, "telemetry-test", "telemetry-test"},
+		{"trace override", []Option{WithTraceServiceName("checkout-api")}, "checkout-api", "telemetry-test"},
+		{"metric override", []Option{WithMetricServiceName("checkout-worker")}, "telemetry-test", "checkout-worker"},
+		{"both overrides", []Option{WithTraceServiceName("checkout-api"), WithMetricServiceName("checkout-worker")}, "checkout-api", "checkout-worker"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tt := newTestTelemetry(t, tc.opts...)
+			_, span := tt.StartSpan(context.Background(), "operation")
+			tt.EndSpan(span)
+			tt.IncrementCounter(context.Background(), "operations", 1)
+
+			ended := tt.endedSpan(t, "operation")
+			if got := attrMap(ended.Resource().Attributes())["service.name"]; got != tc.wantTrace {
+				t.Errorf("span service.name = %q, want %q", got, tc.wantTrace)
+			}
+			if got := ended.InstrumentationScope().Name; got != tc.wantTrace {
+				t.Errorf("tracer name = %q, want %q", got, tc.wantTrace)
+			}
+			rm := tt.collect(t)
+			if got := attrMap(rm.Resource.Attributes())["service.name"]; got != tc.wantMetrics {
+				t.Errorf("metric service.name = %q, want %q", got, tc.wantMetrics)
+			}
+			if got := attrMap(ended.Resource().Attributes())["telemetry.sdk.language"]; got != "go" {
+				t.Errorf("span resource telemetry.sdk.language = %q, want the shared attributes kept", got)
+			}
+		})
+	}
+}
+
+func TestWithResourceAttributesAccumulateOnBothSignals(t *testing.T) {
+	t.Setenv("OTEL_SERVICE_VERSION", "2.4.0")
+	tt := newTestTelemetry(t,
+		WithResourceAttributes(attribute.String("deployment.environment", "production")),
+		WithResourceAttributes(attribute.String("service.instance.id", "pod-7"), attribute.String("service.name", "ignored")))
+
+	_, span := tt.StartSpan(context.Background(), "operation")
+	tt.EndSpan(span)
+	tt.IncrementCounter(context.Background(), "operations", 1)
+
+	for signal, attrs := range map[string]map[string]string{
+		"span":   attrMap(tt.endedSpan(t, "operation").Resource().Attributes()),
+		"metric": attrMap(tt.collect(t).Resource.Attributes()),
+	} {
+		for key, want := range map[string]string{
+			"deployment.environment": "production",
+			"service.instance.id":    "pod-7",
+			"service.version":        "2.4.0",
+			"service.name":           "telemetry-test",
+		} {
+			if attrs[key] != want {
+				t.Errorf("%s resource %s = %q, want %q", signal, key, attrs[key], want)
+			}
+		}
+	}
+}
+
+func TestKubernetesDetectorReadsDownwardAPIVariables(t *testing.T) {
+	t.Setenv("POD_NAME", "checkout-7d9f")
+	t.Setenv("POD_NAMESPACE", "shop")
+	t.Setenv("NODE_NAME", "")
+	t.Setenv("POD_UID", "0b6c-42")
+
+	res, err := kubernetesDetector{}.Detect(context.Background())
+	if err != nil {
+		t.Fatalf("Detect() error = %v", err)
+	}
+	attrs := attrMap(res.Attributes())
+	want := map[string]string{
+		"k8s.pod.name":       "checkout-7d9f",
+		"k8s.namespace.name": "shop",
+		"k8s.pod.uid":        "0b6c-42",
+	}
+	if len(attrs) != len(want) {
+		t.Errorf("detected attributes = %v, want only the set variables", attrs)
+	}
+	for key, value := range want {
+		if attrs[key] != value {
+			t.Errorf("%s = %q, want %q", key, attrs[key], value)
+		}
+	}
+	if res.SchemaURL() != "" {
+		t.Errorf("detected resource schema URL = %q, want a schemaless resource", res.SchemaURL())
+	}
+}
+
+func TestKubernetesDetectorOptOut(t *testing.T) {
+	t.Setenv("POD_NAME", "checkout-7d9f")
+	for enabled, want := range map[bool]string{true: "checkout-7d9f", false: ""} {
+		res, _, err := newResource(context.Background(), "checkout", otelConfig{kubernetesDetector: enabled})
+		if err != nil {
+			t.Fatalf("newResource() error = %v", err)
+		}
+		if got := attrMap(res.Attributes())["k8s.pod.name"]; got != want {
+			t.Errorf("with the detector enabled = %v, k8s.pod.name = %q, want %q", enabled, got, want)
+		}
+	}
+}
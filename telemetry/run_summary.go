@@ -0,0 +1,78 @@
+// run_summary.go - Run totals of short-lived jobs emitted as metrics on flush and shutdown
+
+package telemetry
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sort"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// SummaryCollector accumulates named totals over a run, such as items processed
+// or errors seen. It is safe for concurrent use.
+type SummaryCollector struct {
+	mu     sync.Mutex
+	totals map[string]float64
+}
+
+// NewSummaryCollector returns an empty SummaryCollector
+func NewSummaryCollector() *SummaryCollector {
+	return &SummaryCollector{totals: make(map[string]float64)}
+}
+
+// Add adds delta to the total of key
+func (c *SummaryCollector) Add(key string, delta float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.totals[key] += delta
+}
+
+// Totals returns a copy of the accumulated totals
+func (c *SummaryCollector) Totals() map[string]float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	totals := make(map[string]float64, len(c.totals))
+	for key, total := range c.totals {
+		totals[key] = total
+	}
+	return totals
+}
+
+// AddToSummary adds delta to the run total of key. On Flush and Shutdown, every
+// total is recorded as a gauge named key, alongside the run.duration gauge in
+// seconds, with a run.id attribute identifying the run.
+func (o *OpenTelemetry) AddToSummary(key string, delta float64) {
+	o.runSummary.Add(key, delta)
+}
+
+// emitRunSummary records the run totals as gauges, if any were added
+func (o *OpenTelemetry) emitRunSummary(ctx context.Context) {
+	totals := o.runSummary.Totals()
+	if len(totals) == 0 {
+		return
+	}
+	runID := attribute.String("run.id", o.config.runID)
+	keys := make([]string, 0, len(totals))
+	for key := range totals {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		o.RecordGauge(ctx, key, totals[key], runID)
+	}
+	o.RecordGauge(ctx, "run.duration", time.Since(o.startedAt).Seconds(), runID)
+}
+
+// newRunID returns a random identifier for a run
+func newRunID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,65 @@
+package telemetry
+
+import (
+	"context"
+	"testing"
+)
+
+func TestRunSummaryIsRecordedOnFlush(t *testing.T) {
+	collector := newStubCollector(t)
+	tt := newTestTelemetry(t, WithTraceEndpoint(collector.addr), WithMetricEndpoint(collector.addr), WithRunID("nightly-42"))
+	ctx := context.Background()
+
+	if err := tt.Flush(ctx); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	if _, ok := findMetric(tt.collect(t), "run.duration"); ok {
+		t.Error("run.duration recorded without summary totals")
+	}
+
+	tt.AddToSummary("items.processed", 40)
+	tt.AddToSummary("items.processed", 2)
+	tt.AddToSummary("items.failed", 1)
+	if err := tt.Flush(ctx); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	for name, want := range map[string]float64{"items.processed": 42, "items.failed": 1} {
+		points := gaugePoints(t, tt.metric(t, name))
+		if len(points) != 1 || points[0].Value != want {
+			t.Errorf("%s = %+v, want a single point with %v", name, points, want)
+			continue
+		}
+		if id := attrMap(points[0].Attributes.ToSlice())["run.id"]; id != "nightly-42" {
+			t.Errorf("%s run.id = %q, want the WithRunID value", name, id)
+		}
+	}
+	if points := gaugePoints(t, tt.metric(t, "run.duration")); len(points) != 1 || points[0].Value <= 0 {
+		t.Errorf("run.duration = %+v, want the positive run duration", points)
+	}
+}
+
+func TestRunSummaryIsExportedOnShutdown(t *testing.T) {
+	collector := newStubCollector(t)
+	tt := newTestTelemetry(t, WithTraceEndpoint(collector.addr), WithMetricEndpoint(collector.addr))
+
+	tt.AddToSummary("rows.imported", 1250)
+	if err := tt.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+
+	for _, name := range []string{"rows.imported", "run.duration"} {
+		m := collector.receivedMetric(name)
+		if m == nil || len(m.GetGauge().GetDataPoints()) != 1 {
+			t.Errorf("collector received %s = %v, want a gauge with one data point", name, m)
+			continue
+		}
+		dp := m.GetGauge().GetDataPoints()[0]
+		if name == "rows.imported" && dp.GetAsDouble() != 1250 {
+			t.Errorf("rows.imported = %v, want 1250", dp.GetAsDouble())
+		}
+		attrs := dp.GetAttributes()
+		if len(attrs) != 1 || attrs[0].GetKey() != "run.id" || attrs[0].GetValue().GetStringValue() == "" {
+			t.Errorf("%s attributes = %v, want the generated run.id", name, attrs)
+		}
+	}
+}
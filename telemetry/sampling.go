@@ -0,0 +1,333 @@
+// sampling.go - Trace sampling configuration and context-scoped sampling decisions
+
+package telemetry
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/sadco-io/sad-go-logger/logger"
+	"go.opentelemetry.io/otel/baggage"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// DefaultSamplingPriorityHeader is the request header read for a client-requested sampling priority
+const DefaultSamplingPriorityHeader = "X-Sampling-Priority"
+
+// SamplingPriority is a caller-requested sampling priority carried in the context
+type SamplingPriority int
+
+const (
+	// SamplingPriorityLow asks for new traces to be dropped
+	SamplingPriorityLow SamplingPriority = iota - 1
+	// SamplingPriorityNormal leaves the decision to the configured sampler
+	SamplingPriorityNormal
+	// SamplingPriorityHigh forces the trace to be sampled
+	SamplingPriorityHigh
+)
+
+type samplingPriorityKey struct{}
+
+// WithSamplingPriority returns a context carrying the given sampling priority,
+// consulted when spans are started from it
+func WithSamplingPriority(ctx context.Context, priority SamplingPriority) context.Context {
+	return context.WithValue(ctx, samplingPriorityKey{}, priority)
+}
+
+// SamplingPriorityFromContext returns the sampling priority carried in ctx, or
+// SamplingPriorityNormal if none was set
+func SamplingPriorityFromContext(ctx context.Context) SamplingPriority {
+	if priority, ok := ctx.Value(samplingPriorityKey{}).(SamplingPriority); ok {
+		return priority
+	}
+	return SamplingPriorityNormal
+}
+
+// ParseSamplingPriority parses a sampling priority header value. It accepts
+// "high", "normal" and "low", or an integer where values > 0 are high and
+// values <= 0 are low.
+func ParseSamplingPriority(value string) (SamplingPriority, bool) {
+	value = strings.TrimSpace(value)
+	switch strings.ToLower(value) {
+	case "":
+		return SamplingPriorityNormal, false
+	case "high":
+		return SamplingPriorityHigh, true
+	case "normal":
+		return SamplingPriorityNormal, true
+	case "low":
+		return SamplingPriorityLow, true
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return SamplingPriorityNormal, false
+	}
+	if n > 0 {
+		return SamplingPriorityHigh, true
+	}
+	return SamplingPriorityLow, true
+}
+
+// ContextWithHeaderSamplingPriority applies the sampling priority requested in
+// the configured sampling priority header, if any, to ctx
+func (o *OpenTelemetry) ContextWithHeaderSamplingPriority(ctx context.Context, header http.Header) context.Context {
+	priority, ok := ParseSamplingPriority(header.Get(o.config.samplingPriorityHeader))
+	if !ok {
+		return ctx
+	}
+	return WithSamplingPriority(ctx, priority)
+}
+
+// defaultSamplerRatio is the ratio of ratio-based environment samplers whose
+// OTEL_TRACES_SAMPLER_ARG is missing or invalid
+const defaultSamplerRatio = 1.0
+
+// parseEnvSampler returns the sampler selected by OTEL_TRACES_SAMPLER and
+// OTEL_TRACES_SAMPLER_ARG values: always_on, always_off, traceidratio, and their
+// parentbased_ variants. An unknown sampler is logged and reported as not ok; an
+// invalid ratio is logged and replaced by defaultSamplerRatio.
+func parseEnvSampler(name, arg string) (sdktrace.Sampler, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	parentBased := strings.HasPrefix(name, "parentbased_")
+
+	var root sdktrace.Sampler
+	switch strings.TrimPrefix(name, "parentbased_") {
+	case "always_on":
+		root = sdktrace.AlwaysSample()
+	case "always_off":
+		root = sdktrace.NeverSample()
+	case "traceidratio":
+		root = sdktrace.TraceIDRatioBased(parseSamplerRatio(arg))
+	default:
+		logger.Log.Warn("Unknown OTEL_TRACES_SAMPLER, using the default sampler", zap.String("sampler", name))
+		return nil, false
+	}
+	if parentBased {
+		return sdktrace.ParentBased(root), true
+	}
+	return root, true
+}
+
+// parseSamplerRatio parses a sampling ratio in [0, 1], falling back to defaultSamplerRatio
+func parseSamplerRatio(arg string) float64 {
+	ratio, err := strconv.ParseFloat(strings.TrimSpace(arg), 64)
+	if err != nil || ratio < 0 || ratio > 1 {
+		logger.Log.Warn("Invalid OTEL_TRACES_SAMPLER_ARG, using the default ratio",
+			zap.String("arg", arg),
+			zap.Float64("ratio", defaultSamplerRatio))
+		return defaultSamplerRatio
+	}
+	return ratio
+}
+
+// prioritySampler honors the sampling priority carried in the parent context
+// and delegates to the wrapped sampler otherwise
+type prioritySampler struct {
+	base         sdktrace.Sampler
+	strictParent bool
+}
+
+// newPrioritySampler wraps base so that context-scoped sampling priorities take
+// precedence, except for spans with a remote parent when strictParent is set
+func newPrioritySampler(base sdktrace.Sampler, strictParent bool) sdktrace.Sampler {
+	return prioritySampler{base: base, strictParent: strictParent}
+}
+
+// ShouldSample force-samples high priority contexts, drops new traces for low
+// priority contexts, and otherwise defers to the wrapped sampler. With a strict
+// parent, the wrapped sampler always decides for spans with a remote parent.
+func (s prioritySampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	psc := trace.SpanContextFromContext(p.ParentContext)
+	if s.strictParent && psc.IsRemote() {
+		return s.base.ShouldSample(p)
+	}
+	switch SamplingPriorityFromContext(p.ParentContext) {
+	case SamplingPriorityHigh:
+		return sdktrace.SamplingResult{Decision: sdktrace.RecordAndSample, Tracestate: psc.TraceState()}
+	case SamplingPriorityLow:
+		if !psc.IsValid() {
+			return sdktrace.SamplingResult{Decision: sdktrace.Drop, Tracestate: psc.TraceState()}
+		}
+	}
+	return s.base.ShouldSample(p)
+}
+
+// Description returns a description of the sampler
+func (s prioritySampler) Description() string {
+	return "PrioritySampler{" + s.base.Description() + "}"
+}
+
+// parentStrictSampler samples root spans at a ratio and strictly follows the
+// sampling decision of any parent
+type parentStrictSampler struct {
+	root sdktrace.Sampler
+}
+
+// ParentStrictSampler returns a sampler that samples root spans at the given ratio
+// and honors the decision of a parent span, in particular a remote one, even when
+// it conflicts with local rules such as sampling priorities
+func ParentStrictSampler(ratio float64) sdktrace.Sampler {
+	return parentStrictSampler{root: sdktrace.TraceIDRatioBased(ratio)}
+}
+
+// ShouldSample follows the parent's sampled flag, or the root sampler without a parent
+func (s parentStrictSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	psc := trace.SpanContextFromContext(p.ParentContext)
+	if !psc.IsValid() {
+		return s.root.ShouldSample(p)
+	}
+	if psc.IsSampled() {
+		return sdktrace.SamplingResult{Decision: sdktrace.RecordAndSample, Tracestate: psc.TraceState()}
+	}
+	return sdktrace.SamplingResult{Decision: sdktrace.Drop, Tracestate: psc.TraceState()}
+}
+
+// Description returns a description of the sampler
+func (s parentStrictSampler) Description() string {
+	return "ParentStrictSampler{root:" + s.root.Description() + "}"
+}
+
+// deterministicSampler samples trace IDs whose hash, salted with a seed, falls
+// below a threshold
+type deterministicSampler struct {
+	seed      int64
+	ratio     float64
+	threshold uint64
+}
+
+// DeterministicSampler returns a sampler that samples a ratio of traces chosen by a
+// hash of the trace ID and seed, so that the same traces are sampled across runs
+// and instances using the same seed, e.g. to compare load test runs. Unlike
+// TraceIDRatioBased, a different seed selects a different subset of traces.
+func DeterministicSampler(seed int64, ratio float64) sdktrace.Sampler {
+	s := deterministicSampler{seed: seed, ratio: ratio}
+	switch {
+	case ratio >= 1:
+		s.threshold = math.MaxUint64
+	case ratio > 0:
+		s.threshold = uint64(ratio * math.MaxUint64)
+	}
+	return s
+}
+
+// ShouldSample samples the trace if the salted hash of its ID is below the threshold
+func (s deterministicSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	psc := trace.SpanContextFromContext(p.ParentContext)
+	h := fnv.New64a()
+	var seed [8]byte
+	binary.BigEndian.PutUint64(seed[:], uint64(s.seed))
+	h.Write(seed[:])
+	h.Write(p.TraceID[:])
+	if s.threshold == math.MaxUint64 || h.Sum64() < s.threshold {
+		return sdktrace.SamplingResult{Decision: sdktrace.RecordAndSample, Tracestate: psc.TraceState()}
+	}
+	return sdktrace.SamplingResult{Decision: sdktrace.Drop, Tracestate: psc.TraceState()}
+}
+
+// Description returns a description of the sampler
+func (s deterministicSampler) Description() string {
+	return fmt.Sprintf("DeterministicSampler{seed:%d,ratio:%g}", s.seed, s.ratio)
+}
+
+// BaggageKeyValue identifies a baggage member by key and value
+type BaggageKeyValue struct {
+	Key   string
+	Value string
+}
+
+// baggageSampler samples at a ratio chosen from the baggage in the parent context
+type baggageSampler struct {
+	base  sdktrace.Sampler
+	rules map[BaggageKeyValue]sdktrace.Sampler
+	ratio map[BaggageKeyValue]float64
+}
+
+// BaggageSampler returns a sampler that samples at baseRatio unless the parent
+// context carries baggage matching one of rules, in which case the highest
+// matching ratio is used
+func BaggageSampler(baseRatio float64, rules map[BaggageKeyValue]float64) sdktrace.Sampler {
+	s := baggageSampler{
+		base:  sdktrace.TraceIDRatioBased(baseRatio),
+		rules: make(map[BaggageKeyValue]sdktrace.Sampler, len(rules)),
+		ratio: make(map[BaggageKeyValue]float64, len(rules)),
+	}
+	for kv, ratio := range rules {
+		s.rules[kv] = sdktrace.TraceIDRatioBased(ratio)
+		s.ratio[kv] = ratio
+	}
+	return s
+}
+
+// ShouldSample applies the ratio of the highest matching baggage rule
+func (s baggageSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	sampler := s.base
+	best := -1.0
+	for _, member := range baggage.FromContext(p.ParentContext).Members() {
+		kv := BaggageKeyValue{Key: member.Key(), Value: member.Value()}
+		if ratio, ok := s.ratio[kv]; ok && ratio > best {
+			sampler, best = s.rules[kv], ratio
+		}
+	}
+	return sampler.ShouldSample(p)
+}
+
+// Description returns a description of the sampler
+func (s baggageSampler) Description() string {
+	return "BaggageSampler{base:" + s.base.Description() + "}"
+}
+
+// routeSampler samples root spans carrying an http.route start attribute at the
+// ratio configured for that route, and defers to the wrapped sampler otherwise
+type routeSampler struct {
+	base   sdktrace.Sampler
+	routes map[string]sdktrace.Sampler
+}
+
+// samplerChain wraps sampler with the per-route ratios, if any, and reports whether
+// sampler strictly follows the decision of a parent
+func samplerChain(sampler sdktrace.Sampler, routes map[string]float64) (sdktrace.Sampler, bool) {
+	_, strictParent := sampler.(parentStrictSampler)
+	if len(routes) > 0 {
+		sampler = newRouteSampler(sampler, routes)
+	}
+	return sampler, strictParent
+}
+
+// newRouteSampler wraps base with per-route sampling ratios keyed by route template
+func newRouteSampler(base sdktrace.Sampler, ratios map[string]float64) sdktrace.Sampler {
+	s := routeSampler{base: base, routes: make(map[string]sdktrace.Sampler, len(ratios))}
+	for route, ratio := range ratios {
+		s.routes[route] = sdktrace.TraceIDRatioBased(ratio)
+	}
+	return s
+}
+
+// ShouldSample applies the ratio of the span's route to root spans
+func (s routeSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	if !trace.SpanContextFromContext(p.ParentContext).IsValid() {
+		for _, kv := range p.Attributes {
+			if kv.Key != semconv.HTTPRouteKey {
+				continue
+			}
+			if sampler, ok := s.routes[kv.Value.AsString()]; ok {
+				return sampler.ShouldSample(p)
+			}
+			break
+		}
+	}
+	return s.base.ShouldSample(p)
+}
+
+// Description returns a description of the sampler
+func (s routeSampler) Description() string {
+	return "RouteSampler{base:" + s.base.Description() + "}"
+}
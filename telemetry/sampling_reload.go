@@ -0,0 +1,169 @@
+// sampling_reload.go - Live reloading of the sampling configuration from a file
+
+package telemetry
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/sadco-io/sad-go-logger/logger"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.uber.org/zap"
+)
+
+// errSamplingReloadDisabled is returned when watching a sampling configuration
+// without tracing enabled
+var errSamplingReloadDisabled = errors.New("sampling configuration requires tracing to be enabled")
+
+// switchableSampler delegates to a sampler that can be replaced while spans are started
+type switchableSampler struct {
+	current atomic.Pointer[sdktrace.Sampler]
+}
+
+// newSwitchableSampler returns a switchableSampler initially delegating to sampler
+func newSwitchableSampler(sampler sdktrace.Sampler) *switchableSampler {
+	s := &switchableSampler{}
+	s.current.Store(&sampler)
+	return s
+}
+
+// set replaces the sampler used for spans started from now on
+func (s *switchableSampler) set(sampler sdktrace.Sampler) {
+	s.current.Store(&sampler)
+}
+
+// ShouldSample delegates to the current sampler
+func (s *switchableSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	return (*s.current.Load()).ShouldSample(p)
+}
+
+// Description returns the description of the current sampler
+func (s *switchableSampler) Description() string {
+	return (*s.current.Load()).Description()
+}
+
+// samplingFileConfig is the JSON format of a sampling configuration file, e.g.
+// {"ratio": 0.1, "routes": {"/healthz": 0}}
+type samplingFileConfig struct {
+	Ratio  *float64           `json:"ratio"`
+	Routes map[string]float64 `json:"routes"`
+}
+
+// loadSamplingConfig reads and validates the sampling configuration file at path
+// and returns the sampler chain it describes. The root ratio keeps the parent
+// policy of the configured sampler, which the priority sampler relies on, and the
+// file's routes are applied over those set with WithRouteSampling.
+func (o *OpenTelemetry) loadSamplingConfig(path string) (sdktrace.Sampler, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sampling configuration: %w", err)
+	}
+	var cfg samplingFileConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse sampling configuration: %w", err)
+	}
+	if cfg.Ratio == nil {
+		return nil, errors.New("sampling configuration has no ratio")
+	}
+	if *cfg.Ratio < 0 || *cfg.Ratio > 1 {
+		return nil, fmt.Errorf("sampling ratio %v is outside [0, 1]", *cfg.Ratio)
+	}
+	for route, ratio := range cfg.Routes {
+		if ratio < 0 || ratio > 1 {
+			return nil, fmt.Errorf("sampling ratio %v of route %q is outside [0, 1]", ratio, route)
+		}
+	}
+
+	var root sdktrace.Sampler = sdktrace.ParentBased(sdktrace.TraceIDRatioBased(*cfg.Ratio))
+	if _, strictParent := o.config.sampler.(parentStrictSampler); strictParent {
+		root = ParentStrictSampler(*cfg.Ratio)
+	}
+	routes := make(map[string]float64, len(o.config.routeSampling)+len(cfg.Routes))
+	for route, ratio := range o.config.routeSampling {
+		routes[route] = ratio
+	}
+	for route, ratio := range cfg.Routes {
+		routes[route] = ratio
+	}
+	sampler, _ := samplerChain(root, routes)
+	return sampler, nil
+}
+
+// WatchSamplingConfig applies the sampling configuration in the JSON file at path,
+// with a root span ratio and optional per-route ratios, and re-applies it whenever
+// the file changes, as checked every WithSamplingConfigPollInterval. Invalid changes
+// are logged and the current sampler is kept. Context-scoped sampling priorities
+// still apply on top. A later call replaces the watch, which stops on Shutdown.
+func (o *OpenTelemetry) WatchSamplingConfig(path string) error {
+	if o.sampler == nil {
+		return errSamplingReloadDisabled
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to read sampling configuration: %w", err)
+	}
+	sampler, err := o.loadSamplingConfig(path)
+	if err != nil {
+		return err
+	}
+	o.sampler.set(sampler)
+
+	stop := make(chan struct{})
+	o.samplingWatchMu.Lock()
+	if o.samplingWatchStop != nil {
+		close(o.samplingWatchStop)
+	}
+	o.samplingWatchStop = stop
+	o.samplingWatchMu.Unlock()
+
+	go o.pollSamplingConfig(path, info, stop)
+	return nil
+}
+
+// pollSamplingConfig reloads the sampling configuration when the modification time
+// or size of the file changes, until stop is closed
+func (o *OpenTelemetry) pollSamplingConfig(path string, last os.FileInfo, stop <-chan struct{}) {
+	ticker := time.NewTicker(o.config.samplingPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			logger.Log.Error("Failed to check sampling configuration", zap.Error(err), zap.String("path", path))
+			continue
+		}
+		if info.ModTime().Equal(last.ModTime()) && info.Size() == last.Size() {
+			continue
+		}
+		last = info
+
+		sampler, err := o.loadSamplingConfig(path)
+		if err != nil {
+			logger.Log.Error("Ignoring invalid sampling configuration", zap.Error(err), zap.String("path", path))
+			continue
+		}
+		o.sampler.set(sampler)
+		logger.Log.Info("Applied sampling configuration",
+			zap.String("path", path),
+			zap.String("sampler", sampler.Description()))
+	}
+}
+
+// stopSamplingWatch stops the watch started by WatchSamplingConfig, if any
+func (o *OpenTelemetry) stopSamplingWatch() {
+	o.samplingWatchMu.Lock()
+	defer o.samplingWatchMu.Unlock()
+	if o.samplingWatchStop != nil {
+		close(o.samplingWatchStop)
+		o.samplingWatchStop = nil
+	}
+}
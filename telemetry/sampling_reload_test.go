@@ -0,0 +1,111 @@
+package telemetry
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func writeSamplingConfig(t *testing.T, path, config string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(config), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+}
+
+// routeSampled reports whether a root span for route is sampled
+func routeSampled(tt *testTelemetry, route string) bool {
+	_, span := tt.tracer.Start(context.Background(), "GET "+route, trace.WithAttributes(semconv.HTTPRoute(route)))
+	defer span.End()
+	return span.SpanContext().IsSampled()
+}
+
+func TestWatchSamplingConfigReloadsRewrittenFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sampling.json")
+	writeSamplingConfig(t, path, `{"ratio": 0}`)
+	tt := newTestTelemetry(t,
+		WithSamplingConfigPollInterval(10*time.Millisecond),
+		WithRouteSampling(map[string]float64{"/healthz": 0, "/checkout": 1}))
+	logs := observeLogs(t)
+
+	if err := tt.WatchSamplingConfig(path); err != nil {
+		t.Fatalf("WatchSamplingConfig() error = %v", err)
+	}
+	if routeSampled(tt, "/search") {
+		t.Error("root span sampled with ratio 0")
+	}
+	if !routeSampled(tt, "/checkout") {
+		t.Error("route configured with WithRouteSampling not applied on top of the file")
+	}
+
+	writeSamplingConfig(t, path, `{"ratio": 1, "routes": {"/checkout": 0}}`)
+	deadline := time.Now().Add(5 * time.Second)
+	for !routeSampled(tt, "/search") {
+		if time.Now().After(deadline) {
+			t.Fatal("rewritten sampling configuration not applied")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if routeSampled(tt, "/healthz") {
+		t.Error("route configured with WithRouteSampling lost on reload")
+	}
+	if routeSampled(tt, "/checkout") {
+		t.Error("route of the file not applied over WithRouteSampling")
+	}
+	if n := len(logs.FilterMessage("Applied sampling configuration").All()); n != 1 {
+		t.Errorf("logged %d applied configurations, want 1", n)
+	}
+
+	writeSamplingConfig(t, path, `{"ratio": 2}`)
+	deadline = time.Now().Add(5 * time.Second)
+	for len(logs.FilterMessage("Ignoring invalid sampling configuration").All()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("invalid sampling configuration not reported")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if !routeSampled(tt, "/search") {
+		t.Error("invalid configuration replaced the current sampler")
+	}
+}
+
+func TestWatchSamplingConfigKeepsParentStrictPolicy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sampling.json")
+	writeSamplingConfig(t, path, `{"ratio": 1}`)
+	tt := newTestTelemetry(t, WithSampler(ParentStrictSampler(0)))
+
+	if err := tt.WatchSamplingConfig(path); err != nil {
+		t.Fatalf("WatchSamplingConfig() error = %v", err)
+	}
+	if got := tt.sampler.Description(); !strings.HasPrefix(got, "ParentStrictSampler{") {
+		t.Errorf("reloaded sampler = %s, want the configured parent-strict policy", got)
+	}
+	ctx := WithSamplingPriority(remoteParent(false), SamplingPriorityHigh)
+	_, span := tt.StartSpan(ctx, "remote unsampled parent with high priority")
+	defer tt.EndSpan(span)
+	if span.SpanContext().IsSampled() {
+		t.Error("reloaded sampler overrode the decision of the remote parent")
+	}
+	if !routeSampled(tt, "/search") {
+		t.Error("root span not sampled with the reloaded ratio 1")
+	}
+}
+
+func TestWatchSamplingConfigRejectsInvalidFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sampling.json")
+	writeSamplingConfig(t, path, `{"routes": {"/healthz": 0}}`)
+	tt := newTestTelemetry(t)
+
+	if err := tt.WatchSamplingConfig(path); err == nil {
+		t.Error("WatchSamplingConfig() error = nil, want an error for a missing ratio")
+	}
+	if err := tt.WatchSamplingConfig(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("WatchSamplingConfig() error = nil, want an error for a missing file")
+	}
+}
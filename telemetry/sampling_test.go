@@ -0,0 +1,242 @@
+package telemetry
+
+import (
+	"context"
+	"encoding/binary"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel/baggage"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// remoteParent returns a context with a remote parent span context using the
+// given sampled flag
+func remoteParent(sampled bool) context.Context {
+	var flags trace.TraceFlags
+	if sampled {
+		flags = trace.FlagsSampled
+	}
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36},
+		SpanID:     trace.SpanID{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7},
+		TraceFlags: flags,
+		Remote:     true,
+	})
+	return trace.ContextWithRemoteSpanContext(context.Background(), sc)
+}
+
+func TestParentStrictSamplerHonorsRemoteParent(t *testing.T) {
+	tt := newTestTelemetry(t, WithSampler(ParentStrictSampler(0)))
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want bool
+	}{
+		{"remote sampled parent", remoteParent(true), true},
+		{"remote unsampled parent with high priority", WithSamplingPriority(remoteParent(false), SamplingPriorityHigh), false},
+		{"root span", context.Background(), false},
+		{"root span with high priority", WithSamplingPriority(context.Background(), SamplingPriorityHigh), true},
+	}
+	for _, tc := range tests {
+		_, span := tt.StartSpan(tc.ctx, tc.name)
+		if got := span.SpanContext().IsSampled(); got != tc.want {
+			t.Errorf("%s: sampled = %v, want %v", tc.name, got, tc.want)
+		}
+		tt.EndSpan(span)
+	}
+
+	child := tt.endedSpan(t, "remote sampled parent")
+	if child.Parent().SpanID() != (trace.SpanID{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7}) || !child.Parent().IsRemote() {
+		t.Errorf("child parent = %v, want the remote span", child.Parent())
+	}
+}
+
+// sampledCount starts n root spans from ctx and returns how many were sampled
+func sampledCount(tt *testTelemetry, ctx context.Context, n int) int {
+	sampled := 0
+	for i := 0; i < n; i++ {
+		_, span := tt.StartSpan(ctx, "root")
+		if span.SpanContext().IsSampled() {
+			sampled++
+		}
+		tt.EndSpan(span)
+	}
+	return sampled
+}
+
+func TestBaggageSamplerRaisesRatioForMatchingBaggage(t *testing.T) {
+	tt := newTestTelemetry(t, WithBaggageSampler(0.05, map[BaggageKeyValue]float64{
+		{Key: "tenant.tier", Value: "premium"}: 0.9,
+	}))
+	premium, err := baggage.Parse("tenant.tier=premium")
+	if err != nil {
+		t.Fatalf("baggage.Parse() error = %v", err)
+	}
+	free, err := baggage.Parse("tenant.tier=free")
+	if err != nil {
+		t.Fatalf("baggage.Parse() error = %v", err)
+	}
+
+	const n = 1000
+	premiumSampled := sampledCount(tt, baggage.ContextWithBaggage(context.Background(), premium), n)
+	freeSampled := sampledCount(tt, baggage.ContextWithBaggage(context.Background(), free), n)
+	baseline := sampledCount(tt, context.Background(), n)
+
+	if premiumSampled < 800 {
+		t.Errorf("sampled %d of %d premium traces, want about 90%%", premiumSampled, n)
+	}
+	if freeSampled > 150 || baseline > 150 {
+		t.Errorf("sampled %d free and %d baseline traces of %d, want about 5%%", freeSampled, baseline, n)
+	}
+}
+
+func TestParseEnvSampler(t *testing.T) {
+	tests := []struct {
+		name, arg string
+		want      string
+		wantOK    bool
+	}{
+		{"always_on", "", "AlwaysOnSampler", true},
+		{"Always_Off", "", "AlwaysOffSampler", true},
+		{"traceidratio", "0.25", "TraceIDRatioBased{0.25}", true},
+		{"parentbased_always_on", "", "ParentBased{root:AlwaysOnSampler", true},
+		{"parentbased_traceidratio", " 0.5 ", "ParentBased{root:TraceIDRatioBased{0.5}", true},
+		{"jaeger_remote", "", "", false},
+	}
+	for _, tc := range tests {
+		sampler, ok := parseEnvSampler(tc.name, tc.arg)
+		if ok != tc.wantOK {
+			t.Errorf("parseEnvSampler(%q, %q) ok = %v, want %v", tc.name, tc.arg, ok, tc.wantOK)
+			continue
+		}
+		if ok && !strings.HasPrefix(sampler.Description(), tc.want) {
+			t.Errorf("parseEnvSampler(%q, %q) = %s, want %s", tc.name, tc.arg, sampler.Description(), tc.want)
+		}
+	}
+}
+
+func TestParseEnvSamplerInvalidRatioUsesDefault(t *testing.T) {
+	logs := observeLogs(t)
+	for _, arg := range []string{"", "half", "-0.1", "1.5"} {
+		sampler, ok := parseEnvSampler("traceidratio", arg)
+		if !ok || sampler.Description() != "AlwaysOnSampler" {
+			t.Errorf("parseEnvSampler(traceidratio, %q) = %v, want the default ratio 1", arg, sampler)
+		}
+	}
+	if n := len(logs.FilterMessage("Invalid OTEL_TRACES_SAMPLER_ARG, using the default ratio").All()); n != 4 {
+		t.Errorf("logged %d invalid ratios, want 4", n)
+	}
+}
+
+func TestNewTelemetryReadsSamplerFromEnvironment(t *testing.T) {
+	t.Setenv("TELEMETRY_TYPE", "otel")
+	t.Setenv("SERVICE_NAME", "telemetry-test")
+	t.Setenv("OTEL_TRACE_ENABLED", "true")
+	t.Setenv("OTEL_METRICS_ENABLED", "")
+	t.Setenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "127.0.0.1:1")
+	t.Setenv("OTEL_EXPORTER_OTLP_INSECURE", "true")
+	t.Setenv("OTEL_TRACES_SAMPLER", "always_off")
+
+	impl, err := NewTelemetry()
+	if err != nil {
+		t.Fatalf("NewTelemetry() error = %v", err)
+	}
+	t.Cleanup(func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_ = impl.Shutdown(ctx)
+	})
+	_, span := impl.StartSpan(context.Background(), "operation")
+	defer impl.EndSpan(span)
+	if span.SpanContext().IsSampled() {
+		t.Error("span sampled with OTEL_TRACES_SAMPLER=always_off")
+	}
+}
+
+// testTraceIDs returns n distinct trace IDs, the same on every call
+func testTraceIDs(n int) []trace.TraceID {
+	ids := make([]trace.TraceID, n)
+	for i := range ids {
+		binary.BigEndian.PutUint64(ids[i][:8], uint64(i)*0x9e3779b97f4a7c15)
+		binary.BigEndian.PutUint64(ids[i][8:], uint64(i+1))
+	}
+	return ids
+}
+
+// deterministicDecisions returns whether sampler samples each of ids
+func deterministicDecisions(sampler sdktrace.Sampler, ids []trace.TraceID) []bool {
+	sampled := make([]bool, len(ids))
+	for i, id := range ids {
+		result := sampler.ShouldSample(sdktrace.SamplingParameters{
+			ParentContext: context.Background(),
+			TraceID:       id,
+			Name:          "load-test",
+		})
+		sampled[i] = result.Decision == sdktrace.RecordAndSample
+	}
+	return sampled
+}
+
+func TestDeterministicSamplerIsStableAcrossInstances(t *testing.T) {
+	ids := testTraceIDs(2000)
+	first := deterministicDecisions(DeterministicSampler(42, 0.25), ids)
+	second := deterministicDecisions(DeterministicSampler(42, 0.25), ids)
+	otherSeed := deterministicDecisions(DeterministicSampler(7, 0.25), ids)
+
+	var sampled, differ int
+	for i := range ids {
+		if first[i] != second[i] {
+			t.Fatalf("trace %s sampled = %v by one instance and %v by another with the same seed", ids[i], first[i], second[i])
+		}
+		if first[i] {
+			sampled++
+		}
+		if first[i] != otherSeed[i] {
+			differ++
+		}
+	}
+	if got := float64(sampled) / float64(len(ids)); got < 0.2 || got > 0.3 {
+		t.Errorf("sampled ratio = %.3f, want about 0.25", got)
+	}
+	if differ == 0 {
+		t.Error("a different seed sampled the same traces, want a different subset")
+	}
+}
+
+func TestDeterministicSamplerRatioBounds(t *testing.T) {
+	ids := testTraceIDs(100)
+	for _, tc := range []struct {
+		ratio float64
+		want  bool
+	}{
+		{0, false},
+		{-1, false},
+		{1, true},
+		{2, true},
+	} {
+		for i, sampled := range deterministicDecisions(DeterministicSampler(42, tc.ratio), ids) {
+			if sampled != tc.want {
+				t.Fatalf("ratio %g: trace %s sampled = %v, want %v", tc.ratio, ids[i], sampled, tc.want)
+			}
+		}
+	}
+}
+
+func TestDeterministicSamplerViaWithSampler(t *testing.T) {
+	ids := testTraceIDs(50)
+	want := deterministicDecisions(DeterministicSampler(42, 0.5), ids)
+	tt := newTestTelemetry(t, WithSampler(DeterministicSampler(42, 0.5)))
+
+	for i, id := range ids {
+		sc := trace.NewSpanContext(trace.SpanContextConfig{TraceID: id, SpanID: trace.SpanID{1}, Remote: true})
+		ctx := trace.ContextWithRemoteSpanContext(context.Background(), sc)
+		_, span := tt.StartSpan(ctx, "load-test")
+		if got := span.SpanContext().IsSampled(); got != want[i] {
+			t.Errorf("trace %s sampled = %v through the provider, want %v", id, got, want[i])
+		}
+		tt.EndSpan(span)
+	}
+}
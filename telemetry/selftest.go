@@ -0,0 +1,50 @@
+// selftest.go - Startup self-test verifying the export pipeline
+
+package telemetry
+
+import (
+	"context"
+	"time"
+
+	"github.com/sadco-io/sad-go-logger/logger"
+	"go.opentelemetry.io/otel/attribute"
+	"go.uber.org/zap"
+)
+
+const (
+	selfTestSpanName    = "telemetry.selftest"
+	selfTestCounterName = "telemetry.selftest"
+	selfTestTimeout     = 10 * time.Second
+)
+
+// runSelfTest emits a canary span and counter, flushes them and logs whether
+// the export succeeded. The providers are flushed directly rather than through
+// Flush, which would also emit the run summary and push to the Pushgateway
+// before the application has done any work.
+func (o *OpenTelemetry) runSelfTest(ctx context.Context) {
+	if !o.traceEnabled && !o.metricsEnabled {
+		logger.Log.Warn("Telemetry self-test skipped, tracing and metrics are disabled")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, selfTestTimeout)
+	defer cancel()
+
+	if o.traceEnabled {
+		_, span := o.StartSpan(ctx, selfTestSpanName)
+		span.SetAttributes(attribute.Bool("telemetry.canary", true))
+		o.EndSpan(span)
+	}
+	o.IncrementCounter(ctx, selfTestCounterName, 1, attribute.Bool("telemetry.canary", true))
+
+	if err := o.forceFlush(ctx); err != nil {
+		logger.Log.Error("Telemetry self-test export failed",
+			zap.Error(err),
+			zap.Bool("traceEnabled", o.traceEnabled),
+			zap.Bool("metricsEnabled", o.metricsEnabled))
+		return
+	}
+	logger.Log.Info("Telemetry self-test export succeeded",
+		zap.Bool("traceEnabled", o.traceEnabled),
+		zap.Bool("metricsEnabled", o.metricsEnabled))
+}
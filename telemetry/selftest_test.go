@@ -0,0 +1,46 @@
+package telemetry
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestStartupSelfTestEmitsCanary(t *testing.T) {
+	collector := newStubCollector(t)
+	var pushes atomic.Int64
+	pushgateway := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		pushes.Add(1)
+	}))
+	defer pushgateway.Close()
+	logs := observeLogs(t)
+
+	tt := newTestTelemetry(t,
+		WithTraceEndpoint(collector.addr),
+		WithMetricEndpoint(collector.addr),
+		WithPushgateway(pushgateway.URL, "selftest"),
+		WithStartupSelfTest(true))
+
+	span := tt.endedSpan(t, selfTestSpanName)
+	if attrMap(span.Attributes())["telemetry.canary"] != "true" {
+		t.Errorf("canary span attributes = %v, want telemetry.canary=true", span.Attributes())
+	}
+	if spans := collector.receivedSpans(); len(spans) != 1 || spans[0] != selfTestSpanName {
+		t.Errorf("collector received spans %v, want the canary span", spans)
+	}
+	if collector.receivedMetricExports() == 0 {
+		t.Error("collector received no metrics")
+	}
+	points := sumPoints(t, tt.metric(t, selfTestCounterName))
+	if len(points) != 1 || points[0].Value != 1 {
+		t.Errorf("canary counter = %+v, want a single increment", points)
+	}
+
+	if n := logs.FilterMessage("Telemetry self-test export succeeded").Len(); n != 1 {
+		t.Errorf("logged %d self-test successes, want 1: %v", n, logs.All())
+	}
+	if n := pushes.Load(); n != 0 {
+		t.Errorf("self-test pushed %d times to the Pushgateway, want 0", n)
+	}
+}
@@ -0,0 +1,112 @@
+// severity.go - Severity levels of trace messages and logs, and their backend mappings
+
+package telemetry
+
+import (
+	"strings"
+
+	"github.com/microsoft/ApplicationInsights-Go/appinsights/contracts"
+	"go.uber.org/zap/zapcore"
+)
+
+// SeverityLevel is the severity of a trace message or log. Its values match those
+// of the Application Insights contracts.SeverityLevel.
+type SeverityLevel int
+
+const (
+	// SeverityVerbose is the severity of verbose, debug-level messages
+	SeverityVerbose SeverityLevel = iota
+	// SeverityInformation is the severity of informational messages
+	SeverityInformation
+	// SeverityWarning is the severity of warnings
+	SeverityWarning
+	// SeverityError is the severity of errors
+	SeverityError
+	// SeverityCritical is the severity of critical errors
+	SeverityCritical
+)
+
+// ParseSeverityLevel parses a severity name such as "info", "warning" or "error",
+// case-insensitively. Unknown names are reported as SeverityInformation and not ok.
+func ParseSeverityLevel(name string) (SeverityLevel, bool) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "verbose", "debug", "trace":
+		return SeverityVerbose, true
+	case "information", "info":
+		return SeverityInformation, true
+	case "warning", "warn":
+		return SeverityWarning, true
+	case "error":
+		return SeverityError, true
+	case "critical", "fatal":
+		return SeverityCritical, true
+	default:
+		return SeverityInformation, false
+	}
+}
+
+// String returns the lowercase name of the severity level
+func (s SeverityLevel) String() string {
+	switch s {
+	case SeverityVerbose:
+		return "verbose"
+	case SeverityInformation:
+		return "information"
+	case SeverityWarning:
+		return "warning"
+	case SeverityError:
+		return "error"
+	case SeverityCritical:
+		return "critical"
+	default:
+		return "unknown"
+	}
+}
+
+// ToAppInsights returns the Application Insights severity level of s, with unknown
+// levels mapped to contracts.Information
+func (s SeverityLevel) ToAppInsights() contracts.SeverityLevel {
+	switch s {
+	case SeverityVerbose:
+		return contracts.Verbose
+	case SeverityWarning:
+		return contracts.Warning
+	case SeverityError:
+		return contracts.Error
+	case SeverityCritical:
+		return contracts.Critical
+	default:
+		return contracts.Information
+	}
+}
+
+// ToOTelSeverity returns the SeverityNumber of the OpenTelemetry log data model for
+// s: TRACE (1), INFO (9), WARN (13), ERROR (17) or FATAL (21)
+func (s SeverityLevel) ToOTelSeverity() int {
+	switch s {
+	case SeverityVerbose:
+		return 1
+	case SeverityWarning:
+		return 13
+	case SeverityError:
+		return 17
+	case SeverityCritical:
+		return 21
+	default:
+		return 9
+	}
+}
+
+// zapLevel returns the logger level messages of severity s are logged at
+func (s SeverityLevel) zapLevel() zapcore.Level {
+	switch s {
+	case SeverityVerbose:
+		return zapcore.DebugLevel
+	case SeverityWarning:
+		return zapcore.WarnLevel
+	case SeverityError, SeverityCritical:
+		return zapcore.ErrorLevel
+	default:
+		return zapcore.InfoLevel
+	}
+}
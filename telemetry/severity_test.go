@@ -0,0 +1,61 @@
+package telemetry
+
+import (
+	"testing"
+
+	"github.com/microsoft/ApplicationInsights-Go/appinsights/contracts"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestParseSeverityLevel(t *testing.T) {
+	tests := []struct {
+		name   string
+		want   SeverityLevel
+		wantOK bool
+	}{
+		{"debug", SeverityVerbose, true},
+		{" Info ", SeverityInformation, true},
+		{"WARN", SeverityWarning, true},
+		{"error", SeverityError, true},
+		{"fatal", SeverityCritical, true},
+		{"notice", SeverityInformation, false},
+		{"", SeverityInformation, false},
+	}
+	for _, tc := range tests {
+		got, ok := ParseSeverityLevel(tc.name)
+		if got != tc.want || ok != tc.wantOK {
+			t.Errorf("ParseSeverityLevel(%q) = %v, %v, want %v, %v", tc.name, got, ok, tc.want, tc.wantOK)
+		}
+	}
+}
+
+func TestSeverityLevelMappings(t *testing.T) {
+	tests := []struct {
+		level       SeverityLevel
+		name        string
+		appInsights contracts.SeverityLevel
+		otel        int
+		zap         zapcore.Level
+	}{
+		{SeverityVerbose, "verbose", contracts.Verbose, 1, zapcore.DebugLevel},
+		{SeverityInformation, "information", contracts.Information, 9, zapcore.InfoLevel},
+		{SeverityWarning, "warning", contracts.Warning, 13, zapcore.WarnLevel},
+		{SeverityError, "error", contracts.Error, 17, zapcore.ErrorLevel},
+		{SeverityCritical, "critical", contracts.Critical, 21, zapcore.ErrorLevel},
+		{SeverityLevel(9), "unknown", contracts.Information, 9, zapcore.InfoLevel},
+	}
+	for _, tc := range tests {
+		if got := tc.level.String(); got != tc.name {
+			t.Errorf("SeverityLevel(%d).String() = %q, want %q", tc.level, got, tc.name)
+		}
+		if got := tc.level.ToAppInsights(); got != tc.appInsights {
+			t.Errorf("%s ToAppInsights() = %v, want %v", tc.name, got, tc.appInsights)
+		}
+		if got := tc.level.ToOTelSeverity(); got != tc.otel {
+			t.Errorf("%s ToOTelSeverity() = %d, want %d", tc.name, got, tc.otel)
+		}
+		if got := tc.level.zapLevel(); got != tc.zap {
+			t.Errorf("%s zapLevel() = %v, want %v", tc.name, got, tc.zap)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+// span_leaks.go - Detection of spans that are started but never ended
+
+package telemetry
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sadco-io/sad-go-logger/logger"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// SpanInfo describes a span that has been started but not ended yet
+type SpanInfo struct {
+	Name      string
+	TraceID   string
+	SpanID    string
+	StartTime time.Time
+}
+
+// liveSpan is a span tracked by the leak detector
+type liveSpan struct {
+	info   SpanInfo
+	warned bool
+}
+
+// liveSpanProcessor tracks the spans that are live, i.e. started and not ended, and
+// logs a warning once for each span live longer than threshold
+type liveSpanProcessor struct {
+	threshold time.Duration
+
+	mu    sync.Mutex
+	spans map[trace.SpanID]*liveSpan
+
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// newLiveSpanProcessor returns a processor checking for leaked spans every threshold
+func newLiveSpanProcessor(threshold time.Duration) *liveSpanProcessor {
+	p := &liveSpanProcessor{
+		threshold: threshold,
+		spans:     make(map[trace.SpanID]*liveSpan),
+		done:      make(chan struct{}),
+	}
+	go p.run()
+	return p
+}
+
+// run checks the live spans every threshold until the processor shuts down
+func (p *liveSpanProcessor) run() {
+	ticker := time.NewTicker(p.threshold)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.done:
+			return
+		case now := <-ticker.C:
+			p.check(now)
+		}
+	}
+}
+
+// check logs a warning for every span live for longer than threshold at now that
+// has not been reported yet
+func (p *liveSpanProcessor) check(now time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, s := range p.spans {
+		age := now.Sub(s.info.StartTime)
+		if s.warned || age <= p.threshold {
+			continue
+		}
+		s.warned = true
+		logger.Log.Warn("Span not ended, EndSpan may be missing",
+			zap.String("span", s.info.Name),
+			zap.String("trace_id", s.info.TraceID),
+			zap.String("span_id", s.info.SpanID),
+			zap.Duration("age", age))
+	}
+}
+
+// snapshot returns the live spans, oldest first
+func (p *liveSpanProcessor) snapshot() []SpanInfo {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	spans := make([]SpanInfo, 0, len(p.spans))
+	for _, s := range p.spans {
+		spans = append(spans, s.info)
+	}
+	sort.Slice(spans, func(i, j int) bool {
+		return spans[i].StartTime.Before(spans[j].StartTime)
+	})
+	return spans
+}
+
+// OnStart starts tracking the span
+func (p *liveSpanProcessor) OnStart(_ context.Context, s sdktrace.ReadWriteSpan) {
+	sc := s.SpanContext()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.spans[sc.SpanID()] = &liveSpan{info: SpanInfo{
+		Name:      s.Name(),
+		TraceID:   sc.TraceID().String(),
+		SpanID:    sc.SpanID().String(),
+		StartTime: s.StartTime(),
+	}}
+}
+
+// OnEnd stops tracking the span
+func (p *liveSpanProcessor) OnEnd(s sdktrace.ReadOnlySpan) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.spans, s.SpanContext().SpanID())
+}
+
+// Shutdown stops the periodic check
+func (p *liveSpanProcessor) Shutdown(context.Context) error {
+	p.stopOnce.Do(func() { close(p.done) })
+	return nil
+}
+
+// ForceFlush does nothing, live spans are not exported
+func (p *liveSpanProcessor) ForceFlush(context.Context) error { return nil }
+
+// LiveSpans returns the recorded spans that have been started but not ended yet,
+// oldest first, to help find missing EndSpan calls. It returns nil unless span
+// leak detection is enabled with WithSpanLeakDetection.
+func (o *OpenTelemetry) LiveSpans() []SpanInfo {
+	if o.liveSpans == nil {
+		return nil
+	}
+	return o.liveSpans.snapshot()
+}
@@ -0,0 +1,60 @@
+package telemetry
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLiveSpansListsAndWarnsAboutUnendedSpans(t *testing.T) {
+	logs := observeLogs(t)
+	tt := newTestTelemetry(t, WithSpanLeakDetection(time.Hour))
+	ctx := context.Background()
+
+	_, leaked := tt.StartSpan(ctx, "leaked")
+	_, ended := tt.StartSpan(ctx, "ended")
+	_, alsoLeaked := tt.StartSpan(ctx, "also leaked")
+	tt.EndSpan(ended)
+
+	live := tt.LiveSpans()
+	if len(live) != 2 || live[0].Name != "leaked" || live[1].Name != "also leaked" {
+		t.Fatalf("LiveSpans() = %+v, want the two unended spans, oldest first", live)
+	}
+	if live[0].SpanID != leaked.SpanContext().SpanID().String() || live[0].TraceID != leaked.SpanContext().TraceID().String() {
+		t.Errorf("LiveSpans()[0] = %+v, want the IDs of the leaked span", live[0])
+	}
+
+	tt.liveSpans.check(live[1].StartTime.Add(30 * time.Minute))
+	if n := logs.FilterMessage("Span not ended, EndSpan may be missing").Len(); n != 0 {
+		t.Fatalf("logged %d leak warnings before the threshold, want none", n)
+	}
+	tt.liveSpans.check(live[1].StartTime.Add(2 * time.Hour))
+	tt.liveSpans.check(live[1].StartTime.Add(3 * time.Hour))
+	warnings := logs.FilterMessage("Span not ended, EndSpan may be missing")
+	if warnings.Len() != 2 {
+		t.Fatalf("logged %d leak warnings, want one per leaked span", warnings.Len())
+	}
+	warned := map[string]bool{}
+	for _, entry := range warnings.All() {
+		warned[entry.ContextMap()["span"].(string)] = true
+	}
+	if !warned["leaked"] || !warned["also leaked"] {
+		t.Errorf("warned about %v, want both leaked spans", warned)
+	}
+
+	tt.EndSpan(leaked)
+	tt.EndSpan(alsoLeaked)
+	if live := tt.LiveSpans(); len(live) != 0 {
+		t.Errorf("LiveSpans() = %+v after ending every span, want none", live)
+	}
+}
+
+func TestLiveSpansDisabledByDefault(t *testing.T) {
+	tt := newTestTelemetry(t)
+	_, span := tt.StartSpan(context.Background(), "leaked")
+	defer tt.EndSpan(span)
+
+	if live := tt.LiveSpans(); live != nil {
+		t.Errorf("LiveSpans() = %+v without WithSpanLeakDetection, want nil", live)
+	}
+}
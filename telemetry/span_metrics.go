@@ -0,0 +1,80 @@
+// span_metrics.go - Automatic span duration metrics
+
+package telemetry
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+const (
+	// maxSpanNames is the number of distinct span names recorded by each span
+	// metric; spans with further names are recorded under otherSpanName
+	maxSpanNames  = 500
+	otherSpanName = "other"
+)
+
+// spanDurationProcessor records the span.duration histogram for every ended span
+type spanDurationProcessor struct {
+	o *OpenTelemetry
+	spanNames
+}
+
+// spanNames bounds the span.name attribute values of a span metric
+type spanNames struct {
+	names     sync.Map
+	nameCount atomic.Int64
+}
+
+// OnStart does nothing, durations are recorded when spans end
+func (p *spanDurationProcessor) OnStart(context.Context, sdktrace.ReadWriteSpan) {}
+
+// OnEnd records the span's duration in milliseconds with its normalized name
+func (p *spanDurationProcessor) OnEnd(s sdktrace.ReadOnlySpan) {
+	duration := s.EndTime().Sub(s.StartTime())
+	p.o.RecordHistogram(context.Background(), "span.duration", durationMillis(duration),
+		attribute.String("span.name", p.spanName(s.Name())))
+}
+
+// spanName returns the normalized span name used as the span.name attribute,
+// collapsing names beyond maxSpanNames into otherSpanName
+func (p *spanNames) spanName(name string) string {
+	name = normalizeSpanName(name)
+	if _, ok := p.names.Load(name); ok {
+		return name
+	}
+	if p.nameCount.Add(1) > maxSpanNames {
+		p.nameCount.Add(-1)
+		return otherSpanName
+	}
+	if _, loaded := p.names.LoadOrStore(name, struct{}{}); loaded {
+		p.nameCount.Add(-1)
+	}
+	return name
+}
+
+// Shutdown does nothing
+func (p *spanDurationProcessor) Shutdown(context.Context) error { return nil }
+
+// ForceFlush does nothing
+func (p *spanDurationProcessor) ForceFlush(context.Context) error { return nil }
+
+// normalizeSpanName replaces path segments containing digits, such as numeric IDs
+// and UUIDs in names like "GET /users/42", with {id}
+func normalizeSpanName(name string) string {
+	if !strings.ContainsAny(name, "0123456789") {
+		return name
+	}
+	segments := strings.Split(name, "/")
+	for i, segment := range segments {
+		if i > 0 && strings.ContainsAny(segment, "0123456789") {
+			segments[i] = "{id}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
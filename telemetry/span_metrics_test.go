@@ -0,0 +1,57 @@
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestSpanDurationMetrics(t *testing.T) {
+	tt := newTestTelemetry(t, WithSpanDurationMetrics(true))
+	ctx := context.Background()
+
+	start := time.Now()
+	for _, name := range []string{"GET /users/42", "GET /users/7"} {
+		_, span := tt.tracer.Start(ctx, name, trace.WithTimestamp(start))
+		span.End(trace.WithTimestamp(start.Add(2500 * time.Microsecond)))
+	}
+
+	points := histogramPoints(t, tt.metric(t, "span.duration"))
+	if len(points) != 1 {
+		t.Fatalf("got %d span.duration points, want the names normalized into one: %+v", len(points), points)
+	}
+	if name := attrMap(points[0].Attributes.ToSlice())["span.name"]; name != "GET /users/{id}" {
+		t.Errorf("span.name = %q, want GET /users/{id}", name)
+	}
+	if points[0].Count != 2 || points[0].Sum != 5 {
+		t.Errorf("span.duration count, sum = %d, %v, want 2, 5ms", points[0].Count, points[0].Sum)
+	}
+}
+
+func TestSpanDurationMetricsDisabledByDefault(t *testing.T) {
+	tt := newTestTelemetry(t)
+	_, span := tt.StartSpan(context.Background(), "operation")
+	tt.EndSpan(span)
+
+	if _, ok := findMetric(tt.collect(t), "span.duration"); ok {
+		t.Error("span.duration recorded without WithSpanDurationMetrics")
+	}
+}
+
+func TestSpanDurationNameCardinalityIsBounded(t *testing.T) {
+	p := &spanDurationProcessor{}
+	for i := 0; i < maxSpanNames; i++ {
+		if got := p.spanName(fmt.Sprintf("job-%d", i)); got == otherSpanName {
+			t.Fatalf("span name %d collapsed into %q before reaching the limit", i, otherSpanName)
+		}
+	}
+	if got := p.spanName("one-more"); got != otherSpanName {
+		t.Errorf("spanName() = %q beyond the limit, want %q", got, otherSpanName)
+	}
+	if got := p.spanName("job-0"); got != "job-0" {
+		t.Errorf("spanName() = %q for a known name, want it kept", got)
+	}
+}
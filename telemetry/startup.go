@@ -0,0 +1,37 @@
+// startup.go - Recording application startup phases and readiness
+
+package telemetry
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RecordStartupPhase records the duration of a startup phase, e.g. the
+// initialization of a subsystem, in the startup.phase.duration histogram in
+// milliseconds with a phase attribute
+func (o *OpenTelemetry) RecordStartupPhase(name string, duration time.Duration) {
+	o.RecordHistogram(context.Background(), "startup.phase.duration", durationMillis(duration),
+		attribute.String("phase", name))
+}
+
+// MarkReady records a service.ready event, carrying the time since the telemetry
+// was initialized, on a span of the same name. Only the first call has an effect.
+func (o *OpenTelemetry) MarkReady(ctx context.Context) {
+	o.readyOnce.Do(func() {
+		timeToReady := time.Since(o.startedAt)
+		o.RecordHistogram(ctx, "startup.phase.duration", durationMillis(timeToReady),
+			attribute.String("phase", "ready"))
+		if !o.traceEnabled {
+			return
+		}
+		_, span := o.StartSpan(ctx, "service.ready")
+		defer o.EndSpan(span)
+		attrs := []attribute.KeyValue{attribute.Int64("startup.time_to_ready_ms", timeToReady.Milliseconds())}
+		span.AddEvent("service.ready", trace.WithAttributes(attrs...))
+		o.logEvent(span, "service.ready", attrs)
+	})
+}
@@ -0,0 +1,44 @@
+package telemetry
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestStartupPhasesAndReadiness(t *testing.T) {
+	tt := newTestTelemetry(t)
+	ctx := context.Background()
+
+	tt.RecordStartupPhase("config", 1500*time.Microsecond)
+	tt.RecordStartupPhase("database", 40*time.Millisecond)
+	tt.MarkReady(ctx)
+	tt.MarkReady(ctx)
+
+	sums := make(map[string]float64)
+	for _, p := range histogramPoints(t, tt.metric(t, "startup.phase.duration")) {
+		phase := attrMap(p.Attributes.ToSlice())["phase"]
+		if p.Count != 1 {
+			t.Errorf("phase %q has %d recordings, want 1", phase, p.Count)
+		}
+		sums[phase] = p.Sum
+	}
+	if len(sums) != 3 || sums["config"] != 1.5 || sums["database"] != 40 {
+		t.Errorf("startup.phase.duration = %v, want config 1.5, database 40 and ready", sums)
+	}
+	if _, ok := sums["ready"]; !ok {
+		t.Error("time to ready not recorded in startup.phase.duration")
+	}
+
+	spans := tt.endedSpans("service.ready")
+	if len(spans) != 1 {
+		t.Fatalf("got %d service.ready spans, want one for repeated MarkReady calls", len(spans))
+	}
+	events := spans[0].Events()
+	if len(events) != 1 || events[0].Name != "service.ready" {
+		t.Fatalf("service.ready events = %+v, want a single service.ready event", events)
+	}
+	if _, ok := attrMap(events[0].Attributes)["startup.time_to_ready_ms"]; !ok {
+		t.Errorf("service.ready event attributes = %v, want startup.time_to_ready_ms", events[0].Attributes)
+	}
+}
@@ -0,0 +1,112 @@
+// subscribers.go - Streaming of finished spans to in-process subscribers
+
+package telemetry
+
+import (
+	"context"
+	"sync"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// subscriberQueueSize is the number of finished spans buffered for subscribers;
+// spans ending while the buffer is full are not delivered
+const subscriberQueueSize = 1024
+
+// subscriberProcessor hands finished spans to subscribers on a single worker
+// goroutine, so slow subscribers never block the span pipeline
+type subscriberProcessor struct {
+	mu     sync.RWMutex
+	subs   map[uint64]func(sdktrace.ReadOnlySpan)
+	nextID uint64
+
+	queue     chan sdktrace.ReadOnlySpan
+	done      chan struct{}
+	startOnce sync.Once
+	stopOnce  sync.Once
+}
+
+// newSubscriberProcessor returns a processor without subscribers
+func newSubscriberProcessor() *subscriberProcessor {
+	return &subscriberProcessor{
+		subs:  make(map[uint64]func(sdktrace.ReadOnlySpan)),
+		queue: make(chan sdktrace.ReadOnlySpan, subscriberQueueSize),
+		done:  make(chan struct{}),
+	}
+}
+
+// subscribe registers fn, starting the worker on first use, and returns a function
+// removing it again
+func (p *subscriberProcessor) subscribe(fn func(sdktrace.ReadOnlySpan)) func() {
+	p.startOnce.Do(func() { go p.run() })
+
+	p.mu.Lock()
+	id := p.nextID
+	p.nextID++
+	p.subs[id] = fn
+	p.mu.Unlock()
+
+	return func() {
+		p.mu.Lock()
+		delete(p.subs, id)
+		p.mu.Unlock()
+	}
+}
+
+// run delivers queued spans to the current subscribers until the processor shuts down
+func (p *subscriberProcessor) run() {
+	for {
+		select {
+		case <-p.done:
+			return
+		case s := <-p.queue:
+			p.mu.RLock()
+			subs := make([]func(sdktrace.ReadOnlySpan), 0, len(p.subs))
+			for _, fn := range p.subs {
+				subs = append(subs, fn)
+			}
+			p.mu.RUnlock()
+			for _, fn := range subs {
+				fn(s)
+			}
+		}
+	}
+}
+
+// OnStart does nothing, subscribers only receive finished spans
+func (p *subscriberProcessor) OnStart(context.Context, sdktrace.ReadWriteSpan) {}
+
+// OnEnd queues the span for the subscribers, dropping it if the queue is full
+func (p *subscriberProcessor) OnEnd(s sdktrace.ReadOnlySpan) {
+	p.mu.RLock()
+	subscribed := len(p.subs) > 0
+	p.mu.RUnlock()
+	if !subscribed {
+		return
+	}
+	select {
+	case p.queue <- s:
+	default:
+	}
+}
+
+// Shutdown stops the worker
+func (p *subscriberProcessor) Shutdown(context.Context) error {
+	p.stopOnce.Do(func() { close(p.done) })
+	return nil
+}
+
+// ForceFlush does nothing, queued spans are delivered asynchronously
+func (p *subscriberProcessor) ForceFlush(context.Context) error { return nil }
+
+// Subscribe registers fn to receive every recorded span when it ends,
+// e.g. to stream spans to a live debugging UI. Subscribers run on a single
+// background worker with a bounded buffer, so spans are dropped rather than
+// delaying the application when subscribers fall behind. The returned function
+// unsubscribes fn.
+func (o *OpenTelemetry) Subscribe(fn func(sdktrace.ReadOnlySpan)) (unsubscribe func()) {
+	if o.subscribers == nil {
+		return func() {}
+	}
+	return o.subscribers.subscribe(fn)
+}
@@ -0,0 +1,89 @@
+package telemetry
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+func receiveSpan(t *testing.T, spans <-chan sdktrace.ReadOnlySpan) sdktrace.ReadOnlySpan {
+	t.Helper()
+	select {
+	case s := <-spans:
+		return s
+	case <-time.After(5 * time.Second):
+		t.Fatal("subscriber received no span")
+		return nil
+	}
+}
+
+func TestSubscribeStreamsEndedSpansUntilUnsubscribed(t *testing.T) {
+	tt := newTestTelemetry(t)
+	first := make(chan sdktrace.ReadOnlySpan, 4)
+	second := make(chan sdktrace.ReadOnlySpan, 4)
+	unsubscribe := tt.Subscribe(func(s sdktrace.ReadOnlySpan) { first <- s })
+	tt.Subscribe(func(s sdktrace.ReadOnlySpan) { second <- s })
+
+	_, span := tt.StartSpan(context.Background(), "checkout")
+	tt.EndSpan(span)
+	for _, spans := range []chan sdktrace.ReadOnlySpan{first, second} {
+		if got := receiveSpan(t, spans); got.Name() != "checkout" || got.EndTime().IsZero() {
+			t.Errorf("subscriber received %q ended at %v, want the ended checkout span", got.Name(), got.EndTime())
+		}
+	}
+
+	unsubscribe()
+	_, span = tt.StartSpan(context.Background(), "refund")
+	tt.EndSpan(span)
+	if got := receiveSpan(t, second); got.Name() != "refund" {
+		t.Errorf("remaining subscriber received %q, want refund", got.Name())
+	}
+	select {
+	case s := <-first:
+		t.Errorf("unsubscribed subscriber received %q", s.Name())
+	default:
+	}
+}
+
+func TestSubscribeDropsSpansForSlowSubscriber(t *testing.T) {
+	tt := newTestTelemetry(t)
+	release := make(chan struct{})
+	var delivered atomic.Int64
+	tt.Subscribe(func(sdktrace.ReadOnlySpan) {
+		<-release
+		delivered.Add(1)
+	})
+
+	const spans = 2 * subscriberQueueSize
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < spans; i++ {
+			_, span := tt.StartSpan(context.Background(), "operation")
+			tt.EndSpan(span)
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("ending spans blocked on a slow subscriber")
+	}
+	close(release)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) && delivered.Load() < subscriberQueueSize {
+		time.Sleep(time.Millisecond)
+	}
+	if got := delivered.Load(); got < subscriberQueueSize || got >= spans {
+		t.Errorf("delivered %d spans, want the buffered ones and the rest dropped", got)
+	}
+}
+
+func TestSubscribeWithoutTracingIsNoop(t *testing.T) {
+	tt := newTestTelemetry(t, WithTracingEnabled(false))
+	unsubscribe := tt.Subscribe(func(sdktrace.ReadOnlySpan) { t.Error("subscriber called with tracing disabled") })
+	unsubscribe()
+}
@@ -0,0 +1,100 @@
+// summary.go - Forwarding of pre-aggregated summaries through a metric producer
+
+package telemetry
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+// summaryProducer is an external metric producer exporting the latest pre-aggregated
+// summary recorded for each name and attribute set
+type summaryProducer struct {
+	scope instrumentation.Scope
+
+	mu     sync.Mutex
+	series map[string]map[attribute.Distinct]*metricdata.SummaryDataPoint
+}
+
+// newSummaryProducer returns a producer reporting summaries under the given scope name
+func newSummaryProducer(scopeName string) *summaryProducer {
+	return &summaryProducer{
+		scope:  instrumentation.Scope{Name: scopeName},
+		series: make(map[string]map[attribute.Distinct]*metricdata.SummaryDataPoint),
+	}
+}
+
+// record stores the latest summary for name and attributes, replacing any previous one
+func (p *summaryProducer) record(name string, dp metricdata.SummaryDataPoint) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	points, ok := p.series[name]
+	if !ok {
+		points = make(map[attribute.Distinct]*metricdata.SummaryDataPoint)
+		p.series[name] = points
+	}
+	key := dp.Attributes.Equivalent()
+	if prev, ok := points[key]; ok {
+		dp.StartTime = prev.StartTime
+	}
+	points[key] = &dp
+}
+
+// Produce returns the latest summaries to the reader collecting metrics
+func (p *summaryProducer) Produce(context.Context) ([]metricdata.ScopeMetrics, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.series) == 0 {
+		return nil, nil
+	}
+	metrics := make([]metricdata.Metrics, 0, len(p.series))
+	for name, points := range p.series {
+		summary := metricdata.Summary{DataPoints: make([]metricdata.SummaryDataPoint, 0, len(points))}
+		for _, dp := range points {
+			summary.DataPoints = append(summary.DataPoints, *dp)
+		}
+		metrics = append(metrics, metricdata.Metrics{Name: name, Data: summary})
+	}
+	sort.Slice(metrics, func(i, j int) bool { return metrics[i].Name < metrics[j].Name })
+	return []metricdata.ScopeMetrics{{Scope: p.scope, Metrics: metrics}}, nil
+}
+
+// RecordSummary forwards a summary that was already aggregated upstream without raw
+// samples. Min and max are exported as the 0 and 1 quantiles. The latest summary per
+// name and attribute set is exported on every collection.
+func (o *OpenTelemetry) RecordSummary(ctx context.Context, name string, count uint64, sum, min, max float64, quantiles map[float64]float64, attributes ...attribute.KeyValue) {
+	if !o.metricsEnabled || metricsSuppressed(ctx) || o.summaries == nil {
+		return
+	}
+	attrs, keep := o.metricAttributes(name, attributes)
+	if !keep {
+		return
+	}
+
+	values := make([]metricdata.QuantileValue, 0, len(quantiles)+2)
+	values = append(values, metricdata.QuantileValue{Quantile: 0, Value: min})
+	for q, v := range quantiles {
+		if q <= 0 || q >= 1 {
+			continue
+		}
+		values = append(values, metricdata.QuantileValue{Quantile: q, Value: v})
+	}
+	values = append(values, metricdata.QuantileValue{Quantile: 1, Value: max})
+	sort.Slice(values, func(i, j int) bool { return values[i].Quantile < values[j].Quantile })
+
+	now := time.Now()
+	o.summaries.record(name, metricdata.SummaryDataPoint{
+		Attributes:     attribute.NewSet(attrs...),
+		StartTime:      now,
+		Time:           now,
+		Count:          count,
+		Sum:            sum,
+		QuantileValues: values,
+	})
+}
@@ -0,0 +1,48 @@
+package telemetry
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+func TestRecordSummaryExportsSummaryShape(t *testing.T) {
+	collector := newStubCollector(t)
+	tt := newTestTelemetry(t, WithTraceEndpoint(collector.addr), WithMetricEndpoint(collector.addr))
+	ctx := context.Background()
+
+	tt.RecordSummary(ctx, "upstream.latency", 40, 1200, 5, 250,
+		map[float64]float64{0.5: 20, 0.99: 180, 1.5: 999}, attribute.String("region", "eu"))
+	tt.RecordSummary(ctx, "upstream.latency", 50, 1500, 4, 300,
+		map[float64]float64{0.5: 25, 0.99: 200}, attribute.String("region", "eu"))
+
+	if err := tt.forceFlush(ctx); err != nil {
+		t.Fatalf("forceFlush() error = %v", err)
+	}
+	m := collector.receivedMetric("upstream.latency")
+	if m == nil {
+		t.Fatal("collector received no upstream.latency metric")
+	}
+	summary := m.GetSummary()
+	if summary == nil || len(summary.GetDataPoints()) != 1 {
+		t.Fatalf("upstream.latency = %v, want a summary with one data point", m)
+	}
+	dp := summary.GetDataPoints()[0]
+	if dp.GetCount() != 50 || dp.GetSum() != 1500 {
+		t.Errorf("count, sum = %d, %v, want the latest summary 50, 1500", dp.GetCount(), dp.GetSum())
+	}
+	want := []struct{ quantile, value float64 }{{0, 4}, {0.5, 25}, {0.99, 200}, {1, 300}}
+	got := dp.GetQuantileValues()
+	if len(got) != len(want) {
+		t.Fatalf("quantiles = %v, want %v", got, want)
+	}
+	for i, w := range want {
+		if got[i].GetQuantile() != w.quantile || got[i].GetValue() != w.value {
+			t.Errorf("quantile %d = %v, want %v", i, got[i], w)
+		}
+	}
+	if attrs := dp.GetAttributes(); len(attrs) != 1 || attrs[0].GetValue().GetStringValue() != "eu" {
+		t.Errorf("attributes = %v, want region=eu", attrs)
+	}
+}
@@ -3,11 +3,52 @@
 // starting spans, recording metrics, and managing the telemetry lifecycle.
 package telemetry
 
+import (
+	"fmt"
+	"sync"
+)
+
+var (
+	defaultOnce      sync.Once
+	defaultTelemetry Telemetry
+	defaultInitErr   error
+)
+
 // init initializes the telemetry package. It sets up the logger, reads
 // configuration from environment variables, and initializes the OpenTelemetry/AppInsights
 // provider.
 func init() {
 
-	NewTelemetry()
+	initDefaultTelemetry()
+
+}
+
+// initDefaultTelemetry creates the package-level provider from the environment once
+func initDefaultTelemetry() {
+	defaultOnce.Do(func() {
+		defaultTelemetry, defaultInitErr = NewTelemetry()
+	})
+}
+
+// GetTelemetry returns the provider configured from the environment when the
+// package was initialized. It is nil if initialization failed, see InitError.
+func GetTelemetry() Telemetry {
+	initDefaultTelemetry()
+	return defaultTelemetry
+}
+
+// MustGetTelemetry returns the provider configured from the environment when the
+// package was initialized, and panics if initialization failed
+func MustGetTelemetry() Telemetry {
+	initDefaultTelemetry()
+	if defaultInitErr != nil {
+		panic(fmt.Sprintf("telemetry initialization failed: %v", defaultInitErr))
+	}
+	return defaultTelemetry
+}
 
+// InitError returns the error of the package initialization, if any
+func InitError() error {
+	initDefaultTelemetry()
+	return defaultInitErr
 }
@@ -0,0 +1,112 @@
+package telemetry
+
+import (
+	"context"
+	"sync"
+	"testing"
+)
+
+func TestGetTelemetryReturnsSameInstance(t *testing.T) {
+	first := GetTelemetry()
+	initErr := InitError()
+	if first == nil && initErr == nil {
+		t.Fatal("GetTelemetry() = nil without an initialization error")
+	}
+
+	var wg sync.WaitGroup
+	results := make([]Telemetry, 8)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = GetTelemetry()
+		}(i)
+	}
+	wg.Wait()
+	for i, got := range results {
+		if got != first {
+			t.Errorf("GetTelemetry() call %d returned a different instance", i)
+		}
+	}
+	if err := InitError(); err != initErr {
+		t.Errorf("InitError() = %v on a repeated call, want %v", err, initErr)
+	}
+
+	if initErr != nil {
+		defer func() {
+			if recover() == nil {
+				t.Error("MustGetTelemetry() did not panic after a failed initialization")
+			}
+		}()
+	}
+	if got := MustGetTelemetry(); got != first {
+		t.Error("MustGetTelemetry() returned a different instance than GetTelemetry()")
+	}
+}
+
+func TestNewTelemetrySelectsTelemetryType(t *testing.T) {
+	tests := []struct {
+		telemetryType string
+		traceEnabled  string
+		wantOTel      bool
+		wantErr       bool
+	}{
+		{"", "", false, false},
+		{"", "true", true, false},
+		{"otel", "true", true, false},
+		{"opentelemetry", "true", true, false},
+		{"opentelemetry", "false", false, false},
+		{"none", "true", false, false},
+		{"appinsights", "true", false, true},
+		{"zipkin", "", false, true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.telemetryType+"/"+tc.traceEnabled, func(t *testing.T) {
+			t.Setenv("TELEMETRY_TYPE", tc.telemetryType)
+			t.Setenv("SERVICE_NAME", "telemetry-test")
+			t.Setenv("OTEL_TRACE_ENABLED", tc.traceEnabled)
+			t.Setenv("OTEL_METRICS_ENABLED", "")
+			t.Setenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "127.0.0.1:1")
+			t.Setenv("OTEL_EXPORTER_OTLP_INSECURE", "true")
+
+			got, err := NewTelemetry()
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("NewTelemetry() error = nil, want an unknown telemetry type error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewTelemetry() error = %v", err)
+			}
+			t.Cleanup(func() {
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+				_ = got.Shutdown(ctx)
+			})
+			_, isOTel := got.(*OpenTelemetry)
+			_, isNoop := got.(NoopTelemetry)
+			if isOTel != tc.wantOTel || isNoop == tc.wantOTel {
+				t.Errorf("NewTelemetry() = %T, want OpenTelemetry %v", got, tc.wantOTel)
+			}
+		})
+	}
+}
+
+func TestGetTelemetryType(t *testing.T) {
+	tests := map[string]TelemetryType{
+		"":                    TelemetryTypeOpenTelemetry,
+		"OTel":                TelemetryTypeOpenTelemetry,
+		"opentelemetry":       TelemetryTypeOpenTelemetry,
+		"AppInsights":         TelemetryTypeAppInsights,
+		"applicationinsights": TelemetryTypeAppInsights,
+		"none":                TelemetryTypeNone,
+		"zipkin":              TelemetryTypeOpenTelemetry,
+	}
+	for value, want := range tests {
+		t.Setenv("TELEMETRY_TYPE", value)
+		if got := getTelemetryType(); got != want {
+			t.Errorf("getTelemetryType() with TELEMETRY_TYPE=%q = %q, want %q", value, got, want)
+		}
+	}
+}
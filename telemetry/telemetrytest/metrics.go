@@ -0,0 +1,52 @@
+// metrics.go - Deterministic metric collection for tests
+
+package telemetrytest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sadco-io/sad-go-telemetry/telemetry"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+// MetricReader is a manual metric reader that collects only when Collect is
+// called, so tests can record metrics and then assert on them without timers
+type MetricReader struct {
+	*sdkmetric.ManualReader
+}
+
+// NewMetricReader returns a MetricReader to install with its Option method
+func NewMetricReader() *MetricReader {
+	return &MetricReader{ManualReader: sdkmetric.NewManualReader()}
+}
+
+// Option returns the telemetry option registering the reader on the meter provider
+func (r *MetricReader) Option() telemetry.Option {
+	return telemetry.WithMetricReader(r.ManualReader)
+}
+
+// Collect collects the current metrics, failing the test on error
+func (r *MetricReader) Collect(t testing.TB) metricdata.ResourceMetrics {
+	t.Helper()
+	var rm metricdata.ResourceMetrics
+	if err := r.ManualReader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("failed to collect metrics: %v", err)
+	}
+	return rm
+}
+
+// Metric collects the current metrics and returns the one with the given name
+func (r *MetricReader) Metric(t testing.TB, name string) (metricdata.Metrics, bool) {
+	t.Helper()
+	rm := r.Collect(t)
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name == name {
+				return m, true
+			}
+		}
+	}
+	return metricdata.Metrics{}, false
+}
@@ -0,0 +1,62 @@
+package telemetrytest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sadco-io/sad-go-telemetry/telemetry"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestMetricReaderCollectsOnDemand(t *testing.T) {
+	reader := NewMetricReader()
+	o, err := telemetry.NewOpenTelemetryWithOptions(
+		telemetry.WithServiceName("telemetrytest"),
+		telemetry.WithTracingEnabled(false),
+		telemetry.WithMetricsEnabled(true),
+		telemetry.WithMetricEndpoint("127.0.0.1:1"),
+		telemetry.WithInsecure(),
+		telemetry.WithKubernetesDetector(false),
+		reader.Option(),
+	)
+	if err != nil {
+		t.Fatalf("NewOpenTelemetryWithOptions() error = %v", err)
+	}
+	t.Cleanup(func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_ = o.Shutdown(ctx)
+	})
+	ctx := context.Background()
+
+	if _, ok := reader.Metric(t, "jobs.processed"); ok {
+		t.Fatal("jobs.processed collected before anything was recorded")
+	}
+
+	o.IncrementCounter(ctx, "jobs.processed", 2)
+	o.IncrementCounter(ctx, "jobs.processed", 3)
+	o.RecordGauge(ctx, "queue.depth", 7)
+
+	m, ok := reader.Metric(t, "jobs.processed")
+	if !ok {
+		t.Fatal("jobs.processed not collected")
+	}
+	sum, ok := m.Data.(metricdata.Sum[float64])
+	if !ok || len(sum.DataPoints) != 1 || sum.DataPoints[0].Value != 5 {
+		t.Errorf("jobs.processed = %+v, want a sum of 5", m.Data)
+	}
+	m, ok = reader.Metric(t, "queue.depth")
+	if !ok {
+		t.Fatal("queue.depth not collected")
+	}
+	gauge, ok := m.Data.(metricdata.Gauge[float64])
+	if !ok || len(gauge.DataPoints) != 1 || gauge.DataPoints[0].Value != 7 {
+		t.Errorf("queue.depth = %+v, want a gauge of 7", m.Data)
+	}
+
+	o.IncrementCounter(ctx, "jobs.processed", 1)
+	m, _ = reader.Metric(t, "jobs.processed")
+	if sum := m.Data.(metricdata.Sum[float64]); sum.DataPoints[0].Value != 6 {
+		t.Errorf("jobs.processed after another collection = %v, want the cumulative 6", sum.DataPoints[0].Value)
+	}
+}
@@ -0,0 +1,51 @@
+// Package telemetrytest provides helpers for testing code instrumented with the
+// telemetry package.
+package telemetrytest
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// traceparentHeader is the W3C trace context header carrying the trace and span IDs
+const traceparentHeader = "traceparent"
+
+// traceparentPattern matches a version 00 W3C traceparent header value
+var traceparentPattern = regexp.MustCompile(`^00-([0-9a-f]{32})-([0-9a-f]{16})-[0-9a-f]{2}$`)
+
+// AssertPropagated fails the test unless outbound carries a valid, well-formed
+// traceparent header with non-zero trace and span IDs
+func AssertPropagated(t testing.TB, outbound http.Header) {
+	t.Helper()
+	value := outbound.Get(traceparentHeader)
+	if value == "" {
+		t.Fatalf("expected a %s header, got none", traceparentHeader)
+	}
+	m := traceparentPattern.FindStringSubmatch(value)
+	if m == nil {
+		t.Fatalf("malformed %s header: %q", traceparentHeader, value)
+	}
+	if isAllZeros(m[1]) {
+		t.Fatalf("%s header has an all-zero trace ID: %q", traceparentHeader, value)
+	}
+	if isAllZeros(m[2]) {
+		t.Fatalf("%s header has an all-zero span ID: %q", traceparentHeader, value)
+	}
+}
+
+// ExtractTraceID returns the trace ID carried in the traceparent header of h, or
+// an empty string if the header is missing or malformed
+func ExtractTraceID(h http.Header) string {
+	m := traceparentPattern.FindStringSubmatch(h.Get(traceparentHeader))
+	if m == nil {
+		return ""
+	}
+	return m[1]
+}
+
+// isAllZeros reports whether a hex ID consists only of zeros
+func isAllZeros(id string) bool {
+	return strings.Trim(id, "0") == ""
+}
@@ -0,0 +1,80 @@
+package telemetrytest
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"runtime"
+	"testing"
+
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// fatalRecorder is a testing.TB recording whether Fatalf was called, stopping the
+// calling goroutine like testing.T does
+type fatalRecorder struct {
+	testing.TB
+	failed  bool
+	message string
+}
+
+func (f *fatalRecorder) Helper() {}
+
+func (f *fatalRecorder) Fatalf(format string, args ...any) {
+	f.failed = true
+	f.message = fmt.Sprintf(format, args...)
+	runtime.Goexit()
+}
+
+// assertPropagatedFails reports whether AssertPropagated fails for header
+func assertPropagatedFails(header http.Header) bool {
+	f := &fatalRecorder{}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		AssertPropagated(f, header)
+	}()
+	<-done
+	return f.failed
+}
+
+func TestAssertPropagatedAcceptsInjectedContext(t *testing.T) {
+	rec := NewRecorder()
+	ctx, span := rec.StartSpan(context.Background(), "outbound")
+	defer span.End()
+
+	header := http.Header{}
+	propagation.TraceContext{}.Inject(ctx, propagation.HeaderCarrier(header))
+
+	AssertPropagated(t, header)
+	if got, want := ExtractTraceID(header), span.SpanContext().TraceID().String(); got != want {
+		t.Errorf("ExtractTraceID() = %q, want %q", got, want)
+	}
+}
+
+func TestAssertPropagatedRejectsInvalidHeaders(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		traceID string
+	}{
+		{"missing", "", ""},
+		{"malformed", "00-abc-def-01", ""},
+		{"upper case", "00-4BF92F3577B34DA6A3CE929D0E0E4736-00F067AA0BA902B7-01", ""},
+		{"no flags", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7", ""},
+		{"zero trace", "00-00000000000000000000000000000000-00f067aa0ba902b7-01", "00000000000000000000000000000000"},
+		{"zero span", "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", "4bf92f3577b34da6a3ce929d0e0e4736"},
+	}
+	for _, tc := range tests {
+		header := http.Header{}
+		if tc.value != "" {
+			header.Set("traceparent", tc.value)
+		}
+		if !assertPropagatedFails(header) {
+			t.Errorf("%s: AssertPropagated accepted %q", tc.name, tc.value)
+		}
+		if got := ExtractTraceID(header); got != tc.traceID {
+			t.Errorf("%s: ExtractTraceID(%q) = %q, want %q", tc.name, tc.value, got, tc.traceID)
+		}
+	}
+}
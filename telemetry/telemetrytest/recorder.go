@@ -0,0 +1,318 @@
+// recorder.go - In-memory Telemetry implementation recording calls for assertions
+
+package telemetrytest
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sadco-io/sad-go-telemetry/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RecordedMetric is a metric value recorded through a RecorderTelemetry
+type RecordedMetric struct {
+	Kind       telemetry.InstrumentKind
+	Name       string
+	Value      float64
+	Attributes []attribute.KeyValue
+}
+
+// RecordedEvent is an event recorded through AddEvent or PostEvent
+type RecordedEvent struct {
+	Name       string
+	Attributes []attribute.KeyValue
+}
+
+// RecordedError is an error recorded through RecordError
+type RecordedError struct {
+	Err        error
+	Attributes []attribute.KeyValue
+}
+
+// RecordedLog is a message recorded through LogInfo, LogWarning, LogError or PostTrace
+type RecordedLog struct {
+	Level      telemetry.SeverityLevel
+	Message    string
+	Err        error
+	Attributes []attribute.KeyValue
+}
+
+// RecorderTelemetry implements telemetry.Telemetry by keeping everything recorded
+// in memory, so tests can assert on the spans, events, metrics, errors and logs
+// produced by the code under test. It is safe for concurrent use.
+type RecorderTelemetry struct {
+	spans  *tracetest.SpanRecorder
+	tracer trace.Tracer
+
+	mu      sync.Mutex
+	metrics []RecordedMetric
+	events  []RecordedEvent
+	errors  []RecordedError
+	logs    []RecordedLog
+}
+
+var _ telemetry.Telemetry = (*RecorderTelemetry)(nil)
+
+// NewRecorder returns an empty RecorderTelemetry. Its spans are recorded by a
+// private tracer provider and do not affect the global one.
+func NewRecorder() *RecorderTelemetry {
+	spans := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans))
+	return &RecorderTelemetry{
+		spans:  spans,
+		tracer: tp.Tracer("telemetrytest"),
+	}
+}
+
+// Spans returns the spans ended so far
+func (r *RecorderTelemetry) Spans() []sdktrace.ReadOnlySpan {
+	return r.spans.Ended()
+}
+
+// RecordedMetrics returns the metric values recorded so far, in recording order
+func (r *RecorderTelemetry) RecordedMetrics() []RecordedMetric {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]RecordedMetric(nil), r.metrics...)
+}
+
+// LastMetric returns the last value recorded for the named metric
+func (r *RecorderTelemetry) LastMetric(name string) (RecordedMetric, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := len(r.metrics) - 1; i >= 0; i-- {
+		if r.metrics[i].Name == name {
+			return r.metrics[i], true
+		}
+	}
+	return RecordedMetric{}, false
+}
+
+// Events returns the events recorded so far
+func (r *RecorderTelemetry) Events() []RecordedEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]RecordedEvent(nil), r.events...)
+}
+
+// Errors returns the errors recorded so far
+func (r *RecorderTelemetry) Errors() []RecordedError {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]RecordedError(nil), r.errors...)
+}
+
+// Logs returns the log messages recorded so far
+func (r *RecorderTelemetry) Logs() []RecordedLog {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]RecordedLog(nil), r.logs...)
+}
+
+// Reset discards the recorded metrics, events, errors and logs. Recorded spans
+// are kept.
+func (r *RecorderTelemetry) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.metrics, r.events, r.errors, r.logs = nil, nil, nil, nil
+}
+
+// recordMetric stores a metric value
+func (r *RecorderTelemetry) recordMetric(kind telemetry.InstrumentKind, name string, value float64, attributes []attribute.KeyValue) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.metrics = append(r.metrics, RecordedMetric{Kind: kind, Name: name, Value: value, Attributes: attributes})
+}
+
+// recordEvent stores an event
+func (r *RecorderTelemetry) recordEvent(name string, attributes []attribute.KeyValue) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, RecordedEvent{Name: name, Attributes: attributes})
+}
+
+// recordLog stores a log message
+func (r *RecorderTelemetry) recordLog(level telemetry.SeverityLevel, message string, err error, attributes []attribute.KeyValue) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.logs = append(r.logs, RecordedLog{Level: level, Message: message, Err: err, Attributes: attributes})
+}
+
+// StartSpan starts a recorded span
+func (r *RecorderTelemetry) StartSpan(ctx context.Context, name string) (context.Context, trace.Span) {
+	return r.tracer.Start(ctx, name)
+}
+
+// StartSpanWithKind starts a recorded span of the given kind
+func (r *RecorderTelemetry) StartSpanWithKind(ctx context.Context, name string, kind trace.SpanKind, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	return r.tracer.Start(ctx, name, trace.WithSpanKind(kind), trace.WithAttributes(attributes...))
+}
+
+// StartSpanWithLinks starts a recorded span with the given links
+func (r *RecorderTelemetry) StartSpanWithLinks(ctx context.Context, name string, links []trace.Link) (context.Context, trace.Span) {
+	return r.tracer.Start(ctx, name, trace.WithLinks(links...))
+}
+
+// EndSpan ends the given span
+func (r *RecorderTelemetry) EndSpan(span trace.Span) {
+	if span != nil {
+		span.End()
+	}
+}
+
+// WithSpan runs fn in a recorded span, recording the error fn returns
+func (r *RecorderTelemetry) WithSpan(ctx context.Context, name string, fn func(ctx context.Context) error) error {
+	ctx, span := r.tracer.Start(ctx, name)
+	defer span.End()
+
+	err := fn(ctx)
+	r.RecordError(ctx, err)
+	return err
+}
+
+// AddEvent records an event and adds it to the given span
+func (r *RecorderTelemetry) AddEvent(span trace.Span, name string, attributes ...attribute.KeyValue) {
+	r.recordEvent(name, attributes)
+	if span != nil {
+		span.AddEvent(name, trace.WithAttributes(attributes...))
+	}
+}
+
+// RecordMetric records a counter value
+func (r *RecorderTelemetry) RecordMetric(_ context.Context, name string, value float64, attributes ...attribute.KeyValue) {
+	r.recordMetric(telemetry.InstrumentKindCounter, name, value, attributes)
+}
+
+// RecordMetricE records a counter value and returns nil
+func (r *RecorderTelemetry) RecordMetricE(_ context.Context, name string, value float64, attributes ...attribute.KeyValue) error {
+	r.recordMetric(telemetry.InstrumentKindCounter, name, value, attributes)
+	return nil
+}
+
+// PostEvent records an event with the properties as string attributes and adds it
+// to the span in ctx
+func (r *RecorderTelemetry) PostEvent(ctx context.Context, name string, properties map[string]string) {
+	attrs := propertiesToAttributes(properties)
+	r.recordEvent(name, attrs)
+	trace.SpanFromContext(ctx).AddEvent(name, trace.WithAttributes(attrs...))
+}
+
+// PostTrace records a log message at the given severity and adds a Trace event to
+// the span in ctx, like the OpenTelemetry implementation
+func (r *RecorderTelemetry) PostTrace(ctx context.Context, message string, severity string, properties map[string]string) {
+	level, _ := telemetry.ParseSeverityLevel(severity)
+	attrs := propertiesToAttributes(properties)
+	r.recordLog(level, message, nil, attrs)
+	trace.SpanFromContext(ctx).AddEvent("Trace", trace.WithAttributes(append([]attribute.KeyValue{
+		attribute.String("message", message),
+		attribute.String("severity", level.String()),
+		attribute.Int("severity_number", level.ToOTelSeverity()),
+	}, attrs...)...))
+}
+
+// RecordError records an error and adds it to the span in ctx
+func (r *RecorderTelemetry) RecordError(ctx context.Context, err error, attributes ...attribute.KeyValue) {
+	if err == nil {
+		return
+	}
+	r.mu.Lock()
+	r.errors = append(r.errors, RecordedError{Err: err, Attributes: attributes})
+	r.mu.Unlock()
+
+	span := trace.SpanFromContext(ctx)
+	span.RecordError(err, trace.WithAttributes(attributes...))
+	span.SetStatus(codes.Error, err.Error())
+}
+
+// IncrementCounter records a counter increment
+func (r *RecorderTelemetry) IncrementCounter(_ context.Context, name string, increment float64, attributes ...attribute.KeyValue) {
+	r.recordMetric(telemetry.InstrumentKindCounter, name, increment, attributes)
+}
+
+// RecordGauge records a gauge value
+func (r *RecorderTelemetry) RecordGauge(_ context.Context, name string, value float64, attributes ...attribute.KeyValue) {
+	r.recordMetric(telemetry.InstrumentKindGauge, name, value, attributes)
+}
+
+// LogInfo records an info message
+func (r *RecorderTelemetry) LogInfo(_ context.Context, message string, attributes ...attribute.KeyValue) {
+	r.recordLog(telemetry.SeverityInformation, message, nil, attributes)
+}
+
+// LogWarning records a warning message
+func (r *RecorderTelemetry) LogWarning(_ context.Context, message string, attributes ...attribute.KeyValue) {
+	r.recordLog(telemetry.SeverityWarning, message, nil, attributes)
+}
+
+// LogError records an error message
+func (r *RecorderTelemetry) LogError(_ context.Context, message string, err error, attributes ...attribute.KeyValue) {
+	r.recordLog(telemetry.SeverityError, message, err, attributes)
+}
+
+// TrackRequest records an HTTP Request span, like the OpenTelemetry implementation
+func (r *RecorderTelemetry) TrackRequest(ctx context.Context, method, url string, duration time.Duration, statusCode int) {
+	_, span := r.tracer.Start(ctx, "HTTP Request", trace.WithAttributes(
+		attribute.String("http.method", method),
+		attribute.String("http.url", url),
+		attribute.Int("http.status_code", statusCode),
+		attribute.Int64("http.duration_ms", duration.Milliseconds()),
+	))
+	span.End()
+}
+
+// TrackDependency records a Dependency Call span, like the OpenTelemetry implementation
+func (r *RecorderTelemetry) TrackDependency(ctx context.Context, dependencyType, target string, duration time.Duration, success bool) {
+	_, span := r.tracer.Start(ctx, "Dependency Call", trace.WithAttributes(
+		attribute.String("dependency.type", dependencyType),
+		attribute.String("dependency.target", target),
+		attribute.Bool("dependency.success", success),
+		attribute.Int64("dependency.duration_ms", duration.Milliseconds()),
+	))
+	if !success {
+		span.SetStatus(codes.Error, "Dependency call failed")
+	}
+	span.End()
+}
+
+// TrackAvailability records an availability.tests counter increment
+func (r *RecorderTelemetry) TrackAvailability(_ context.Context, name string, duration time.Duration, success bool) {
+	r.recordMetric(telemetry.InstrumentKindCounter, "availability.tests", 1, []attribute.KeyValue{
+		attribute.String("availability.test", name),
+		attribute.Int64("availability.duration_ms", duration.Milliseconds()),
+		attribute.Bool("availability.success", success),
+	})
+}
+
+// SetUser sets the user.id attribute on the span in ctx
+func (r *RecorderTelemetry) SetUser(ctx context.Context, id string) {
+	trace.SpanFromContext(ctx).SetAttributes(attribute.String("user.id", id))
+}
+
+// SetSession sets the session.id attribute on the span in ctx
+func (r *RecorderTelemetry) SetSession(ctx context.Context, id string) {
+	trace.SpanFromContext(ctx).SetAttributes(attribute.String("session.id", id))
+}
+
+// TracingEnabled reports true
+func (r *RecorderTelemetry) TracingEnabled() bool { return true }
+
+// MetricsEnabled reports true
+func (r *RecorderTelemetry) MetricsEnabled() bool { return true }
+
+// Shutdown does nothing; the recorded data stays available
+func (r *RecorderTelemetry) Shutdown(context.Context) error { return nil }
+
+// propertiesToAttributes converts string properties to attributes
+func propertiesToAttributes(properties map[string]string) []attribute.KeyValue {
+	attrs := make([]attribute.KeyValue, 0, len(properties))
+	for k, v := range properties {
+		attrs = append(attrs, attribute.String(k, v))
+	}
+	return attrs
+}
@@ -0,0 +1,93 @@
+package telemetrytest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sadco-io/sad-go-telemetry/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// processOrder is code under test written against the Telemetry interface
+func processOrder(ctx context.Context, t telemetry.Telemetry, fail bool) error {
+	return t.WithSpan(ctx, "process order", func(ctx context.Context) error {
+		t.IncrementCounter(ctx, "orders.processed", 1, attribute.String("region", "eu"))
+		t.RecordGauge(ctx, "orders.pending", 3)
+		t.PostEvent(ctx, "order.validated", map[string]string{"order.id": "o-1"})
+		t.LogWarning(ctx, "stock is low")
+		if fail {
+			return errors.New("payment declined")
+		}
+		return nil
+	})
+}
+
+func TestRecorderTelemetryRecordsThroughInterface(t *testing.T) {
+	r := NewRecorder()
+	ctx := context.Background()
+
+	if err := processOrder(ctx, r, false); err != nil {
+		t.Fatalf("processOrder() error = %v", err)
+	}
+	if err := processOrder(ctx, r, true); err == nil {
+		t.Fatal("processOrder() error = nil, want the payment failure")
+	}
+
+	spans := r.Spans()
+	if len(spans) != 2 || spans[0].Name() != "process order" {
+		t.Fatalf("recorded spans = %v, want two process order spans", spans)
+	}
+	if spans[0].Status().Code == codes.Error || spans[1].Status().Code != codes.Error {
+		t.Errorf("span statuses = %v, %v, want only the failed run marked as an error", spans[0].Status(), spans[1].Status())
+	}
+	if events := spans[0].Events(); len(events) != 1 || events[0].Name != "order.validated" {
+		t.Errorf("span events = %v, want the posted event", events)
+	}
+
+	if got := len(r.RecordedMetrics()); got != 4 {
+		t.Errorf("recorded %d metric values, want 4", got)
+	}
+	last, ok := r.LastMetric("orders.pending")
+	if !ok || last.Kind != telemetry.InstrumentKindGauge || last.Value != 3 {
+		t.Errorf("LastMetric(orders.pending) = %+v, %v, want a gauge of 3", last, ok)
+	}
+	if errs := r.Errors(); len(errs) != 1 || errs[0].Err.Error() != "payment declined" {
+		t.Errorf("recorded errors = %v, want the payment failure", errs)
+	}
+	if logs := r.Logs(); len(logs) != 2 || logs[0].Level != telemetry.SeverityWarning || logs[0].Message != "stock is low" {
+		t.Errorf("recorded logs = %v, want two warnings", logs)
+	}
+	if events := r.Events(); len(events) != 2 {
+		t.Errorf("recorded %d events, want 2", len(events))
+	}
+
+	r.Reset()
+	if len(r.RecordedMetrics())+len(r.Errors())+len(r.Logs())+len(r.Events()) != 0 {
+		t.Error("Reset() kept recorded values")
+	}
+	if len(r.Spans()) != 2 {
+		t.Error("Reset() discarded the recorded spans")
+	}
+}
+
+func TestRecorderTelemetryTracksRequestsAndDependencies(t *testing.T) {
+	r := NewRecorder()
+	ctx := context.Background()
+
+	r.TrackRequest(ctx, "GET", "/orders", 15*time.Millisecond, 200)
+	r.TrackDependency(ctx, "postgresql", "orders-db", 4*time.Millisecond, false)
+
+	spans := r.Spans()
+	if len(spans) != 2 {
+		t.Fatalf("recorded %d spans, want 2", len(spans))
+	}
+	if spans[0].Name() != "HTTP Request" || spans[1].Name() != "Dependency Call" {
+		t.Errorf("span names = %q, %q", spans[0].Name(), spans[1].Name())
+	}
+	if spans[1].Status().Code != codes.Error {
+		t.Errorf("failed dependency status = %v, want error", spans[1].Status())
+	}
+}
@@ -0,0 +1,125 @@
+// tenant_routing.go - Export of spans to tenant-specific collectors
+
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/sadco-io/sad-go-logger/logger"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.uber.org/zap"
+)
+
+// TenantRouter returns the trace endpoint of the tenant of a span, from the context
+// it is started in, and false for spans exported to the default endpoints
+type TenantRouter func(ctx context.Context) (endpoint string, ok bool)
+
+// tenantRoutingProcessor exports each span either through the default export
+// processors or through the export processor of its tenant's endpoint, created on
+// first use
+type tenantRoutingProcessor struct {
+	route    TenantRouter
+	defaults []sdktrace.SpanProcessor
+	cfg      otelConfig
+
+	spans sync.Map // trace.SpanID -> sdktrace.SpanProcessor
+
+	mu       sync.Mutex
+	tenants  map[string]sdktrace.SpanProcessor
+	shutdown bool
+}
+
+// newTenantRoutingProcessor routes spans with route, falling back to defaults
+func newTenantRoutingProcessor(route TenantRouter, defaults []sdktrace.SpanProcessor, cfg otelConfig) *tenantRoutingProcessor {
+	return &tenantRoutingProcessor{
+		route:    route,
+		defaults: defaults,
+		cfg:      cfg,
+		tenants:  make(map[string]sdktrace.SpanProcessor),
+	}
+}
+
+// OnStart selects the processor of the span from the context it is started in
+func (p *tenantRoutingProcessor) OnStart(parent context.Context, s sdktrace.ReadWriteSpan) {
+	if endpoint, ok := p.route(parent); ok && endpoint != "" {
+		if processor := p.tenantProcessor(endpoint); processor != nil {
+			p.spans.Store(s.SpanContext().SpanID(), processor)
+			processor.OnStart(parent, s)
+			return
+		}
+	}
+	for _, processor := range p.defaults {
+		processor.OnStart(parent, s)
+	}
+}
+
+// OnEnd forwards the span to the processor selected when it started
+func (p *tenantRoutingProcessor) OnEnd(s sdktrace.ReadOnlySpan) {
+	if processor, ok := p.spans.LoadAndDelete(s.SpanContext().SpanID()); ok {
+		processor.(sdktrace.SpanProcessor).OnEnd(s)
+		return
+	}
+	for _, processor := range p.defaults {
+		processor.OnEnd(s)
+	}
+}
+
+// tenantProcessor returns the export processor of endpoint, creating its exporter
+// on first use. It returns nil if the exporter cannot be created, so the span is
+// exported to the default endpoints instead.
+func (p *tenantRoutingProcessor) tenantProcessor(endpoint string) sdktrace.SpanProcessor {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if processor, ok := p.tenants[endpoint]; ok || p.shutdown {
+		return processor
+	}
+	exporter, err := newSpanExporter(context.Background(), endpoint, nil, p.cfg)
+	if err != nil {
+		logger.Log.Error("Failed to create tenant trace exporter, using the default endpoints",
+			zap.Error(err),
+			zap.String("endpoint", endpoint))
+		p.tenants[endpoint] = nil
+		return nil
+	}
+	processor := newExportProcessor(exporter, p.cfg)
+	p.tenants[endpoint] = processor
+	return processor
+}
+
+// processors returns the default and tenant processors
+func (p *tenantRoutingProcessor) processors() []sdktrace.SpanProcessor {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	processors := append([]sdktrace.SpanProcessor(nil), p.defaults...)
+	for _, processor := range p.tenants {
+		if processor != nil {
+			processors = append(processors, processor)
+		}
+	}
+	return processors
+}
+
+// Shutdown shuts down the default and tenant processors
+func (p *tenantRoutingProcessor) Shutdown(ctx context.Context) error {
+	processors := p.processors()
+	p.mu.Lock()
+	p.shutdown = true
+	p.mu.Unlock()
+
+	var errs []error
+	for _, processor := range processors {
+		errs = append(errs, processor.Shutdown(ctx))
+	}
+	return errors.Join(errs...)
+}
+
+// ForceFlush flushes the default and tenant processors
+func (p *tenantRoutingProcessor) ForceFlush(ctx context.Context) error {
+	var errs []error
+	for _, processor := range p.processors() {
+		errs = append(errs, processor.ForceFlush(ctx))
+	}
+	return errors.Join(errs...)
+}
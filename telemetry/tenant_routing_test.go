@@ -0,0 +1,61 @@
+package telemetry
+
+import (
+	"context"
+	"sort"
+	"testing"
+)
+
+// tenantKey is the context key of the tenant in the routing test
+type tenantKey struct{}
+
+func TestTenantRoutingExportsSpansToTenantCollectors(t *testing.T) {
+	defaults, eu, us := newStubCollector(t), newStubCollector(t), newStubCollector(t)
+	endpoints := map[string]string{"acme": eu.addr, "globex": us.addr}
+	tt := newTestTelemetry(t,
+		WithTraceEndpoint(defaults.addr),
+		WithMetricEndpoint(defaults.addr),
+		WithTenantRouting(func(ctx context.Context) (string, bool) {
+			tenant, _ := ctx.Value(tenantKey{}).(string)
+			endpoint, ok := endpoints[tenant]
+			return endpoint, ok
+		}))
+	ctx := context.Background()
+
+	acme := context.WithValue(ctx, tenantKey{}, "acme")
+	acme, acmeSpan := tt.StartSpan(acme, "acme request")
+	_, acmeChild := tt.StartSpan(acme, "acme query")
+	tt.EndSpan(acmeChild)
+	tt.EndSpan(acmeSpan)
+
+	_, globexSpan := tt.StartSpan(context.WithValue(ctx, tenantKey{}, "globex"), "globex request")
+	tt.EndSpan(globexSpan)
+	_, otherSpan := tt.StartSpan(context.WithValue(ctx, tenantKey{}, "initech"), "initech request")
+	tt.EndSpan(otherSpan)
+
+	if err := tt.forceFlush(ctx); err != nil {
+		t.Fatalf("forceFlush() error = %v", err)
+	}
+
+	for name, tc := range map[string]struct {
+		collector *stubCollector
+		want      []string
+	}{
+		"acme":    {eu, []string{"acme query", "acme request"}},
+		"globex":  {us, []string{"globex request"}},
+		"default": {defaults, []string{"initech request"}},
+	} {
+		got := tc.collector.receivedSpans()
+		sort.Strings(got)
+		if len(got) != len(tc.want) {
+			t.Errorf("%s collector received %v, want %v", name, got, tc.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tc.want[i] {
+				t.Errorf("%s collector received %v, want %v", name, got, tc.want)
+				break
+			}
+		}
+	}
+}
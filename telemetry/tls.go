@@ -0,0 +1,107 @@
+// tls.go - Transport security of the OTLP exporters
+
+package telemetry
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// loadTLSConfig returns the TLS configuration of the exporters, adding the CA
+// certificates of WithCACertFile to the roots of WithTLSConfig. It returns nil if
+// neither is set, in which case the exporters use TLS with the system roots unless
+// WithInsecure is given.
+func loadTLSConfig(cfg otelConfig) (*tls.Config, error) {
+	if cfg.caCertFile == "" {
+		return cfg.tlsConfig, nil
+	}
+	pem, err := os.ReadFile(cfg.caCertFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA certificate file: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, errors.New("no valid CA certificates found in " + cfg.caCertFile)
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if cfg.tlsConfig != nil {
+		tlsConfig = cfg.tlsConfig.Clone()
+	}
+	tlsConfig.RootCAs = pool
+	return tlsConfig, nil
+}
+
+// useInsecure reports whether exporters send data without TLS; an explicit TLS
+// configuration takes precedence over WithInsecure
+func useInsecure(cfg otelConfig) bool {
+	return cfg.insecure && cfg.tlsConfig == nil
+}
+
+// grpcTraceSecurityOptions returns the transport security options of a gRPC trace exporter
+func grpcTraceSecurityOptions(cfg otelConfig) []otlptracegrpc.Option {
+	switch {
+	case cfg.tlsConfig != nil:
+		return []otlptracegrpc.Option{otlptracegrpc.WithTLSCredentials(credentials.NewTLS(cfg.tlsConfig))}
+	case useInsecure(cfg):
+		return []otlptracegrpc.Option{otlptracegrpc.WithInsecure()}
+	}
+	return nil
+}
+
+// grpcTransportCredentials returns the transport credentials of gRPC exporter
+// connections dialed by this package, matching grpcTraceSecurityOptions
+func grpcTransportCredentials(cfg otelConfig) credentials.TransportCredentials {
+	switch {
+	case cfg.tlsConfig != nil:
+		return credentials.NewTLS(cfg.tlsConfig)
+	case useInsecure(cfg):
+		return insecure.NewCredentials()
+	}
+	return credentials.NewClientTLSFromCert(nil, "")
+}
+
+// grpcMetricSecurityOptions returns the transport security options of a gRPC metric exporter
+func grpcMetricSecurityOptions(cfg otelConfig) []otlpmetricgrpc.Option {
+	switch {
+	case cfg.tlsConfig != nil:
+		return []otlpmetricgrpc.Option{otlpmetricgrpc.WithTLSCredentials(credentials.NewTLS(cfg.tlsConfig))}
+	case useInsecure(cfg):
+		return []otlpmetricgrpc.Option{otlpmetricgrpc.WithInsecure()}
+	}
+	return nil
+}
+
+// httpTraceSecurityOptions returns the transport security options of an HTTP trace
+// exporter, for endpoints given as host:port
+func httpTraceSecurityOptions(cfg otelConfig) []otlptracehttp.Option {
+	switch {
+	case cfg.tlsConfig != nil:
+		return []otlptracehttp.Option{otlptracehttp.WithTLSClientConfig(cfg.tlsConfig)}
+	case useInsecure(cfg):
+		return []otlptracehttp.Option{otlptracehttp.WithInsecure()}
+	}
+	return nil
+}
+
+// httpMetricSecurityOptions returns the transport security options of an HTTP metric
+// exporter, for endpoints given as host:port
+func httpMetricSecurityOptions(cfg otelConfig) []otlpmetrichttp.Option {
+	switch {
+	case cfg.tlsConfig != nil:
+		return []otlpmetrichttp.Option{otlpmetrichttp.WithTLSClientConfig(cfg.tlsConfig)}
+	case useInsecure(cfg):
+		return []otlpmetrichttp.Option{otlpmetrichttp.WithInsecure()}
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package telemetry_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sadco-io/sad-go-telemetry/telemetry"
+	"github.com/sadco-io/sad-go-telemetry/telemetry/telemetrytest"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// This test lives outside the package so that it can use the telemetrytest recorder
+
+func TestTrackRequestAttributesPerBackend(t *testing.T) {
+	backends := map[string]func(t *testing.T) (telemetry.Telemetry, func() []sdktrace.ReadOnlySpan){
+		"opentelemetry": func(t *testing.T) (telemetry.Telemetry, func() []sdktrace.ReadOnlySpan) {
+			spans := tracetest.NewSpanRecorder()
+			o, err := telemetry.NewOpenTelemetryWithOptions(
+				telemetry.WithServiceName("telemetry-test"),
+				telemetry.WithTracingEnabled(true),
+				telemetry.WithMetricsEnabled(false),
+				telemetry.WithTraceEndpoint("127.0.0.1:1"),
+				telemetry.WithInsecure(),
+				telemetry.WithKubernetesDetector(false),
+				telemetry.WithSpanProcessor(spans))
+			if err != nil {
+				t.Fatalf("NewOpenTelemetryWithOptions() error = %v", err)
+			}
+			t.Cleanup(func() {
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+				_ = o.Shutdown(ctx)
+			})
+			return o, spans.Ended
+		},
+		"recorder": func(*testing.T) (telemetry.Telemetry, func() []sdktrace.ReadOnlySpan) {
+			r := telemetrytest.NewRecorder()
+			return r, r.Spans
+		},
+	}
+
+	for name, newBackend := range backends {
+		t.Run(name, func(t *testing.T) {
+			backend, ended := newBackend(t)
+			backend.TrackRequest(context.Background(), "POST", "/orders", 42*time.Millisecond, 201)
+
+			spans := ended()
+			if len(spans) != 1 || spans[0].Name() != "HTTP Request" {
+				t.Fatalf("recorded spans %v, want one HTTP Request span", spans)
+			}
+			attrs := make(map[string]string)
+			for _, kv := range spans[0].Attributes() {
+				attrs[string(kv.Key)] = kv.Value.Emit()
+			}
+			for key, want := range map[string]string{
+				"http.method":      "POST",
+				"http.url":         "/orders",
+				"http.status_code": "201",
+				"http.duration_ms": "42",
+			} {
+				if attrs[key] != want {
+					t.Errorf("%s = %q, want %q", key, attrs[key], want)
+				}
+			}
+		})
+	}
+}